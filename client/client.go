@@ -0,0 +1,271 @@
+// Package client provides repositories of kokizami on a remote store over HTTP,
+// so that processes can share one store served by Handler.
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pankona/kokizami"
+	"github.com/pankona/kokizami/httpapi"
+)
+
+// New returns a Kokizami that runs against a remote store on specified URL,
+// such as "http://127.0.0.1:7777" served by "kkzm serve".
+// http.DefaultClient is used if hc is nil.
+func New(url string, hc *http.Client) *kokizami.Kokizami {
+	return &kokizami.Kokizami{
+		KizamiRepo:  NewKizamiRepo(url, hc),
+		TagRepo:     NewTagRepo(url, hc),
+		SummaryRepo: NewSummaryRepo(url, hc),
+	}
+}
+
+// conn sends repository operations to Handler
+type conn struct {
+	url string
+	hc  *http.Client
+}
+
+func newConn(url string, hc *http.Client) *conn {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return &conn{url: strings.TrimSuffix(url, "/"), hc: hc}
+}
+
+// remoteError is an error returned by Handler.
+// it is classified by its status as errors of kokizami.
+type remoteError struct {
+	status int
+	msg    string
+}
+
+func (e *remoteError) Error() string {
+	return e.msg
+}
+
+func (e *remoteError) Is(target error) bool {
+	kind := httpapi.KindOf(e.status)
+	return kind != nil && target == kind
+}
+
+// call runs specified operation with req and decodes its result into v if v is not nil
+func (c *conn) call(op string, req *request, v interface{}) error {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.hc.Post(c.url+Prefix+"/"+op, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response of %s: %v", op, err)
+	}
+
+	if res.StatusCode >= 300 {
+		e := &response{}
+		if err := json.Unmarshal(body, e); err != nil || e.Error == "" {
+			return &remoteError{status: res.StatusCode, msg: fmt.Sprintf("%s failed: %s", op, res.Status)}
+		}
+		return &remoteError{status: res.StatusCode, msg: e.Error}
+	}
+
+	if v == nil {
+		return nil
+	}
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		return fmt.Errorf("failed to decode response of %s: %v", op, err)
+	}
+	return nil
+}
+
+// KizamiRepo is a KizamiRepository on a remote store
+type KizamiRepo struct {
+	c *conn
+}
+
+// NewKizamiRepo returns a KizamiRepo on specified URL
+func NewKizamiRepo(url string, hc *http.Client) *KizamiRepo {
+	return &KizamiRepo{c: newConn(url, hc)}
+}
+
+// FindAll returns all Kizamis
+func (r *KizamiRepo) FindAll() ([]*kokizami.Kizami, error) {
+	var ret []*kokizami.Kizami
+	err := r.c.call("kizami/FindAll", &request{}, &ret)
+	return ret, err
+}
+
+// Insert inserts a new Kizami with specified desc
+func (r *KizamiRepo) Insert(desc string) (*kokizami.Kizami, error) {
+	ret := &kokizami.Kizami{}
+	err := r.c.call("kizami/Insert", &request{Desc: desc}, ret)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// InsertWithTimes inserts a new Kizami with specified desc, started_at and stopped_at
+func (r *KizamiRepo) InsertWithTimes(desc string, startedAt, stoppedAt time.Time) (*kokizami.Kizami, error) {
+	ret := &kokizami.Kizami{}
+	err := r.c.call("kizami/InsertWithTimes", &request{Desc: desc, StartedAt: startedAt, StoppedAt: stoppedAt}, ret)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// Update updates a Kizami with specified Kizami
+func (r *KizamiRepo) Update(k *kokizami.Kizami) error {
+	return r.c.call("kizami/Update", &request{Kizami: k}, nil)
+}
+
+// Delete deletes a specified Kizami
+func (r *KizamiRepo) Delete(k *kokizami.Kizami) error {
+	return r.c.call("kizami/Delete", &request{Kizami: k}, nil)
+}
+
+// FindByID returns a Kizami by specified ID
+func (r *KizamiRepo) FindByID(id int) (*kokizami.Kizami, error) {
+	ret := &kokizami.Kizami{}
+	err := r.c.call("kizami/FindByID", &request{ID: id}, ret)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// FindByStoppedAt returns Kizamis by specified stopped_at
+func (r *KizamiRepo) FindByStoppedAt(t time.Time) ([]*kokizami.Kizami, error) {
+	var ret []*kokizami.Kizami
+	err := r.c.call("kizami/FindByStoppedAt", &request{StoppedAt: t}, &ret)
+	return ret, err
+}
+
+// FindByFilter returns Kizamis that match specified filter
+func (r *KizamiRepo) FindByFilter(f *kokizami.KizamiFilter) ([]*kokizami.Kizami, error) {
+	var ret []*kokizami.Kizami
+	err := r.c.call("kizami/FindByFilter", &request{Filter: f}, &ret)
+	return ret, err
+}
+
+// Tagging makes relation between specified Kizami and tags
+func (r *KizamiRepo) Tagging(kizamiID int, tagIDs []int) error {
+	return r.c.call("kizami/Tagging", &request{KizamiID: kizamiID, TagIDs: tagIDs}, nil)
+}
+
+// Untagging removes all tags from specified Kizami
+func (r *KizamiRepo) Untagging(kizamiID int) error {
+	return r.c.call("kizami/Untagging", &request{KizamiID: kizamiID}, nil)
+}
+
+// InsertSegment inserts a new segment and sets its ID
+func (r *KizamiRepo) InsertSegment(s *kokizami.Segment) error {
+	ret := &kokizami.Segment{}
+	err := r.c.call("kizami/InsertSegment", &request{Segment: s}, ret)
+	if err != nil {
+		return err
+	}
+	s.ID = ret.ID
+	return nil
+}
+
+// UpdateSegment updates a segment with specified segment
+func (r *KizamiRepo) UpdateSegment(s *kokizami.Segment) error {
+	return r.c.call("kizami/UpdateSegment", &request{Segment: s}, nil)
+}
+
+// TagRepo is a TagRepository on a remote store
+type TagRepo struct {
+	c *conn
+}
+
+// NewTagRepo returns a TagRepo on specified URL
+func NewTagRepo(url string, hc *http.Client) *TagRepo {
+	return &TagRepo{c: newConn(url, hc)}
+}
+
+// FindByID returns a tag by specified ID
+func (t *TagRepo) FindByID(id int) (*kokizami.Tag, error) {
+	ret := &kokizami.Tag{}
+	err := t.c.call("tag/FindByID", &request{ID: id}, ret)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// FindAll returns all tags
+func (t *TagRepo) FindAll() ([]*kokizami.Tag, error) {
+	var ret []*kokizami.Tag
+	err := t.c.call("tag/FindAll", &request{}, &ret)
+	return ret, err
+}
+
+// FindByKizamiID returns tags of specified Kizami
+func (t *TagRepo) FindByKizamiID(kizamiID int) ([]*kokizami.Tag, error) {
+	var ret []*kokizami.Tag
+	err := t.c.call("tag/FindByKizamiID", &request{KizamiID: kizamiID}, &ret)
+	return ret, err
+}
+
+// FindByLabels returns tags by specified labels
+func (t *TagRepo) FindByLabels(labels []string) ([]*kokizami.Tag, error) {
+	var ret []*kokizami.Tag
+	err := t.c.call("tag/FindByLabels", &request{Labels: labels}, &ret)
+	return ret, err
+}
+
+// Insert inserts tags of specified labels. existing tags are ignored.
+func (t *TagRepo) Insert(labels []string) error {
+	return t.c.call("tag/Insert", &request{Labels: labels}, nil)
+}
+
+// Delete deletes a tag by specified ID
+func (t *TagRepo) Delete(id int) error {
+	return t.c.call("tag/Delete", &request{ID: id}, nil)
+}
+
+// SummaryRepo is a SummaryRepository on a remote store
+type SummaryRepo struct {
+	c *conn
+}
+
+// NewSummaryRepo returns a SummaryRepo on specified URL
+func NewSummaryRepo(url string, hc *http.Client) *SummaryRepo {
+	return &SummaryRepo{c: newConn(url, hc)}
+}
+
+// ElapsedByDesc returns total elapsed time of each desc
+func (s *SummaryRepo) ElapsedByDesc(q *kokizami.SummaryQuery) ([]*kokizami.Elapsed, error) {
+	var ret []*kokizami.Elapsed
+	err := s.c.call("summary/ElapsedByDesc", &request{Query: q}, &ret)
+	return ret, err
+}
+
+// ElapsedByTag returns total elapsed time of each tag
+func (s *SummaryRepo) ElapsedByTag(q *kokizami.SummaryQuery) ([]*kokizami.Elapsed, error) {
+	var ret []*kokizami.Elapsed
+	err := s.c.call("summary/ElapsedByTag", &request{Query: q}, &ret)
+	return ret, err
+}
+
+// ElapsedOfDays returns total elapsed time in each of days
+func (s *SummaryRepo) ElapsedOfDays(q *kokizami.SummaryQuery, days []time.Time) ([]*kokizami.DailyElapsed, error) {
+	var ret []*kokizami.DailyElapsed
+	err := s.c.call("summary/ElapsedOfDays", &request{Query: q, Days: days}, &ret)
+	return ret, err
+}
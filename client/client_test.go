@@ -0,0 +1,313 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pankona/kokizami"
+	"github.com/pankona/kokizami/cmd/kkzm/repo/repotest"
+)
+
+var (
+	_ kokizami.KizamiRepository  = (*KizamiRepo)(nil)
+	_ kokizami.TagRepository     = (*TagRepo)(nil)
+	_ kokizami.SummaryRepository = (*SummaryRepo)(nil)
+)
+
+// setup returns a Kokizami on a temporary sqlite database,
+// and a Kokizami on it through a test server
+func setup(t *testing.T) (*kokizami.Kokizami, *kokizami.Kokizami, *httptest.Server) {
+	local := repotest.OpenTemp(t)
+
+	ts := httptest.NewServer(NewHandler(local))
+	t.Cleanup(ts.Close)
+
+	remote := New(ts.URL, ts.Client())
+	remote.Location = time.UTC
+	return local, remote, ts
+}
+
+func TestClient(t *testing.T) {
+	local, remote, _ := setup(t)
+
+	started, err := remote.Start("write docs #doc")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = remote.TagByDesc(started.ID, started.Desc)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = remote.Pause(started.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = remote.Resume(started.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	added, err := remote.Add("review #review",
+		time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = remote.TagByDesc(added.ID, added.Desc)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	added.Desc = "review again #review"
+	_, err = remote.Edit(added)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	// changes through the client are stored in the remote store
+	got, err := remote.List()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	want, err := local.List()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("unexpected result: (-got +want) %s", diff)
+	}
+	if len(got) != 2 || len(got[0].Segments) != 2 || got[0].ID != started.ID || !got[0].IsRunning() {
+		t.Errorf("unexpected result: [got] %+v [want] resumed kizami and added kizami", got)
+	}
+
+	filtered, err := remote.ListByFilter(&kokizami.KizamiFilter{Tags: []string{"#review"}})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(filtered) != 1 || filtered[0].Desc != "review again #review" {
+		t.Errorf("unexpected result: [got] %+v [want] edited kizami", filtered)
+	}
+
+	tags, err := remote.TagsByKizamiID(started.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(tags) != 1 || tags[0].Label != "#doc" {
+		t.Errorf("unexpected result: [got] %+v [want] #doc", tags)
+	}
+
+	es, err := remote.SummaryByTag("2024-03")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	wantElapsed := []*kokizami.Elapsed{{Tag: "#review", Desc: "review again #review", Count: 1, Elapsed: 90 * time.Minute}}
+	if diff := cmp.Diff(es, wantElapsed); diff != "" {
+		t.Errorf("unexpected result: (-got +want) %s", diff)
+	}
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	daily, err := remote.DailySummaryBetween(from, from.AddDate(0, 0, 2))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	wantDaily := []*kokizami.DailyElapsed{{Date: from, Elapsed: 90 * time.Minute}, {Date: from.AddDate(0, 0, 1)}}
+	if diff := cmp.Diff(daily, wantDaily); diff != "" {
+		t.Errorf("unexpected result: (-got +want) %s", diff)
+	}
+
+	err = remote.StopAll()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	stopped, err := local.Get(started.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if stopped.IsRunning() || stopped.IsPaused() {
+		t.Errorf("unexpected result: [got] %+v [want] stopped kizami", stopped)
+	}
+
+	err = remote.Delete(added.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	_, err = local.Get(added.ID)
+	if !errors.Is(err, kokizami.ErrNotFound) {
+		t.Errorf("unexpected result: [got] %v [want] %v", err, kokizami.ErrNotFound)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	_, remote, _ := setup(t)
+
+	started, err := remote.Start("write docs")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = remote.Stop(started.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	testcases := []struct {
+		inErr   error
+		wantErr error
+		wantMsg string
+	}{
+		{
+			inErr: func() error {
+				_, err := remote.Get(999)
+				return err
+			}(),
+			wantErr: kokizami.ErrNotFound,
+			wantMsg: "kizami [999] not found",
+		},
+		{
+			inErr:   remote.DeleteTag(999),
+			wantErr: kokizami.ErrNotFound,
+			wantMsg: "tag [999] not found",
+		},
+		{
+			inErr:   remote.Pause(started.ID),
+			wantErr: kokizami.ErrConflict,
+			wantMsg: "is not running",
+		},
+	}
+
+	for i, tc := range testcases {
+		if !errors.Is(tc.inErr, tc.wantErr) {
+			t.Errorf("[No.%d] unexpected result: [got] %v [want] %v", i, tc.inErr, tc.wantErr)
+			continue
+		}
+		if !strings.Contains(tc.inErr.Error(), tc.wantMsg) {
+			t.Errorf("[No.%d] unexpected result: [got] %v [want] %v", i, tc.inErr, tc.wantMsg)
+		}
+	}
+}
+
+func TestClientEvents(t *testing.T) {
+	local, remote, _ := setup(t)
+	events, cancel := local.Subscribe()
+	defer cancel()
+
+	// changes through the remote store reach subscribers of the local one
+	ki, err := remote.Start("write docs #doc")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = remote.TagByDesc(ki.ID, ki.Desc)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = remote.Pause(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = remote.Resume(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = remote.Stop(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = remote.Delete(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	want := []kokizami.EventType{
+		kokizami.EventStarted,
+		// tags are replaced by untagging and tagging
+		kokizami.EventTagged,
+		kokizami.EventTagged,
+		kokizami.EventPaused,
+		kokizami.EventResumed,
+		kokizami.EventStopped,
+		kokizami.EventEdited,
+		kokizami.EventDeleted,
+	}
+	var got []kokizami.EventType
+	for len(got) < len(want) {
+		select {
+		case e := <-events:
+			if e.Kizami.ID != ki.ID {
+				t.Errorf("unexpected result: [got] %v [want] %v", e.Kizami.ID, ki.ID)
+			}
+			got = append(got, e.Type)
+		case <-time.After(time.Second):
+			t.Fatalf("unexpected result: [got] %v [want] %v", got, want)
+		}
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("unexpected result (-got +want):\n%s", diff)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	_, _, ts := setup(t)
+
+	testcases := []struct {
+		inMethod   string
+		inPath     string
+		inHeader   map[string]string
+		inBody     string
+		wantStatus int
+	}{
+		{inMethod: http.MethodPost, inPath: "/repo/v1/kizami/FindAll", wantStatus: http.StatusOK},
+		{inMethod: http.MethodPost, inPath: "/repo/v1/kizami/Update", inBody: "{}", wantStatus: http.StatusBadRequest},
+		{inMethod: http.MethodPost, inPath: "/repo/v1/kizami/FindByID", inBody: "{", wantStatus: http.StatusBadRequest},
+		{inMethod: http.MethodPost, inPath: "/repo/v1/tag/Delete", inBody: `{"id": 1}`, wantStatus: http.StatusNotFound},
+		{inMethod: http.MethodGet, inPath: "/repo/v1/tag/FindAll", wantStatus: http.StatusMethodNotAllowed},
+		{inMethod: http.MethodPost, inPath: "/repo/v1/tag/Unknown", wantStatus: http.StatusNotFound},
+		{inMethod: http.MethodPost, inPath: "/repo/v2/tag/FindAll", wantStatus: http.StatusNotFound},
+		// requests of browsers are refused
+		{
+			inMethod:   http.MethodPost,
+			inPath:     "/repo/v1/tag/Insert",
+			inHeader:   map[string]string{"Content-Type": "text/plain"},
+			inBody:     `{"labels": ["#csrf"]}`,
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			inMethod:   http.MethodPost,
+			inPath:     "/repo/v1/tag/Insert",
+			inHeader:   map[string]string{"Origin": "http://localhost:3000"},
+			inBody:     `{"labels": ["#csrf"]}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			inMethod:   http.MethodPost,
+			inPath:     "/repo/v1/tag/Insert",
+			inHeader:   map[string]string{"Host": "evil.example.com"},
+			inBody:     `{"labels": ["#csrf"]}`,
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for i, tc := range testcases {
+		req, err := http.NewRequest(tc.inMethod, ts.URL+tc.inPath, strings.NewReader(tc.inBody))
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range tc.inHeader {
+			if k == "Host" {
+				req.Host = v
+				continue
+			}
+			req.Header.Set(k, v)
+		}
+		res, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		res.Body.Close()
+		if res.StatusCode != tc.wantStatus {
+			t.Errorf("[No.%d] unexpected result: [got] %v [want] %v", i, res.StatusCode, tc.wantStatus)
+		}
+	}
+}
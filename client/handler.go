@@ -0,0 +1,304 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pankona/kokizami"
+	"github.com/pankona/kokizami/httpapi"
+)
+
+// Prefix is the path prefix of repository operations served by Handler
+const Prefix = "/repo/v1"
+
+// Handler serves operations of repositories as JSON over HTTP.
+// each operation is served by POST {Prefix}/{repository}/{method},
+// such as POST /repo/v1/kizami/FindByID, with its arguments in request body.
+// requests from browsers, that have Origin header, are refused.
+type Handler struct {
+	KizamiRepo  kokizami.KizamiRepository
+	TagRepo     kokizami.TagRepository
+	SummaryRepo kokizami.SummaryRepository
+
+	// AllowHosts are names of the handler allowed in Host header besides
+	// loopback names and IP addresses, to block DNS rebinding.
+	AllowHosts []string
+
+	// Publish is called with Events of Kizamis changed by operations.
+	// nothing is published if nil.
+	Publish func(t kokizami.EventType, id int, ki *kokizami.Kizami)
+}
+
+// NewHandler returns a Handler that serves repositories of specified Kokizami.
+// changes by operations are published to subscribers of the Kokizami.
+func NewHandler(k *kokizami.Kokizami) *Handler {
+	return &Handler{
+		KizamiRepo:  k.KizamiRepo,
+		TagRepo:     k.TagRepo,
+		SummaryRepo: k.SummaryRepo,
+		Publish:     k.Publish,
+	}
+}
+
+// publish calls Publish if it is set
+func (h *Handler) publish(t kokizami.EventType, id int) {
+	if h.Publish != nil {
+		h.Publish(t, id, nil)
+	}
+}
+
+// isInitial returns true if t is the initial value of times that are not set yet
+func isInitial(t time.Time) bool {
+	return t.Unix() == 0
+}
+
+// request holds arguments of a repository operation.
+// fields not used by the operation are left empty.
+type request struct {
+	ID        int                    `json:"id,omitempty"`
+	KizamiID  int                    `json:"kizami_id,omitempty"`
+	Desc      string                 `json:"desc,omitempty"`
+	StartedAt time.Time              `json:"started_at,omitempty"`
+	StoppedAt time.Time              `json:"stopped_at,omitempty"`
+	TagIDs    []int                  `json:"tag_ids,omitempty"`
+	Labels    []string               `json:"labels,omitempty"`
+	Kizami    *kokizami.Kizami       `json:"kizami,omitempty"`
+	Segment   *kokizami.Segment      `json:"segment,omitempty"`
+	Filter    *kokizami.KizamiFilter `json:"filter,omitempty"`
+	Query     *kokizami.SummaryQuery `json:"query,omitempty"`
+	Days      []time.Time            `json:"days,omitempty"`
+}
+
+// response is the body of failed operations
+type response struct {
+	Error string `json:"error"`
+}
+
+// operation runs a repository operation and returns its result.
+// nil result is responded as no content.
+type operation func(req *request) (interface{}, error)
+
+// operations returns operations by "{repository}/{method}"
+func (h *Handler) operations() map[string]operation {
+	return map[string]operation{
+		"kizami/FindAll": func(_ *request) (interface{}, error) {
+			return h.KizamiRepo.FindAll()
+		},
+		"kizami/Insert": func(req *request) (interface{}, error) {
+			ki, err := h.KizamiRepo.Insert(req.Desc)
+			if err != nil {
+				return nil, err
+			}
+			h.publish(kokizami.EventStarted, ki.ID)
+			return ki, nil
+		},
+		"kizami/InsertWithTimes": func(req *request) (interface{}, error) {
+			ki, err := h.KizamiRepo.InsertWithTimes(req.Desc, req.StartedAt, req.StoppedAt)
+			if err != nil {
+				return nil, err
+			}
+			if isInitial(req.StoppedAt) {
+				h.publish(kokizami.EventStarted, ki.ID)
+			} else {
+				h.publish(kokizami.EventAdded, ki.ID)
+			}
+			return ki, nil
+		},
+		"kizami/Update": func(req *request) (interface{}, error) {
+			if req.Kizami == nil {
+				return nil, fmt.Errorf("kizami is not specified: %w", kokizami.ErrInvalid)
+			}
+			// stopping is told from editing by the state before updated
+			old, err := h.KizamiRepo.FindByID(req.Kizami.ID)
+			if err != nil {
+				return nil, err
+			}
+			err = h.KizamiRepo.Update(req.Kizami)
+			if err != nil {
+				return nil, err
+			}
+			if isInitial(old.StoppedAt) && !isInitial(req.Kizami.StoppedAt) {
+				h.publish(kokizami.EventStopped, req.Kizami.ID)
+			} else {
+				h.publish(kokizami.EventEdited, req.Kizami.ID)
+			}
+			return nil, nil
+		},
+		"kizami/Delete": func(req *request) (interface{}, error) {
+			if req.Kizami == nil {
+				return nil, fmt.Errorf("kizami is not specified: %w", kokizami.ErrInvalid)
+			}
+			err := h.KizamiRepo.Delete(req.Kizami)
+			if err != nil {
+				return nil, err
+			}
+			if h.Publish != nil {
+				h.Publish(kokizami.EventDeleted, req.Kizami.ID, req.Kizami)
+			}
+			return nil, nil
+		},
+		"kizami/FindByID": func(req *request) (interface{}, error) {
+			return h.KizamiRepo.FindByID(req.ID)
+		},
+		"kizami/FindByStoppedAt": func(req *request) (interface{}, error) {
+			return h.KizamiRepo.FindByStoppedAt(req.StoppedAt)
+		},
+		"kizami/FindByFilter": func(req *request) (interface{}, error) {
+			if req.Filter == nil {
+				req.Filter = &kokizami.KizamiFilter{}
+			}
+			return h.KizamiRepo.FindByFilter(req.Filter)
+		},
+		"kizami/Tagging": func(req *request) (interface{}, error) {
+			err := h.KizamiRepo.Tagging(req.KizamiID, req.TagIDs)
+			if err != nil {
+				return nil, err
+			}
+			h.publish(kokizami.EventTagged, req.KizamiID)
+			return nil, nil
+		},
+		"kizami/Untagging": func(req *request) (interface{}, error) {
+			err := h.KizamiRepo.Untagging(req.KizamiID)
+			if err != nil {
+				return nil, err
+			}
+			h.publish(kokizami.EventTagged, req.KizamiID)
+			return nil, nil
+		},
+		"kizami/InsertSegment": func(req *request) (interface{}, error) {
+			if req.Segment == nil {
+				return nil, fmt.Errorf("segment is not specified: %w", kokizami.ErrInvalid)
+			}
+			// ID of inserted segment is returned to the client
+			err := h.KizamiRepo.InsertSegment(req.Segment)
+			if err != nil {
+				return nil, err
+			}
+			// an open segment is inserted on resume, and
+			// a closed one on the first pause
+			if isInitial(req.Segment.StoppedAt) {
+				h.publish(kokizami.EventResumed, req.Segment.KizamiID)
+			} else {
+				h.publish(kokizami.EventPaused, req.Segment.KizamiID)
+			}
+			return req.Segment, nil
+		},
+		"kizami/UpdateSegment": func(req *request) (interface{}, error) {
+			if req.Segment == nil {
+				return nil, fmt.Errorf("segment is not specified: %w", kokizami.ErrInvalid)
+			}
+			err := h.KizamiRepo.UpdateSegment(req.Segment)
+			if err != nil {
+				return nil, err
+			}
+			// the open segment is closed on pause, and segments are
+			// updated after the kizami on stop and edit
+			ki, err := h.KizamiRepo.FindByID(req.Segment.KizamiID)
+			if err != nil {
+				// the change has been made already. only its notification is lost.
+				return nil, nil
+			}
+			if ki.IsPaused() {
+				h.publish(kokizami.EventPaused, ki.ID)
+			} else {
+				h.publish(kokizami.EventEdited, ki.ID)
+			}
+			return nil, nil
+		},
+		"tag/FindByID": func(req *request) (interface{}, error) {
+			return h.TagRepo.FindByID(req.ID)
+		},
+		"tag/FindAll": func(_ *request) (interface{}, error) {
+			return h.TagRepo.FindAll()
+		},
+		"tag/FindByKizamiID": func(req *request) (interface{}, error) {
+			return h.TagRepo.FindByKizamiID(req.KizamiID)
+		},
+		"tag/FindByLabels": func(req *request) (interface{}, error) {
+			return h.TagRepo.FindByLabels(req.Labels)
+		},
+		"tag/Insert": func(req *request) (interface{}, error) {
+			return nil, h.TagRepo.Insert(req.Labels)
+		},
+		"tag/Delete": func(req *request) (interface{}, error) {
+			return nil, h.TagRepo.Delete(req.ID)
+		},
+		"summary/ElapsedByDesc": func(req *request) (interface{}, error) {
+			if req.Query == nil {
+				return nil, fmt.Errorf("query is not specified: %w", kokizami.ErrInvalid)
+			}
+			return h.SummaryRepo.ElapsedByDesc(req.Query)
+		},
+		"summary/ElapsedByTag": func(req *request) (interface{}, error) {
+			if req.Query == nil {
+				return nil, fmt.Errorf("query is not specified: %w", kokizami.ErrInvalid)
+			}
+			return h.SummaryRepo.ElapsedByTag(req.Query)
+		},
+		"summary/ElapsedOfDays": func(req *request) (interface{}, error) {
+			if req.Query == nil {
+				return nil, fmt.Errorf("query is not specified: %w", kokizami.ErrInvalid)
+			}
+			return h.SummaryRepo.ElapsedOfDays(req.Query, req.Days)
+		},
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status, v := h.serve(r)
+	if v == nil {
+		w.WriteHeader(status)
+		return
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		status = http.StatusInternalServerError
+		b, _ = json.Marshal(&response{Error: err.Error()})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	// error of writing the response can not be reported anymore
+	_, _ = w.Write(append(b, '\n'))
+}
+
+// serve runs requested operation and returns status and body of the response
+func (h *Handler) serve(r *http.Request) (int, interface{}) {
+	name := strings.TrimPrefix(r.URL.Path, Prefix+"/")
+	op, ok := h.operations()[name]
+	if !strings.HasPrefix(r.URL.Path, Prefix+"/") || !ok {
+		return http.StatusNotFound, &response{Error: fmt.Sprintf("%s is not found", r.URL.Path)}
+	}
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed, &response{Error: fmt.Sprintf("method %s is not allowed", r.Method)}
+	}
+	if err := httpapi.CheckHost(r, h.AllowHosts...); err != nil {
+		return http.StatusForbidden, &response{Error: err.Error()}
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		return http.StatusForbidden, &response{Error: fmt.Sprintf("origin %s is not allowed", origin)}
+	}
+	if err := httpapi.RequireJSON(r); err != nil {
+		return http.StatusUnsupportedMediaType, &response{Error: err.Error()}
+	}
+
+	req := &request{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil && err != io.EOF {
+		return http.StatusBadRequest, &response{Error: fmt.Sprintf("invalid request body: %v", err)}
+	}
+
+	v, err := op(req)
+	if err != nil {
+		return httpapi.StatusOf(err), &response{Error: err.Error()}
+	}
+	if v == nil {
+		return http.StatusNoContent, nil
+	}
+	return http.StatusOK, v
+}
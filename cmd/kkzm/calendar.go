@@ -0,0 +1,456 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pankona/kokizami"
+	"github.com/urfave/cli"
+)
+
+// untitledEvent is desc of events without SUMMARY
+const untitledEvent = "untitled"
+
+// calendarOptions holds options to import events of iCalendar
+type calendarOptions struct {
+	// Location is timezone of floating times that have neither "Z" nor TZID
+	Location *time.Location
+
+	// From and To specify range of start of events to import in [From, To)
+	From time.Time
+	To   time.Time
+
+	// Categories maps lower-cased categories to tags.
+	// categories mapped to "" are ignored, and the others become tags of the same name.
+	Categories map[string]string
+	// Tags are added to all the events
+	Tags []string
+
+	// Attendee is address of the user. if specified, events that the
+	// attendee has not accepted are ignored.
+	Attendee string
+}
+
+// categoryTags returns tags of specified categories
+func (o *calendarOptions) categoryTags(categories []string) []string {
+	var tags []string
+	for _, v := range categories {
+		if t, ok := o.Categories[strings.ToLower(strings.TrimSpace(v))]; ok {
+			tags = append(tags, t)
+			continue
+		}
+		if t := normalizeTag(v); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return mergeTags(tags, o.Tags)
+}
+
+// accepted returns true if the attendee has accepted specified event.
+// events that the attendee organizes or is not invited to are taken as accepted.
+func (o *calendarOptions) accepted(c *icsComponent) bool {
+	if strings.EqualFold(c.Value("STATUS"), "CANCELLED") {
+		return false
+	}
+	if o.Attendee == "" {
+		return true
+	}
+
+	address := func(s string) string {
+		s = strings.TrimSpace(s)
+		if len(s) >= len("mailto:") && strings.EqualFold(s[:len("mailto:")], "mailto:") {
+			s = s[len("mailto:"):]
+		}
+		return s
+	}
+
+	if strings.EqualFold(address(c.Value("ORGANIZER")), o.Attendee) {
+		return true
+	}
+	for _, p := range c.Properties {
+		if p.Name != "ATTENDEE" || !strings.EqualFold(address(p.Value), o.Attendee) {
+			continue
+		}
+		// PARTSTAT defaults to NEEDS-ACTION
+		return strings.EqualFold(p.Params["PARTSTAT"], "ACCEPTED")
+	}
+	return true
+}
+
+func calendarFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "from",
+			Usage: "import events started at or after specified time (yyyy-mm-dd [hh:mm], hh:mm, or relative like -24h)",
+		},
+		cli.StringFlag{
+			Name:  "to",
+			Usage: "import events started before specified time (yyyy-mm-dd [hh:mm], hh:mm, or relative like -24h). default is now",
+		},
+		cli.StringSliceFlag{
+			Name:  "category",
+			Usage: "specify tag of a category (e.g. --category Meeting=mtg). empty tag ignores the category. can be specified multiple times",
+		},
+		cli.StringSliceFlag{
+			Name:  "tag",
+			Usage: "specify tag added to all the events. can be specified multiple times",
+		},
+		cli.StringFlag{
+			Name:  "attendee",
+			Usage: "specify your address to import only events that you have accepted",
+		},
+		cli.BoolFlag{
+			Name:  "force",
+			Usage: "import events even if they overlap existing tasks",
+		},
+		cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "show events to be imported and skipped without importing",
+		},
+	}
+}
+
+func importICSCommand() cli.Command {
+	return cli.Command{
+		Name:      "import-ics",
+		Usage:     "import finished events of an iCalendar file as tasks",
+		ArgsUsage: "[file (\"-\" for stdin)]",
+		Action:    CmdImportICS,
+		Flags:     calendarFlags(),
+	}
+}
+
+// calendarOptionsFromFlags returns options to import events specified by flags
+func calendarOptionsFromFlags(c *cli.Context, loc *time.Location, now time.Time) (*calendarOptions, error) {
+	opts := &calendarOptions{
+		Location:   loc,
+		To:         now,
+		Categories: map[string]string{},
+		Tags:       mergeTags(c.StringSlice("tag")),
+		Attendee:   strings.TrimSpace(c.String("attendee")),
+	}
+
+	if !c.IsSet("from") {
+		return nil, fmt.Errorf("--from must be specified")
+	}
+	var err error
+	opts.From, err = parseTimeValue(c.String("from"), time.Time{}, now)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --from: %v", err)
+	}
+	if c.IsSet("to") {
+		opts.To, err = parseTimeValue(c.String("to"), time.Time{}, now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --to: %v", err)
+		}
+	}
+	if !opts.From.Before(opts.To) {
+		return nil, fmt.Errorf("--from (%v) must be before --to (%v)", opts.From, opts.To)
+	}
+
+	for _, v := range c.StringSlice("category") {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid --category %s. should be like category=tag", v)
+		}
+		tag := ""
+		if tags := mergeTags([]string{kv[1]}); len(tags) != 0 {
+			tag = tags[0]
+		}
+		opts.Categories[strings.ToLower(strings.TrimSpace(kv[0]))] = tag
+	}
+
+	return opts, nil
+}
+
+// calendarEvents returns entries of accepted events that start in the range of options.
+// recurring events are expanded, and their occurrences excluded by EXDATE or
+// overridden by RECURRENCE-ID are removed. all-day events are ignored.
+func calendarEvents(cs []*icsComponent, opts *calendarOptions) ([]*entry, error) {
+	var events []*icsComponent
+	for _, c := range cs {
+		for _, v := range c.Components {
+			if v.Name == "VEVENT" {
+				events = append(events, v)
+			}
+		}
+	}
+
+	var errs rowErrors
+
+	// occurrences overridden by other VEVENTs of the same UID
+	overridden := map[string]map[int64]bool{}
+	for _, v := range events {
+		p := v.Property("RECURRENCE-ID")
+		if p == nil {
+			continue
+		}
+		t, _, err := parseICSTime(p, opts.Location)
+		if err != nil {
+			errs = append(errs, &rowError{Line: v.Line, Err: fmt.Errorf("invalid RECURRENCE-ID: %v", err)})
+			continue
+		}
+		uid := v.Value("UID")
+		if overridden[uid] == nil {
+			overridden[uid] = map[int64]bool{}
+		}
+		overridden[uid][t.Unix()] = true
+	}
+
+	var es []*entry
+	for _, v := range events {
+		if !opts.accepted(v) {
+			continue
+		}
+
+		starts, d, err := eventOccurrences(v, opts, overridden[v.Value("UID")])
+		if err != nil {
+			errs = append(errs, &rowError{Line: v.Line, Err: err})
+			continue
+		}
+		if d <= 0 {
+			// events without duration have no time to import
+			continue
+		}
+
+		summary := untitledEvent
+		if p := v.Property("SUMMARY"); p != nil && strings.TrimSpace(p.Value) != "" {
+			summary = strings.Join(splitICSText(p.Value), ",")
+		}
+		var categories []string
+		for _, p := range v.Properties {
+			if p.Name == "CATEGORIES" {
+				categories = append(categories, splitICSText(p.Value)...)
+			}
+		}
+		tags := opts.categoryTags(categories)
+
+		for _, t := range starts {
+			if t.Before(opts.From) || !t.Before(opts.To) {
+				continue
+			}
+			es = append(es, &entry{
+				Desc:      descWithTags(summary, tags),
+				Tags:      tags,
+				StartedAt: t,
+				StoppedAt: t.Add(d),
+				Line:      v.Line,
+			})
+		}
+	}
+
+	sort.SliceStable(es, func(i, j int) bool {
+		return es[i].StartedAt.Before(es[j].StartedAt)
+	})
+
+	if len(errs) != 0 {
+		return es, errs
+	}
+	return es, nil
+}
+
+// eventOccurrences returns start times and duration of specified VEVENT.
+// nothing is returned for all-day events.
+func eventOccurrences(c *icsComponent, opts *calendarOptions, overridden map[int64]bool) ([]time.Time, time.Duration, error) {
+	p := c.Property("DTSTART")
+	if p == nil {
+		return nil, 0, fmt.Errorf("VEVENT must have DTSTART")
+	}
+	start, allDay, err := parseICSTime(p, opts.Location)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid DTSTART: %v", err)
+	}
+	if allDay {
+		return nil, 0, nil
+	}
+
+	var d time.Duration
+	if p := c.Property("DTEND"); p != nil {
+		end, _, err := parseICSTime(p, opts.Location)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid DTEND: %v", err)
+		}
+		d = end.Sub(start)
+	} else if p := c.Property("DURATION"); p != nil {
+		d, err = parseICSDuration(p.Value)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	p = c.Property("RRULE")
+	if p == nil || c.Property("RECURRENCE-ID") != nil {
+		return []time.Time{start}, d, nil
+	}
+
+	r, err := parseICSRule(p.Value, start.Location())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	excluded := map[int64]bool{}
+	for k := range overridden {
+		excluded[k] = true
+	}
+	for _, v := range c.Properties {
+		if v.Name != "EXDATE" {
+			continue
+		}
+		for _, s := range strings.Split(v.Value, ",") {
+			t, _, err := parseICSTime(&icsProperty{Params: v.Params, Value: s}, start.Location())
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid EXDATE: %v", err)
+			}
+			excluded[t.Unix()] = true
+		}
+	}
+
+	var starts []time.Time
+	for _, t := range r.occurrences(start, opts.To) {
+		if !excluded[t.Unix()] {
+			starts = append(starts, t)
+		}
+	}
+	return starts, d, nil
+}
+
+// overlappedKizami returns a kizami that overlaps specified entry.
+// on-going kizamis are taken as they continue until now.
+func overlappedKizami(ks []*kokizami.Kizami, e *entry, now time.Time) *kokizami.Kizami {
+	overlaps := func(start, stop time.Time) bool {
+		if stop.Unix() == 0 {
+			stop = now
+		}
+		return start.Before(e.StoppedAt) && e.StartedAt.Before(stop)
+	}
+
+	for _, k := range ks {
+		if len(k.Segments) == 0 {
+			if overlaps(k.StartedAt, k.StoppedAt) {
+				return k
+			}
+			continue
+		}
+		for _, s := range k.Segments {
+			if overlaps(s.StartedAt, s.StoppedAt) {
+				return k
+			}
+		}
+	}
+	return nil
+}
+
+// CmdImportICS imports events of an iCalendar file
+// kokizami import-ics --from 2024-03-01 calendar.ics
+// kokizami import-ics --from 2024-03-01 --category Meeting=mtg --force calendar.ics
+func CmdImportICS(c *cli.Context) error {
+	args := c.Args()
+	if len(args) != 1 {
+		return fmt.Errorf("import-ics needs one argument [file]")
+	}
+
+	kkzm := kkzm(c)
+	now := currentTime(c)
+	opts, err := calendarOptionsFromFlags(c, kkzm.Location, now)
+	if err != nil {
+		return err
+	}
+
+	es, err := readEntries(args[0], func(r io.Reader, _ *transferOptions) ([]*entry, error) {
+		cs, err := parseICS(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse iCalendar: %v", err)
+		}
+		return calendarEvents(cs, opts)
+	}, nil)
+	errs, ok := err.(rowErrors)
+	if err != nil && !ok {
+		return err
+	}
+
+	return inTransaction(database(c), kkzm.Location, func(kkzm *kokizami.Kokizami) error {
+		return importEvents(os.Stdout, kkzm, es, errs, now, c.Bool("force"), c.Bool("dry-run"))
+	})
+}
+
+// importEvents imports entries of events as finished kizamis.
+// events that are malformed, have not finished yet, have been imported already,
+// or overlap existing kizamis or former events are reported and skipped.
+// errs holds errors of malformed events.
+// overlapping events are imported if force is true.
+func importEvents(w io.Writer, kkzm *kokizami.Kokizami, es []*entry, errs rowErrors, now time.Time, force, dryRun bool) error {
+	var until time.Time
+	for _, e := range es {
+		if e.StoppedAt.After(until) {
+			until = e.StoppedAt
+		}
+	}
+	ks, err := kkzm.ListByFilter(&kokizami.KizamiFilter{Until: until})
+	if err != nil {
+		return err
+	}
+
+	var imported, skipped int
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Line < errs[j].Line
+	})
+	for _, v := range errs {
+		skipped++
+		fmt.Fprintf(w, "line %d: %v, skipped\n", v.Line, v.Err)
+	}
+
+	// lines of events that are imported in this time
+	lines := map[*kokizami.Kizami]int{}
+	for _, e := range es {
+		when := e.StartedAt.In(kkzm.Location).Format("2006-01-02 15:04")
+
+		if e.StoppedAt.After(now) {
+			skipped++
+			fmt.Fprintf(w, "line %d: %s (%s) has not finished yet, skipped\n", e.Line, e.Desc, when)
+			continue
+		}
+
+		k, err := findConflict(kkzm, e)
+		if err != nil {
+			return err
+		}
+		if k != nil {
+			skipped++
+			fmt.Fprintf(w, "line %d: %s (%s) has been imported as task %d, skipped\n", e.Line, e.Desc, when, k.ID)
+			continue
+		}
+
+		if k := overlappedKizami(ks, e, now); k != nil && !force {
+			skipped++
+			if line, ok := lines[k]; ok {
+				fmt.Fprintf(w, "line %d: %s (%s) overlaps event on line %d, skipped\n", e.Line, e.Desc, when, line)
+				continue
+			}
+			fmt.Fprintf(w, "line %d: %s (%s) overlaps existing task %d (%s), skipped\n", e.Line, e.Desc, when, k.ID, k.Desc)
+			continue
+		}
+
+		k = &kokizami.Kizami{Desc: e.Desc, StartedAt: e.StartedAt, StoppedAt: e.StoppedAt}
+		if !dryRun {
+			k, err = importEntry(kkzm, e)
+			if err != nil {
+				return fmt.Errorf("line %d: failed to import: %v", e.Line, err)
+			}
+		}
+		// later events that overlap this event are skipped as well
+		ks = append(ks, k)
+		lines[k] = e.Line
+		imported++
+	}
+
+	if dryRun {
+		fmt.Fprintf(w, "%d event(s) will be imported, %d skipped (dry run)\n", imported, skipped)
+		return nil
+	}
+	fmt.Fprintf(w, "%d event(s) imported, %d skipped\n", imported, skipped)
+	return nil
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pankona/kokizami"
+	"github.com/pankona/kokizami/cmd/kkzm/repo/repotest"
+)
+
+const testCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup\r\n" +
+	"DTSTART;TZID=Asia/Tokyo:20240304T100000\r\n" +
+	"DTEND;TZID=Asia/Tokyo:20240304T103000\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4\r\n" +
+	"EXDATE;TZID=Asia/Tokyo:20240306T100000\r\n" +
+	"SUMMARY:Standup\r\n" +
+	"CATEGORIES:Meeting,Daily\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"ACTION:DISPLAY\r\n" +
+	"TRIGGER:-PT5M\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup\r\n" +
+	"RECURRENCE-ID;TZID=Asia/Tokyo:20240311T100000\r\n" +
+	"DTSTART;TZID=Asia/Tokyo:20240311T110000\r\n" +
+	"DURATION:PT45M\r\n" +
+	"SUMMARY:Standup (moved)\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:review\r\n" +
+	"DTSTART:20240305T050000Z\r\n" +
+	"DTEND:20240305T060000Z\r\n" +
+	"SUMMARY:Review\\, design of\r\n" +
+	"  parser\r\n" +
+	"ORGANIZER:mailto:boss@example.com\r\n" +
+	"ATTENDEE;CN=\"Me; myself\";PARTSTAT=DECLINED:mailto:me@example.com\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:cancelled\r\n" +
+	"DTSTART:20240305T070000Z\r\n" +
+	"DTEND:20240305T080000Z\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"SUMMARY:Cancelled\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:holiday\r\n" +
+	"DTSTART;VALUE=DATE:20240305\r\n" +
+	"SUMMARY:Holiday\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:out-of-range\r\n" +
+	"DTSTART:20240401T000000Z\r\n" +
+	"DTEND:20240401T010000Z\r\n" +
+	"SUMMARY:Next month\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestCalendarEvents(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("timezone database is not available: %v", err)
+	}
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2024, 3, day, hour, min, 0, 0, tokyo)
+	}
+
+	cs, err := parseICS(strings.NewReader(testCalendar))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	tcs := []struct {
+		inAttendee string
+		wantDescs  []string
+		wantStarts []time.Time
+		wantStops  []time.Time
+	}{
+		{
+			// standup on 6th is excluded and one on 11th is moved.
+			// categories are mapped to tags or ignored.
+			wantDescs: []string{
+				"Standup #mtg",
+				"Review, design of parser",
+				"Standup (moved)",
+				"Standup #mtg",
+			},
+			wantStarts: []time.Time{at(4, 10, 0), at(5, 14, 0), at(11, 11, 0), at(13, 10, 0)},
+			wantStops:  []time.Time{at(4, 10, 30), at(5, 15, 0), at(11, 11, 45), at(13, 10, 30)},
+		},
+		{
+			// declined events are ignored
+			inAttendee: "Me@example.com",
+			wantDescs: []string{
+				"Standup #mtg",
+				"Standup (moved)",
+				"Standup #mtg",
+			},
+			wantStarts: []time.Time{at(4, 10, 0), at(11, 11, 0), at(13, 10, 0)},
+			wantStops:  []time.Time{at(4, 10, 30), at(11, 11, 45), at(13, 10, 30)},
+		},
+	}
+
+	for i, tc := range tcs {
+		es, err := calendarEvents(cs, &calendarOptions{
+			Location:   time.UTC,
+			From:       at(1, 0, 0),
+			To:         at(31, 0, 0),
+			Categories: map[string]string{"meeting": "#mtg", "daily": ""},
+			Attendee:   tc.inAttendee,
+		})
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+
+		var descs []string
+		var starts, stops []time.Time
+		for _, v := range es {
+			descs = append(descs, v.Desc)
+			starts = append(starts, v.StartedAt)
+			stops = append(stops, v.StoppedAt)
+		}
+		if diff := cmp.Diff(descs, tc.wantDescs); diff != "" {
+			t.Errorf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+		if diff := cmp.Diff(starts, tc.wantStarts); diff != "" {
+			t.Errorf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+		if diff := cmp.Diff(stops, tc.wantStops); diff != "" {
+			t.Errorf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+}
+
+func TestCalendarEventsError(t *testing.T) {
+	cs, err := parseICS(strings.NewReader("BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART:20240301T000000Z\r\n" +
+		"DTEND:20240301T010000Z\r\n" +
+		"RRULE:FREQ=MONTHLY;BYSETPOS=-1\r\n" +
+		"SUMMARY:unsupported\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART:20240302T000000Z\r\n" +
+		"DTEND:20240302T010000Z\r\n" +
+		"SUMMARY:supported\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	es, err := calendarEvents(cs, &calendarOptions{
+		Location: time.UTC,
+		From:     time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+	})
+	errs, ok := err.(rowErrors)
+	if !ok || len(errs) != 1 || errs[0].Line != 2 {
+		t.Fatalf("unexpected result: [got] %v [want] error of line 2", err)
+	}
+	if len(es) != 1 || es[0].Desc != "supported" {
+		t.Errorf("unexpected result: [got] %v [want] supported event", es)
+	}
+}
+
+func TestParseICSError(t *testing.T) {
+	tcs := []struct {
+		in string
+	}{
+		{in: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VCALENDAR\r\n"},
+		{in: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VEVENT\r\n"},
+		{in: "SUMMARY:out of component\r\n"},
+		{in: "BEGIN:VCALENDAR\r\nno colon\r\nEND:VCALENDAR\r\n"},
+	}
+
+	for i, tc := range tcs {
+		_, err := parseICS(strings.NewReader(tc.in))
+		if err == nil {
+			t.Errorf("[No.%d] unexpected result: [got] nil [want] error", i)
+		}
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone database is not available: %v", err)
+	}
+	start := time.Date(2024, 3, 8, 9, 0, 0, 0, ny)
+
+	tcs := []struct {
+		inRule string
+		inEnd  time.Time
+		want   []time.Time
+	}{
+		{
+			// wall clock is kept across DST
+			inRule: "FREQ=DAILY;INTERVAL=2",
+			inEnd:  time.Date(2024, 3, 13, 0, 0, 0, 0, ny),
+			want: []time.Time{
+				time.Date(2024, 3, 8, 9, 0, 0, 0, ny),
+				time.Date(2024, 3, 10, 9, 0, 0, 0, ny),
+				time.Date(2024, 3, 12, 9, 0, 0, 0, ny),
+			},
+		},
+		{
+			inRule: "FREQ=WEEKLY;BYDAY=MO,FR;COUNT=3",
+			inEnd:  time.Date(2025, 1, 1, 0, 0, 0, 0, ny),
+			want: []time.Time{
+				time.Date(2024, 3, 8, 9, 0, 0, 0, ny),
+				time.Date(2024, 3, 11, 9, 0, 0, 0, ny),
+				time.Date(2024, 3, 15, 9, 0, 0, 0, ny),
+			},
+		},
+		{
+			inRule: "FREQ=WEEKLY;UNTIL=20240322T130000Z",
+			inEnd:  time.Date(2025, 1, 1, 0, 0, 0, 0, ny),
+			want: []time.Time{
+				time.Date(2024, 3, 8, 9, 0, 0, 0, ny),
+				time.Date(2024, 3, 15, 9, 0, 0, 0, ny),
+				time.Date(2024, 3, 22, 9, 0, 0, 0, ny),
+			},
+		},
+		{
+			inRule: "FREQ=MONTHLY;COUNT=2",
+			inEnd:  time.Date(2025, 1, 1, 0, 0, 0, 0, ny),
+			want: []time.Time{
+				time.Date(2024, 3, 8, 9, 0, 0, 0, ny),
+				time.Date(2024, 4, 8, 9, 0, 0, 0, ny),
+			},
+		},
+		{
+			inRule: "FREQ=YEARLY",
+			inEnd:  time.Date(2026, 1, 1, 0, 0, 0, 0, ny),
+			want: []time.Time{
+				time.Date(2024, 3, 8, 9, 0, 0, 0, ny),
+				time.Date(2025, 3, 8, 9, 0, 0, 0, ny),
+			},
+		},
+	}
+
+	for i, tc := range tcs {
+		r, err := parseICSRule(tc.inRule, ny)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		got := r.occurrences(start, tc.inEnd)
+		if diff := cmp.Diff(got, tc.want); diff != "" {
+			t.Errorf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+}
+
+func TestOccurrencesSkipMissingDays(t *testing.T) {
+	r, err := parseICSRule("FREQ=MONTHLY;COUNT=3", time.UTC)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	got := r.occurrences(time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	want := []time.Time{
+		time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 31, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 5, 31, 9, 0, 0, 0, time.UTC),
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("unexpected result: (-got +want) %s", diff)
+	}
+}
+
+func TestParseICSDuration(t *testing.T) {
+	tcs := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "PT1H30M", want: 90 * time.Minute},
+		{in: "P1D", want: 24 * time.Hour},
+		{in: "P1W", want: 7 * 24 * time.Hour},
+		{in: "P1DT2H3M4S", want: 26*time.Hour + 3*time.Minute + 4*time.Second},
+		{in: "-PT5M", want: -5 * time.Minute},
+		{in: "PT", wantErr: true},
+		{in: "1H", wantErr: true},
+		{in: "P1H", wantErr: true},
+		{in: "PT1D", wantErr: true},
+		{in: "PT1", wantErr: true},
+	}
+
+	for i, tc := range tcs {
+		got, err := parseICSDuration(tc.in)
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want error] %v", i, err, tc.wantErr)
+		}
+		if got != tc.want {
+			t.Errorf("[No.%d] unexpected result: [got] %v [want] %v", i, got, tc.want)
+		}
+	}
+}
+
+func TestOverlappedKizami(t *testing.T) {
+	base := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	now := base.Add(24 * time.Hour)
+
+	ks := []*kokizami.Kizami{
+		{ID: 1, StartedAt: base, StoppedAt: base.Add(time.Hour)},
+		{
+			// paused between 11:00 and 12:00
+			ID:        2,
+			StartedAt: base.Add(time.Hour),
+			StoppedAt: base.Add(4 * time.Hour),
+			Segments: []kokizami.Segment{
+				{StartedAt: base.Add(time.Hour), StoppedAt: base.Add(2 * time.Hour)},
+				{StartedAt: base.Add(3 * time.Hour), StoppedAt: base.Add(4 * time.Hour)},
+			},
+		},
+		{ID: 3, StartedAt: base.Add(8 * time.Hour), StoppedAt: time.Unix(0, 0)},
+	}
+
+	tcs := []struct {
+		inStart time.Duration
+		inStop  time.Duration
+		want    int
+	}{
+		{inStart: 30 * time.Minute, inStop: 90 * time.Minute, want: 1},
+		{inStart: 2 * time.Hour, inStop: 3 * time.Hour, want: 0},
+		{inStart: 150 * time.Minute, inStop: 210 * time.Minute, want: 2},
+		{inStart: 5 * time.Hour, inStop: 6 * time.Hour, want: 0},
+		{inStart: 10 * time.Hour, inStop: 11 * time.Hour, want: 3},
+	}
+
+	for i, tc := range tcs {
+		k := overlappedKizami(ks, &entry{StartedAt: base.Add(tc.inStart), StoppedAt: base.Add(tc.inStop)}, now)
+		got := 0
+		if k != nil {
+			got = k.ID
+		}
+		if got != tc.want {
+			t.Errorf("[No.%d] unexpected result: [got] %v [want] %v", i, got, tc.want)
+		}
+	}
+}
+
+func TestImportEvents(t *testing.T) {
+	kkzm := repotest.OpenTemp(t)
+	base := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	now := base.Add(24 * time.Hour)
+
+	es := []*entry{
+		{Desc: "standup", StartedAt: base, StoppedAt: base.Add(30 * time.Minute), Line: 3},
+		{Desc: "review", StartedAt: base.Add(15 * time.Minute), StoppedAt: base.Add(time.Hour), Line: 10},
+		{Desc: "lunch", StartedAt: base.Add(3 * time.Hour), StoppedAt: base.Add(4 * time.Hour), Line: 20},
+	}
+	errs := rowErrors{{Line: 15, Err: fmt.Errorf("VEVENT must have DTSTART")}}
+
+	buf := &bytes.Buffer{}
+	err := importEvents(buf, kkzm, es, errs, now, false, false)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	// malformed events and events overlapping former ones are skipped
+	want := "line 15: VEVENT must have DTSTART, skipped\n" +
+		"line 10: review (2024-03-01 09:15) overlaps event on line 3, skipped\n" +
+		"2 event(s) imported, 2 skipped\n"
+	if diff := cmp.Diff(buf.String(), want); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	ks, err := kkzm.List()
+	if err != nil || len(ks) != 2 {
+		t.Fatalf("unexpected result: [got] %v, %v [want] 2 kizamis", ks, err)
+	}
+}
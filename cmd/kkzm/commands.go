@@ -22,6 +22,17 @@ func globalFlags() []cli.Flag {
 			Name:  "verbose",
 			Usage: "specify to enable verbose mode",
 		},
+		cli.StringFlag{
+			Name:   "timezone, tz",
+			Usage:  "specify timezone to compute boundaries of summaries (e.g. Asia/Tokyo). default is local",
+			EnvVar: "KKZM_TIMEZONE",
+		},
+		cli.StringFlag{
+			Name:   "format",
+			Value:  formatTable,
+			Usage:  "specify output format of list, summary and tags (" + strings.Join(formats, ", ") + ")",
+			EnvVar: "KKZM_FORMAT",
+		},
 	}
 }
 
@@ -31,38 +42,85 @@ func commands() []cli.Command {
 			Name:   "start",
 			Usage:  "start new task",
 			Action: CmdStart,
-			Flags: []cli.Flag{
+			Flags: append([]cli.Flag{
 				cli.BoolFlag{
 					Name:  "s, stop",
 					Usage: "stop all on-going kizami in advance",
 				},
+			}, atFlags()...),
+		},
+		{
+			Name:      "add",
+			Usage:     "add finished task retroactively",
+			ArgsUsage: "[desc]",
+			Action:    CmdAdd,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "from",
+					Usage: "specify started_at (hh:mm, yyyy-mm-dd hh:mm, or relative like -2h)",
+				},
+				cli.StringFlag{
+					Name:  "to",
+					Usage: "specify stopped_at (hh:mm, yyyy-mm-dd hh:mm, or relative like -30m). default is now",
+				},
+				cli.StringFlag{
+					Name:  "d, duration",
+					Usage: "specify duration of the task (e.g. 1h30m) instead of --from or --to",
+				},
 			},
 		},
 		{
 			Name:   "restart",
 			Usage:  "restart old task",
 			Action: CmdRestart,
-			Flags: []cli.Flag{
+			Flags: append([]cli.Flag{
 				cli.BoolFlag{
 					Name:  "s, stop",
 					Usage: "stop all on-going kizami in advance",
 				},
-			},
+			}, atFlags()...),
 		},
 		{
-			Name:   "edit",
-			Usage:  "edit task",
-			Action: CmdEdit,
+			Name:      "edit",
+			Usage:     "edit task",
+			ArgsUsage: "[id] ([desc|started_at|stopped_at] [new value])",
+			Action:    CmdEdit,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "desc",
+					Usage: "specify new desc. tags are extracted again",
+				},
+				cli.StringFlag{
+					Name:  "start",
+					Usage: "specify new started_at (hh:mm, yyyy-mm-dd hh:mm, or relative like +15m, -1h)",
+				},
+				cli.StringFlag{
+					Name:  "stop",
+					Usage: "specify new stopped_at (hh:mm, yyyy-mm-dd hh:mm, relative like +15m, -1h, or \"-\" for on-going)",
+				},
+			},
 		},
 		{
 			Name:   "list",
-			Usage:  "show list of tasks",
+			Usage:  "show list of tasks (last 20 tasks by default)",
 			Action: CmdList,
+			Flags:  append(listFlags(), templateFlags()...),
 		},
 		{
 			Name:   "stop",
 			Usage:  "stop task",
 			Action: CmdStop,
+			Flags:  atFlags(),
+		},
+		{
+			Name:   "pause",
+			Usage:  "pause task",
+			Action: CmdPause,
+		},
+		{
+			Name:   "resume",
+			Usage:  "resume paused task",
+			Action: CmdResume,
 		},
 		{
 			Name:   "delete",
@@ -71,15 +129,18 @@ func commands() []cli.Command {
 		},
 		{
 			Name:   "summary",
-			Usage:  "show summary of specified month",
+			Usage:  "show summary of specified period (this month by default)",
 			Action: CmdSummary,
-			Flags: []cli.Flag{
-				cli.StringFlag{
-					Name:  "m, month",
-					Value: thisMonth(),
-					Usage: "specify year and month to show summary",
+			Flags: append(append(periodFlags(),
+				cli.BoolFlag{
+					Name:  "prorate",
+					Usage: "count only the time fell inside the period for tasks across its boundaries",
 				},
-			},
+				cli.BoolFlag{
+					Name:  "r, running",
+					Usage: "include on-going tasks until now. they are marked with \"*\"",
+				},
+			), templateFlags()...),
 		},
 		{
 			Name:   "tags",
@@ -92,6 +153,15 @@ func commands() []cli.Command {
 				},
 			},
 		},
+		exportCommand(),
+		importCommand(),
+		importICSCommand(),
+		dumpCommand(),
+		restoreCommand(),
+		serveCommand(),
+		webCommand(),
+		tuiCommand(),
+		dbCommand(),
 	}
 
 }
@@ -103,10 +173,6 @@ func CommandNotFound(c *cli.Context, command string) {
 	os.Exit(2)
 }
 
-func thisMonth() string {
-	return time.Now().Format("2006-01")
-}
-
 func round(d, r time.Duration) time.Duration {
 	if r <= 0 {
 		return d
@@ -126,34 +192,34 @@ func round(d, r time.Duration) time.Duration {
 	return d
 }
 
-func toString(k *kokizami.Kizami) string {
-	var stoppedAt string
-	if k.StoppedAt.Unix() == 0 {
-		stoppedAt = "*" + time.Now().In(time.Local).Format("2006-01-02 15:04:05")
-	} else {
-		stoppedAt = k.StoppedAt.In(time.Local).Format("2006-01-02 15:04:05")
+// stoppedAtString returns stopped_at of specified kizami to show.
+// on-going kizami shows current time with "*" prefix,
+// and paused kizami shows the time it was paused with "=" prefix.
+func stoppedAtString(k *kokizami.Kizami, loc *time.Location) string {
+	switch {
+	case k.IsPaused():
+		return "=" + k.Segments[len(k.Segments)-1].StoppedAt.In(loc).Format("2006-01-02 15:04:05")
+	case k.IsRunning():
+		return "*" + time.Now().In(loc).Format("2006-01-02 15:04:05")
+	default:
+		return k.StoppedAt.In(loc).Format("2006-01-02 15:04:05")
 	}
+}
 
+func toString(k *kokizami.Kizami, loc *time.Location) string {
 	return strconv.Itoa(k.ID) + "\t" +
 		k.Desc + "\t" +
-		k.StartedAt.In(time.Local).Format("2006-01-02 15:04:05") + "\t" +
-		stoppedAt + "\t" +
+		k.StartedAt.In(loc).Format("2006-01-02 15:04:05") + "\t" +
+		stoppedAtString(k, loc) + "\t" +
 		round(k.Elapsed(), time.Second).String()
 }
 
-func toStringArray(k *kokizami.Kizami) []string {
-	var stoppedAt string
-	if k.StoppedAt.Unix() == 0 {
-		stoppedAt = "*" + time.Now().In(time.Local).Format("2006-01-02 15:04:05")
-	} else {
-		stoppedAt = k.StoppedAt.In(time.Local).Format("2006-01-02 15:04:05")
-	}
-
+func toStringArray(k *kokizami.Kizami, loc *time.Location) []string {
 	return []string{
 		strconv.Itoa(k.ID),
 		k.Desc,
-		k.StartedAt.In(time.Local).Format("2006-01-02 15:04:05"),
-		stoppedAt,
+		k.StartedAt.In(loc).Format("2006-01-02 15:04:05"),
+		stoppedAtString(k, loc),
 		round(k.Elapsed(), time.Second).String(),
 	}
 }
@@ -163,8 +229,15 @@ func kkzm(c *cli.Context) *kokizami.Kokizami {
 	return c.App.Metadata["kkzm"].(*kokizami.Kokizami)
 }
 
+// currentTime returns now in the timezone of --timezone.
+// times specified on command line are parsed in it.
+func currentTime(c *cli.Context) time.Time {
+	return time.Now().In(kkzm(c).Location)
+}
+
 // CmdStart starts a new task
 // kokizami start [new desc]
+// kokizami start --at 09:15 [new desc] ... start a task at specified time
 func CmdStart(c *cli.Context) error {
 	args := c.Args()
 
@@ -192,51 +265,118 @@ func CmdStart(c *cli.Context) error {
 		return fmt.Errorf("start needs one arguments [desc]")
 	}
 
-	kkzm := kkzm(c)
+	return start(c, kkzm(c), desc)
+}
 
-	if c.GlobalBool("stop") {
-		err := kkzm.StopAll()
+// start starts a new task with specified desc.
+// the time to start is specified by --at or --ago, otherwise now.
+func start(c *cli.Context, kkzm *kokizami.Kokizami, desc string) error {
+	t, specified, err := timeFromFlags(c, currentTime(c))
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("stop") {
+		if specified {
+			err = kkzm.StopAllAt(t)
+		} else {
+			err = kkzm.StopAll()
+		}
 		if err != nil {
 			return err
 		}
 	}
 
-	k, err := kkzm.Start(desc)
+	var k *kokizami.Kizami
+	if specified {
+		k, err = kkzm.StartAt(desc, t)
+	} else {
+		k, err = kkzm.Start(desc)
+	}
 	if err != nil {
 		return err
 	}
-	fmt.Println(toString(k))
+	fmt.Println(toString(k, kkzm.Location))
 
-	return tagging(kkzm, k.ID, desc)
+	return kkzm.TagByDesc(k.ID, desc)
 }
 
-func tagging(kkzm *kokizami.Kokizami, kizamiID int, desc string) error {
-	// remove all tags from specified kizami first
-	err := kkzm.Untagging(kizamiID)
+// CmdAdd adds a finished task
+// kokizami add [desc] --from [time] --to [time]
+// kokizami add [desc] --duration [duration]
+func CmdAdd(c *cli.Context) error {
+	args := c.Args()
+	if len(args) != 1 {
+		return fmt.Errorf("add needs one arguments [desc]")
+	}
+	desc := args[0]
+
+	from, to, err := rangeFromAddFlags(c, currentTime(c))
 	if err != nil {
 		return err
 	}
 
-	tags := extractTagsFromString(desc)
-	if len(tags) == 0 {
-		return nil
-	}
+	kkzm := kkzm(c)
 
-	err = kkzm.AddTags(tags)
+	k, err := kkzm.Add(desc, from, to)
 	if err != nil {
 		return err
 	}
+	fmt.Println(toString(k, kkzm.Location))
 
-	tagIDs := make([]int, len(tags))
-	ts, err := kkzm.TagsByLabels(tags)
-	if err != nil {
-		return err
+	return kkzm.TagByDesc(k.ID, desc)
+}
+
+// rangeFromAddFlags returns started_at and stopped_at specified
+// by combination of --from, --to and --duration
+func rangeFromAddFlags(c *cli.Context, now time.Time) (time.Time, time.Time, error) {
+	var (
+		from, to time.Time
+		d        time.Duration
+		err      error
+	)
+
+	if c.IsSet("duration") {
+		d, err = time.ParseDuration(c.String("duration"))
+		if err != nil {
+			return from, to, fmt.Errorf("invalid duration: %v", err)
+		}
+		if d <= 0 {
+			return from, to, fmt.Errorf("duration must be positive: %s", c.String("duration"))
+		}
 	}
-	for i, v := range ts {
-		tagIDs[i] = v.ID
+
+	if c.IsSet("from") {
+		from, err = parseTimeValue(c.String("from"), time.Time{}, now)
+		if err != nil {
+			return from, to, err
+		}
 	}
 
-	return kkzm.Tagging(kizamiID, tagIDs)
+	if c.IsSet("to") {
+		to, err = parseTimeValue(c.String("to"), time.Time{}, now)
+		if err != nil {
+			return from, to, err
+		}
+	}
+
+	switch {
+	case c.IsSet("from") && c.IsSet("to") && c.IsSet("duration"):
+		return from, to, fmt.Errorf("--from, --to and --duration can not be specified at once")
+	case c.IsSet("from") && c.IsSet("duration"):
+		to = from.Add(d)
+	case c.IsSet("from") && !c.IsSet("to"):
+		to = now
+	case c.IsSet("to") && c.IsSet("duration"):
+		from = to.Add(-d)
+	case c.IsSet("duration"):
+		to = now
+		from = to.Add(-d)
+	case !c.IsSet("from"):
+		return from, to, fmt.Errorf("--from or --duration must be specified")
+	}
+
+	return from, to, nil
 }
 
 // CmdRestart starts a task from old task list
@@ -254,59 +394,142 @@ func CmdRestart(c *cli.Context) error {
 
 	kkzm := kkzm(c)
 
-	if c.GlobalBool("stop") {
-		err := kkzm.StopAll()
-		if err != nil {
-			return err
-		}
+	k, err := kkzm.Get(id)
+	if err != nil {
+		return err
 	}
 
-	k, err := kkzm.Get(id)
+	return start(c, kkzm, k.Desc)
+}
+
+// CmdEdit edits a specified task
+// kokizami edit [id]                              ... edit with text editor
+// kokizami edit [id] --desc [desc] --start [time] ... edit specified fields
+// kokizami edit [id] [field] [new value]          ... edit specified field
+func CmdEdit(c *cli.Context) error {
+	args := c.Args()
+	if len(args) != 1 && len(args) != 3 {
+		return fmt.Errorf("edit needs one argument (id) with options, or three arguments (id, [desc|started_at|stopped_at], [new value])")
+	}
+
+	id, err := strconv.Atoi(args[0])
 	if err != nil {
 		return err
 	}
 
-	k, err = kkzm.Start(k.Desc)
+	fields := map[string]string{}
+	if len(args) == 3 {
+		fields[args[1]] = args[2]
+	}
+	for flag, field := range map[string]string{
+		"desc":  "desc",
+		"start": "started_at",
+		"stop":  "stopped_at",
+	} {
+		if c.IsSet(flag) {
+			fields[field] = c.String(flag)
+		}
+	}
+
+	var k *kokizami.Kizami
+	if len(fields) == 0 {
+		// the whole of task will be edited with text editor
+		k, err = editTaskWithEditor(kkzm(c), id)
+	} else {
+		k, err = editFields(kkzm(c), id, fields, currentTime(c))
+	}
 	if err != nil {
 		return err
 	}
-	fmt.Println(toString(k))
 
-	return tagging(kkzm, k.ID, k.Desc)
+	fmt.Println(toString(k, kkzm(c).Location))
+	return nil
 }
 
-// CmdEdit edits a specified task
-func CmdEdit(c *cli.Context) error {
-	args := c.Args()
+// editFields edits specified fields of a task.
+// tags are extracted again by Edit if desc is edited.
+func editFields(kkzm *kokizami.Kokizami, id int, fields map[string]string, now time.Time) (*kokizami.Kizami, error) {
+	k, err := kkzm.Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve task by ID: %v", err)
+	}
 
-	// len(args) == 1 means that the whole of task will be edited with text editor
-	// e.g) kkzm edit [id]
-	if len(args) == 1 {
-		id, err := strconv.Atoi(args[0])
-		if err != nil {
-			return err
+	for field, value := range fields {
+		switch field {
+		case "desc":
+			if value == "" {
+				return nil, fmt.Errorf("desc must not be empty")
+			}
+			k.Desc = value
+		case "started_at":
+			k.StartedAt, err = parseTimeValue(value, k.StartedAt, now)
+		case "stopped_at":
+			if value == "-" {
+				k.StoppedAt = time.Unix(0, 0).UTC()
+				continue
+			}
+			k.StoppedAt, err = parseTimeValue(value, k.StoppedAt, now)
+		default:
+			return nil, fmt.Errorf("unknown field %s. should be one of desc, started_at or stopped_at", field)
 		}
-
-		k, err := editTaskWithEditor(kkzm(c), id)
 		if err != nil {
-			return err
+			return nil, err
 		}
-
-		fmt.Println(toString(k))
-		return nil
 	}
 
-	return fmt.Errorf("edit needs three arguments (id, [desc|started_at|stopped_at], [new value])")
+	return kkzm.Edit(k)
 }
 
 // CmdList shows kokizami list
 // kokizami list
 func CmdList(c *cli.Context) error {
-	l, err := kkzm(c).List()
+	f, err := filterFromFlags(c, currentTime(c), defaultListLimit)
 	if err != nil {
 		return err
 	}
 
+	format, err := outputFormat(c)
+	if err != nil {
+		return err
+	}
+
+	kkzm := kkzm(c)
+	t, err := outputTemplate(c, format, kkzm.Location)
+	if err != nil {
+		return err
+	}
+
+	l, err := kkzm.ListByFilter(f)
+	if err != nil {
+		return err
+	}
+
+	if t != nil {
+		items := make([]interface{}, len(l))
+		ds := make([]*kizamiData, len(l))
+		for i, v := range l {
+			ts, err := kkzm.TagsByKizamiID(v.ID)
+			if err != nil {
+				return err
+			}
+			ds[i] = newKizamiData(v, ts)
+			items[i] = ds[i]
+		}
+		return executeTemplate(os.Stdout, t, &reportData{Items: ds}, items)
+	}
+
+	if format != formatTable {
+		rs := make([]record, len(l))
+		for i, v := range l {
+			ts, err := kkzm.TagsByKizamiID(v.ID)
+			if err != nil {
+				return err
+			}
+			rs[i] = newKizamiRecord(v, ts, kkzm.Location)
+		}
+		return writeRecords(os.Stdout, format, kizamiHeader, rs)
+	}
+
 	if len(l) == 0 {
 		fmt.Println("list is empty")
 		return nil
@@ -324,7 +547,7 @@ func CmdList(c *cli.Context) error {
 
 	for _, v := range l {
 		v := v
-		table.Append(toStringArray(v))
+		table.Append(toStringArray(v, kkzm.Location))
 	}
 	table.Render()
 
@@ -335,10 +558,19 @@ func CmdList(c *cli.Context) error {
 // kokizami stop      ... stop all tasks they don't have stopped_at
 // kokizami stop [id] ... stop a task by specified id
 func CmdStop(c *cli.Context) error {
+	t, specified, err := timeFromFlags(c, currentTime(c))
+	if err != nil {
+		return err
+	}
+
 	args := c.Args()
 	switch len(args) {
 	case 0:
-		err := kkzm(c).StopAll()
+		if specified {
+			err = kkzm(c).StopAllAt(t)
+		} else {
+			err = kkzm(c).StopAll()
+		}
 		if err != nil {
 			return err
 		}
@@ -347,7 +579,11 @@ func CmdStop(c *cli.Context) error {
 		if err != nil {
 			return err
 		}
-		err = kkzm(c).Stop(id)
+		if specified {
+			err = kkzm(c).StopAt(id, t)
+		} else {
+			err = kkzm(c).Stop(id)
+		}
 		if err != nil {
 			return err
 		}
@@ -357,6 +593,41 @@ func CmdStop(c *cli.Context) error {
 	return nil
 }
 
+// CmdPause pauses specified task
+// kokizami pause      ... pause all on-going tasks
+// kokizami pause [id] ... pause a task by specified id
+func CmdPause(c *cli.Context) error {
+	args := c.Args()
+	switch len(args) {
+	case 0:
+		return kkzm(c).PauseAll()
+	case 1:
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return err
+		}
+		return kkzm(c).Pause(id)
+	default:
+		return fmt.Errorf("pause needs at most one arguments [id]")
+	}
+}
+
+// CmdResume resumes specified paused task
+// kokizami resume [id]
+func CmdResume(c *cli.Context) error {
+	args := c.Args()
+	if len(args) != 1 {
+		return fmt.Errorf("resume needs one arguments [id]")
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return err
+	}
+
+	return kkzm(c).Resume(id)
+}
+
 // CmdDelete deletes specified task
 // kokizami delete [id]
 func CmdDelete(c *cli.Context) error {
@@ -383,12 +654,12 @@ func editTaskWithEditor(kkzm *kokizami.Kokizami, id int) (*kokizami.Kizami, erro
 		if k.StoppedAt.Unix() == 0 {
 			return "-"
 		}
-		return k.StoppedAt.In(time.Local).Format("2006-01-02 15:04:05")
+		return k.StoppedAt.In(kkzm.Location).Format("2006-01-02 15:04:05")
 	}()
 
 	filename, err := editTextWithEditor(fmt.Sprintf("%s\n%s\n%s",
 		k.Desc,
-		k.StartedAt.In(time.Local).Format("2006-01-02 15:04:05"),
+		k.StartedAt.In(kkzm.Location).Format("2006-01-02 15:04:05"),
 		stoppedAt))
 	if err != nil {
 		return nil, fmt.Errorf("failed to edit text with editor: %v", err)
@@ -418,21 +689,17 @@ func editTaskWithEditor(kkzm *kokizami.Kokizami, id int) (*kokizami.Kizami, erro
 }
 
 func edit(kkzm *kokizami.Kokizami, k *kokizami.Kizami, id int, desc, start, stop string) (*kokizami.Kizami, error) {
-	startedAt, err := time.ParseInLocation("2006-01-02 15:04:05", start, time.Local)
+	startedAt, err := time.ParseInLocation("2006-01-02 15:04:05", start, kkzm.Location)
 	if err != nil {
 		return nil, err
 	}
 
-	if stop == "-" {
-		t, err := time.Parse("2006-01-02 15:04:05", "1970-01-01 00:00:00")
+	stoppedAt := time.Unix(0, 0).UTC()
+	if stop != "-" {
+		stoppedAt, err = time.ParseInLocation("2006-01-02 15:04:05", stop, kkzm.Location)
 		if err != nil {
 			return nil, err
 		}
-		stop = t.Local().Format("2006-01-02 15:04:05")
-	}
-	stoppedAt, err := time.ParseInLocation("2006-01-02 15:04:05", stop, time.Local)
-	if err != nil {
-		return nil, err
 	}
 
 	k.ID = id
@@ -440,12 +707,7 @@ func edit(kkzm *kokizami.Kokizami, k *kokizami.Kizami, id int, desc, start, stop
 	k.StartedAt = startedAt
 	k.StoppedAt = stoppedAt
 
-	ret, err := kkzm.Edit(k)
-	if err != nil {
-		return nil, err
-	}
-
-	return ret, tagging(kkzm, k.ID, desc)
+	return kkzm.Edit(k)
 }
 
 func editTextWithEditor(prewrite string) (string, error) {
@@ -488,15 +750,26 @@ func runEditor(filename string) error {
 type descSummary struct {
 	desc        string
 	descElapsed time.Duration
+	running     bool
 }
 
 type tagSummary struct {
 	tagElapsed    time.Duration
+	running       bool
 	descSummaries []*descSummary
 }
 
 type tagSummaries map[string]*tagSummary
 
+// elapsedString returns elapsed time to show.
+// elapsed time including on-going tasks has "*" prefix.
+func elapsedString(d time.Duration, running bool) string {
+	if running {
+		return "*" + d.String()
+	}
+	return d.String()
+}
+
 func (s tagSummaries) String() string {
 	keys := make([]string, len(s))
 	var index int
@@ -512,19 +785,40 @@ func (s tagSummaries) String() string {
 		if v != "" {
 			tag = v
 		}
-		fmt.Fprintf(buf, "%s\t%s\n", tag, s[v].tagElapsed)
+		fmt.Fprintf(buf, "%s\t%s\n", tag, elapsedString(s[v].tagElapsed, s[v].running))
 
 		for _, d := range s[v].descSummaries {
-			fmt.Fprintf(buf, "  %s\t%s\n", d.desc, d.descElapsed)
+			fmt.Fprintf(buf, "  %s\t%s\n", d.desc, elapsedString(d.descElapsed, d.running))
 		}
 	}
 	return buf.String()
 }
 
-// CmdSummary shows summary of elapsed time of specified month
+// CmdSummary shows summary of elapsed time of specified period
 func CmdSummary(c *cli.Context) error {
-	yyyymm := c.String("month")
-	tags, err := kkzm(c).SummaryByTag(yyyymm)
+	format, err := outputFormat(c)
+	if err != nil {
+		return err
+	}
+
+	kkzm := kkzm(c)
+
+	p, err := periodFromFlags(c, time.Now(), kkzm.Location)
+	if err != nil {
+		return err
+	}
+
+	t, err := outputTemplate(c, format, kkzm.Location)
+	if err != nil {
+		return err
+	}
+
+	opts := []kokizami.SummaryOption{
+		kokizami.WithProrate(c.Bool("prorate")),
+		kokizami.WithRunning(c.Bool("running")),
+	}
+
+	tags, err := kkzm.SummaryByTagBetween(p.from, p.to, opts...)
 	if err != nil {
 		return err
 	}
@@ -533,25 +827,67 @@ func CmdSummary(c *cli.Context) error {
 	for _, v := range tags {
 		summaries[v.Tag] = &tagSummary{
 			tagElapsed: v.Elapsed,
+			running:    v.Running,
 		}
 	}
 
-	descs, err := kkzm(c).SummaryByDesc(yyyymm)
+	descs, err := kkzm.SummaryByDescBetween(p.from, p.to, opts...)
 	if err != nil {
 		return err
 	}
 
+	if t != nil {
+		ds := newElapsedData(tags, descs)
+		items := make([]interface{}, len(ds))
+		for i, v := range ds {
+			items[i] = v
+		}
+		return executeTemplate(os.Stdout, t, &reportData{
+			Label: p.label,
+			From:  p.from,
+			To:    p.to,
+			Items: ds,
+		}, items)
+	}
+
+	if format != formatTable {
+		return writeRecords(os.Stdout, format, elapsedHeader, summaryRecords(tags, descs, p, kkzm.Location))
+	}
+
 	for _, v := range descs {
 		summaries[v.Tag].descSummaries = append(summaries[v.Tag].descSummaries, &descSummary{
 			desc:        v.Desc,
 			descElapsed: v.Elapsed,
+			running:     v.Running,
 		})
 	}
 
-	fmt.Printf("Summary of %s\n%s\n", yyyymm, summaries)
+	fmt.Printf("Summary of %s\n%s\n", p.label, summaries)
 	return nil
 }
 
+// summaryRecords returns records of summary ordered by tag.
+// each tag's total is followed by totals of descs in the tag.
+func summaryRecords(tags, descs []*kokizami.Elapsed, p *period, loc *time.Location) []record {
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].Tag < tags[j].Tag
+	})
+	sort.SliceStable(descs, func(i, j int) bool {
+		return descs[i].Desc < descs[j].Desc
+	})
+
+	rs := []record{}
+	for _, t := range tags {
+		rs = append(rs, newElapsedRecord("tag", t, p, loc))
+		for _, d := range descs {
+			if d.Tag == t.Tag {
+				rs = append(rs, newElapsedRecord("desc", d, p, loc))
+			}
+		}
+	}
+	return rs
+}
+
 // CmdTags shows list of tags
 func CmdTags(c *cli.Context) error {
 	var (
@@ -559,6 +895,11 @@ func CmdTags(c *cli.Context) error {
 		err error
 	)
 
+	format, err := outputFormat(c)
+	if err != nil {
+		return err
+	}
+
 	kkzm := kkzm(c)
 	id := c.Int("id")
 
@@ -574,6 +915,14 @@ func CmdTags(c *cli.Context) error {
 		}
 	}
 
+	if format != formatTable {
+		rs := make([]record, len(ts))
+		for i, v := range ts {
+			rs[i] = newTagRecord(v)
+		}
+		return writeRecords(os.Stdout, format, tagHeader, rs)
+	}
+
 	buf := bytes.NewBuffer([]byte{})
 	for _, v := range ts {
 		fmt.Fprintln(buf, v.Label)
@@ -582,14 +931,3 @@ func CmdTags(c *cli.Context) error {
 	fmt.Printf("%s", buf)
 	return nil
 }
-
-func extractTagsFromString(s string) []string {
-	ss := strings.Split(s, " ")
-	var tags []string
-	for _, v := range ss {
-		if strings.HasPrefix(v, "#") && len(v) >= 2 {
-			tags = append(tags, v)
-		}
-	}
-	return tags
-}
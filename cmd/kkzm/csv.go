@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvHeader is header of exported CSV.
+// times are RFC 3339 with explicit offset, and tags are separated by spaces.
+// stopped_at is empty if the kizami is running.
+var csvHeader = []string{"id", "desc", "tags", "started_at", "stopped_at"}
+
+func exportCSV(w io.Writer, es []*entry, opts *transferOptions) error {
+	loc := opts.Location
+	cw := csv.NewWriter(w)
+	err := cw.Write(csvHeader)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range es {
+		stoppedAt := ""
+		if !e.StoppedAt.IsZero() {
+			stoppedAt = e.StoppedAt.In(loc).Format(time.RFC3339)
+		}
+		err = cw.Write([]string{
+			strconv.Itoa(e.ID),
+			e.Desc,
+			strings.Join(e.Tags, " "),
+			e.StartedAt.In(loc).Format(time.RFC3339),
+			stoppedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// importCSV reads CSV written by exportCSV.
+// columns are identified by the header and id and tags are optional.
+// times without offset are taken as in opts.Location.
+// well-formed rows are returned with rowErrors if some rows are malformed.
+func importCSV(r io.Reader, opts *transferOptions) ([]*entry, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("csv is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %v", err)
+	}
+
+	cols := map[string]int{}
+	for i, v := range header {
+		cols[strings.ToLower(strings.TrimSpace(v))] = i
+	}
+	for _, v := range []string{"desc", "started_at", "stopped_at"} {
+		if _, ok := cols[v]; !ok {
+			return nil, fmt.Errorf("csv header must have %s column", v)
+		}
+	}
+
+	var (
+		es   []*entry
+		errs rowErrors
+		// line is counted by rows, that differs from lines
+		// only if a field has newlines
+		line = 1
+	)
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			errs = append(errs, &rowError{Line: line, Err: err})
+			continue
+		}
+
+		e, err := csvEntry(rec, cols, opts.Location)
+		if err != nil {
+			errs = append(errs, &rowError{Line: line, Err: err})
+			continue
+		}
+		e.Line = line
+		es = append(es, e)
+	}
+
+	if len(errs) != 0 {
+		return es, errs
+	}
+	return es, nil
+}
+
+func csvEntry(rec []string, cols map[string]int, loc *time.Location) (*entry, error) {
+	field := func(name string) string {
+		i, ok := cols[name]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	e := &entry{
+		Desc: field("desc"),
+		Tags: strings.Fields(field("tags")),
+	}
+
+	var err error
+	if v := field("id"); v != "" {
+		e.ID, err = strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id: %s", v)
+		}
+	}
+
+	e.StartedAt, err = parseCSVTime(field("started_at"), loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid started_at: %v", err)
+	}
+	if v := field("stopped_at"); v != "" {
+		e.StoppedAt, err = parseCSVTime(v, loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stopped_at: %v", err)
+		}
+	}
+
+	return e, nil
+}
+
+func parseCSVTime(s string, loc *time.Location) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, fmt.Errorf("empty")
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err == nil {
+		return t, nil
+	}
+	return parseDateTime(s, loc)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	startedAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	es := []*entry{
+		{
+			ID:        1,
+			Desc:      "write, docs #doc",
+			Tags:      []string{"#doc", "#en"},
+			StartedAt: startedAt,
+			StoppedAt: startedAt.Add(90 * time.Minute),
+		},
+		{
+			ID:        2,
+			Desc:      "on-going",
+			Tags:      []string{},
+			StartedAt: startedAt.Add(2 * time.Hour),
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	err := exportCSV(buf, es, &transferOptions{Location: loc})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	want := "id,desc,tags,started_at,stopped_at\n" +
+		"1,\"write, docs #doc\",#doc #en,2024-03-01T09:00:00+09:00,2024-03-01T10:30:00+09:00\n" +
+		"2,on-going,,2024-03-01T11:00:00+09:00,\n"
+	if diff := cmp.Diff(buf.String(), want); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	ret, err := importCSV(buf, &transferOptions{Location: time.UTC})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	for i := range ret {
+		if ret[i].ID != es[i].ID || ret[i].Desc != es[i].Desc ||
+			!ret[i].StartedAt.Equal(es[i].StartedAt) || !ret[i].StoppedAt.Equal(es[i].StoppedAt) ||
+			strings.Join(ret[i].Tags, " ") != strings.Join(es[i].Tags, " ") {
+			t.Fatalf("[No.%d] unexpected result: [got] %+v [want] %+v", i, ret[i], es[i])
+		}
+		if ret[i].Line != i+2 {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] %v", i, ret[i].Line, i+2)
+		}
+	}
+}
+
+func TestImportCSVMalformed(t *testing.T) {
+	in := "desc,started_at,stopped_at\n" +
+		"ok,2024-03-01 09:00,2024-03-01 10:00\n" +
+		"bad,2024-13-01 09:00,\n" +
+		"bad,2024-03-01 09:00,yesterday\n"
+
+	ret, err := importCSV(strings.NewReader(in), &transferOptions{Location: time.UTC})
+	errs, ok := err.(rowErrors)
+	if !ok {
+		t.Fatalf("unexpected result: [got] %v [want] rowErrors", err)
+	}
+	if len(errs) != 2 || errs[0].Line != 3 || errs[1].Line != 4 {
+		t.Fatalf("unexpected result: [got] %v [want] errors on line 3 and 4", errs)
+	}
+	if len(ret) != 1 || ret[0].Desc != "ok" {
+		t.Fatalf("unexpected result: [got] %v [want] an entry of line 2", ret)
+	}
+
+	_, err = importCSV(strings.NewReader("desc,started_at\n"), &transferOptions{Location: time.UTC})
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] error for missing column")
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	ret := mergeTags([]string{"#a", "b", " ", "#"}, []string{"#b", "#c"})
+	if diff := cmp.Diff(ret, []string{"#a", "#b", "#c"}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+}
+
+func TestNormalizeTag(t *testing.T) {
+	tcs := []struct {
+		in   string
+		want string
+	}{
+		{in: "dev", want: "#dev"},
+		{in: "#dev", want: "#dev"},
+		{in: "##dev", want: "#dev"},
+		{in: " code  review ", want: "#code_review"},
+		{in: "#", want: ""},
+		{in: " ", want: ""},
+		{in: "", want: ""},
+	}
+
+	for i, tc := range tcs {
+		ret := normalizeTag(tc.in)
+		if ret != tc.want {
+			t.Errorf("[No.%d] unexpected result: [got] %v [want] %v", i, ret, tc.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/pankona/kokizami"
+	"github.com/pankona/kokizami/cmd/kkzm/repo"
+	"github.com/urfave/cli"
+)
+
+func dbCommand() cli.Command {
+	return cli.Command{
+		Name:  "db",
+		Usage: "manage database schema",
+		Subcommands: []cli.Command{
+			{
+				Name:   "status",
+				Usage:  "show schema version and pending migrations",
+				Action: CmdDBStatus,
+			},
+			{
+				Name:   "migrate",
+				Usage:  "apply pending migrations",
+				Action: CmdDBMigrate,
+			},
+		},
+	}
+}
+
+func database(c *cli.Context) *sql.DB {
+	enableVerboseQuery(c.GlobalBool("verbose"))
+	return c.App.Metadata["db"].(*sql.DB)
+}
+
+// CmdDBStatus shows current schema version and pending migrations
+// kokizami db status
+func CmdDBStatus(c *cli.Context) error {
+	db := database(c)
+
+	applied, err := repo.AppliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	version, err := repo.SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("schema version: %d (latest: %d)\n", version, repo.LatestSchemaVersion())
+	for _, v := range applied {
+		fmt.Printf("  applied  %d\t%s\n", v.Version, v.AppliedAt.In(time.Local).Format("2006-01-02 15:04:05"))
+	}
+
+	pending, err := repo.PendingMigrations(db)
+	if err != nil {
+		return err
+	}
+	for _, v := range pending {
+		fmt.Printf("  pending  %d\t%s\n", v.Version, v.Desc)
+	}
+
+	return nil
+}
+
+// CmdDBMigrate applies pending migrations
+// kokizami db migrate
+func CmdDBMigrate(c *cli.Context) error {
+	applied, err := repo.Migrate(database(c))
+	if err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		fmt.Println("database is up to date")
+		return nil
+	}
+
+	for _, v := range applied {
+		fmt.Printf("applied %d\t%s\n", v.Version, v.Desc)
+	}
+	return nil
+}
+
+// inTransaction calls fn with a Kokizami in loc on a transaction of db.
+// the transaction is committed if fn succeeds, or rolled back otherwise.
+func inTransaction(db *sql.DB, loc *time.Location, fn func(*kokizami.Kokizami) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = fn(&kokizami.Kokizami{
+		KizamiRepo:  repo.NewKizamiRepo(tx),
+		TagRepo:     repo.NewTagRepo(tx),
+		SummaryRepo: repo.NewSummaryRepo(tx),
+		Location:    loc,
+	})
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("failed to rollback: %v (original error: %v)", e, err)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
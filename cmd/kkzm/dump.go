@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/pankona/kokizami/cmd/kkzm/repo"
+	"github.com/urfave/cli"
+)
+
+func dumpCommand() cli.Command {
+	return cli.Command{
+		Name:   "dump",
+		Usage:  "dump all tasks, tags and their relations as JSON",
+		Action: CmdDump,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "o, output",
+				Usage: "specify file to write. default is stdout",
+			},
+		},
+	}
+}
+
+func restoreCommand() cli.Command {
+	return cli.Command{
+		Name:      "restore",
+		Usage:     "restore a dump into an empty database",
+		ArgsUsage: "[file (\"-\" for stdin)]",
+		Action:    CmdRestore,
+	}
+}
+
+// CmdDump dumps whole database
+// kokizami dump > backup.json
+func CmdDump(c *cli.Context) error {
+	d, err := repo.NewDump(database(c))
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := c.String("output")
+	if path == "" {
+		_, err = fmt.Printf("%s\n", b)
+		return err
+	}
+
+	err = ioutil.WriteFile(path, append(b, '\n'), 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return nil
+}
+
+// CmdRestore restores a dump
+// kokizami restore backup.json
+func CmdRestore(c *cli.Context) error {
+	args := c.Args()
+	if len(args) != 1 {
+		return fmt.Errorf("restore needs one argument [file]")
+	}
+
+	var (
+		b   []byte
+		err error
+	)
+	if args[0] == "-" {
+		b, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		b, err = ioutil.ReadFile(args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", args[0], err)
+	}
+
+	d := &repo.Dump{}
+	err = json.Unmarshal(b, d)
+	if err != nil {
+		return fmt.Errorf("failed to parse dump: %v", err)
+	}
+
+	err = repo.Restore(database(c), d)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("restored %d task(s), %d tag(s), %d relation(s) and %d segment(s)\n",
+		len(d.Kizamis), len(d.Tags), len(d.Relations), len(d.Segments))
+	return nil
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pankona/kokizami"
+	"github.com/urfave/cli"
+)
+
+// output formats of read commands
+const (
+	formatTable    = "table"
+	formatJSON     = "json"
+	formatJSONL    = "jsonl"
+	formatCSV      = "csv"
+	formatTSV      = "tsv"
+	formatMarkdown = "markdown"
+)
+
+var formats = []string{
+	formatTable,
+	formatJSON,
+	formatJSONL,
+	formatCSV,
+	formatTSV,
+	formatMarkdown,
+}
+
+// outputFormat returns output format specified by --format
+func outputFormat(c *cli.Context) (string, error) {
+	f := c.GlobalString("format")
+	if f == "" {
+		return formatTable, nil
+	}
+	for _, v := range formats {
+		if f == v {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format %s. should be one of %s", f, strings.Join(formats, ", "))
+}
+
+// record is a row of machine-readable output
+type record interface {
+	// values returns fields in the same order as its header
+	values() []string
+}
+
+// timeLayout is layout of times in machine-readable output (ISO-8601)
+const timeLayout = time.RFC3339
+
+// kizamiRecord represents a Kizami with its tags
+type kizamiRecord struct {
+	ID        int      `json:"id"`
+	Desc      string   `json:"desc"`
+	Tags      []string `json:"tags"`
+	StartedAt string   `json:"started_at"`
+	StoppedAt *string  `json:"stopped_at"`
+	Elapsed   int64    `json:"elapsed"`
+	Running   bool     `json:"running"`
+	Paused    bool     `json:"paused"`
+}
+
+var kizamiHeader = []string{"id", "desc", "tags", "started_at", "stopped_at", "elapsed", "running", "paused"}
+
+func newKizamiRecord(k *kokizami.Kizami, tags []*kokizami.Tag, loc *time.Location) *kizamiRecord {
+	r := &kizamiRecord{
+		ID:        k.ID,
+		Desc:      k.Desc,
+		Tags:      []string{},
+		StartedAt: k.StartedAt.In(loc).Format(timeLayout),
+		Elapsed:   int64(round(k.Elapsed(), time.Second) / time.Second),
+		Running:   k.IsRunning(),
+		Paused:    k.IsPaused(),
+	}
+	for _, v := range tags {
+		r.Tags = append(r.Tags, v.Label)
+	}
+	if !r.Running && !r.Paused {
+		s := k.StoppedAt.In(loc).Format(timeLayout)
+		r.StoppedAt = &s
+	}
+	return r
+}
+
+func (r *kizamiRecord) values() []string {
+	stoppedAt := ""
+	if r.StoppedAt != nil {
+		stoppedAt = *r.StoppedAt
+	}
+	return []string{
+		strconv.Itoa(r.ID),
+		r.Desc,
+		strings.Join(r.Tags, " "),
+		r.StartedAt,
+		stoppedAt,
+		strconv.FormatInt(r.Elapsed, 10),
+		strconv.FormatBool(r.Running),
+		strconv.FormatBool(r.Paused),
+	}
+}
+
+// elapsedRecord represents an Elapsed of summary.
+// Group is "tag" for total of a tag, or "desc" for total of a desc in a tag.
+type elapsedRecord struct {
+	Group   string `json:"group"`
+	Tag     string `json:"tag"`
+	Desc    string `json:"desc"`
+	Count   int    `json:"count"`
+	Elapsed int64  `json:"elapsed"`
+	Running bool   `json:"running"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
+
+var elapsedHeader = []string{"group", "tag", "desc", "count", "elapsed", "running", "from", "to"}
+
+func newElapsedRecord(group string, e *kokizami.Elapsed, p *period, loc *time.Location) *elapsedRecord {
+	r := &elapsedRecord{
+		Group:   group,
+		Tag:     e.Tag,
+		Count:   e.Count,
+		Elapsed: int64(e.Elapsed / time.Second),
+		Running: e.Running,
+		From:    p.from.In(loc).Format(timeLayout),
+		To:      p.to.In(loc).Format(timeLayout),
+	}
+	if group == "desc" {
+		r.Desc = e.Desc
+	}
+	return r
+}
+
+func (r *elapsedRecord) values() []string {
+	return []string{
+		r.Group,
+		r.Tag,
+		r.Desc,
+		strconv.Itoa(r.Count),
+		strconv.FormatInt(r.Elapsed, 10),
+		strconv.FormatBool(r.Running),
+		r.From,
+		r.To,
+	}
+}
+
+// tagRecord represents a Tag
+type tagRecord struct {
+	ID    int    `json:"id"`
+	Label string `json:"label"`
+}
+
+var tagHeader = []string{"id", "label"}
+
+func newTagRecord(t *kokizami.Tag) *tagRecord {
+	return &tagRecord{
+		ID:    t.ID,
+		Label: t.Label,
+	}
+}
+
+func (r *tagRecord) values() []string {
+	return []string{
+		strconv.Itoa(r.ID),
+		r.Label,
+	}
+}
+
+// writeRecords writes records in specified machine-readable format
+func writeRecords(w io.Writer, format string, header []string, rs []record) error {
+	switch format {
+	case formatJSON:
+		if rs == nil {
+			rs = []record{}
+		}
+		b, err := json.MarshalIndent(rs, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+
+	case formatJSONL:
+		e := json.NewEncoder(w)
+		for _, v := range rs {
+			err := e.Encode(v)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case formatCSV, formatTSV:
+		cw := csv.NewWriter(w)
+		if format == formatTSV {
+			cw.Comma = '\t'
+		}
+		err := cw.Write(header)
+		if err != nil {
+			return err
+		}
+		for _, v := range rs {
+			err = cw.Write(v.values())
+			if err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case formatMarkdown:
+		err := writeMarkdownRow(w, header)
+		if err != nil {
+			return err
+		}
+		sep := make([]string, len(header))
+		for i := range sep {
+			sep[i] = "---"
+		}
+		err = writeMarkdownRow(w, sep)
+		if err != nil {
+			return err
+		}
+		for _, v := range rs {
+			err = writeMarkdownRow(w, v.values())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("format %s is not machine-readable", format)
+	}
+}
+
+var markdownEscaper = strings.NewReplacer(`|`, `\|`, "\n", " ")
+
+func writeMarkdownRow(w io.Writer, vs []string) error {
+	cells := make([]string, len(vs))
+	for i, v := range vs {
+		cells[i] = markdownEscaper.Replace(v)
+	}
+	_, err := fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+	return err
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pankona/kokizami"
+)
+
+func TestWriteRecords(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	startedAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	rs := []record{
+		newKizamiRecord(&kokizami.Kizami{
+			ID:        1,
+			Desc:      "write | docs #doc",
+			StartedAt: startedAt,
+			StoppedAt: startedAt.Add(90 * time.Minute),
+		}, []*kokizami.Tag{{ID: 1, Label: "#doc"}}, loc),
+		newKizamiRecord(&kokizami.Kizami{
+			ID:        2,
+			Desc:      "review, again",
+			StartedAt: startedAt.Add(2 * time.Hour),
+			StoppedAt: time.Unix(0, 0).UTC(),
+		}, nil, loc),
+	}
+
+	tcs := []struct {
+		inFormat string
+		inRecs   []record
+		want     string
+		wantErr  bool
+	}{
+		{
+			inFormat: formatCSV,
+			inRecs:   rs[:1],
+			want: "id,desc,tags,started_at,stopped_at,elapsed,running,paused\n" +
+				"1,write | docs #doc,#doc,2024-03-01T09:00:00+09:00,2024-03-01T10:30:00+09:00,5400,false,false\n",
+		},
+		{
+			inFormat: formatTSV,
+			inRecs:   rs[:1],
+			want: "id\tdesc\ttags\tstarted_at\tstopped_at\telapsed\trunning\tpaused\n" +
+				"1\twrite | docs #doc\t#doc\t2024-03-01T09:00:00+09:00\t2024-03-01T10:30:00+09:00\t5400\tfalse\tfalse\n",
+		},
+		{
+			inFormat: formatMarkdown,
+			inRecs:   rs[:1],
+			want: "| id | desc | tags | started_at | stopped_at | elapsed | running | paused |\n" +
+				"| --- | --- | --- | --- | --- | --- | --- | --- |\n" +
+				"| 1 | write \\| docs #doc | #doc | 2024-03-01T09:00:00+09:00 | 2024-03-01T10:30:00+09:00 | 5400 | false | false |\n",
+		},
+		{
+			inFormat: formatJSONL,
+			inRecs:   rs[:1],
+			want: `{"id":1,"desc":"write | docs #doc","tags":["#doc"],"started_at":"2024-03-01T09:00:00+09:00",` +
+				`"stopped_at":"2024-03-01T10:30:00+09:00","elapsed":5400,"running":false,"paused":false}` + "\n",
+		},
+		{
+			inFormat: formatJSON,
+			inRecs:   nil,
+			want:     "[]\n",
+		},
+		{
+			inFormat: formatTable,
+			inRecs:   rs,
+			wantErr:  true,
+		},
+	}
+
+	for i, tc := range tcs {
+		buf := &bytes.Buffer{}
+		err := writeRecords(buf, tc.inFormat, kizamiHeader, tc.inRecs)
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("[No.%d] unexpected result: [got] nil [want] some error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if diff := cmp.Diff(buf.String(), tc.want); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+
+	// on-going kizami has no stopped_at
+	r := rs[1].(*kizamiRecord)
+	if r.StoppedAt != nil || !r.Running || len(r.Tags) != 0 {
+		t.Fatalf("unexpected result: [got] %+v [want] running kizami without stopped_at and tags", r)
+	}
+}
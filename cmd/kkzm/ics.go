@@ -0,0 +1,501 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// icsLayout is layout of DATE-TIME values in UTC of iCalendar
+const icsLayout = "20060102T150405Z"
+
+// icsUID returns a stable UID of specified kizami
+func icsUID(id int) string {
+	return "kizami-" + strconv.Itoa(id) + "@kokizami"
+}
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// writeICSLine writes a content line of iCalendar.
+// lines longer than 75 octets are folded as RFC 5545 requires.
+func writeICSLine(w io.Writer, line string) error {
+	const limit = 75
+
+	var b strings.Builder
+	n := 0
+	for _, r := range line {
+		size := len(string(r))
+		if n+size > limit {
+			b.WriteString("\r\n ")
+			// the leading space is counted
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	b.WriteString("\r\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// exportICS writes finished entries as VEVENTs of an iCalendar.
+// on-going entries are not written since they have no end.
+func exportICS(w io.Writer, es []*entry, _ *transferOptions) error {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//pankona//kokizami " + Version + "//EN",
+		"CALSCALE:GREGORIAN",
+	}
+
+	for _, e := range es {
+		if e.StoppedAt.IsZero() {
+			continue
+		}
+
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+icsUID(e.ID),
+			// DTSTAMP is the time the kizami is stopped to keep output stable
+			"DTSTAMP:"+e.StoppedAt.UTC().Format(icsLayout),
+			"DTSTART:"+e.StartedAt.UTC().Format(icsLayout),
+			"DTEND:"+e.StoppedAt.UTC().Format(icsLayout),
+			"SUMMARY:"+icsEscaper.Replace(e.Desc),
+		)
+		if len(e.Tags) != 0 {
+			categories := make([]string, len(e.Tags))
+			for i, v := range e.Tags {
+				categories[i] = icsEscaper.Replace(strings.TrimPrefix(v, "#"))
+			}
+			lines = append(lines, "CATEGORIES:"+strings.Join(categories, ","))
+		}
+		lines = append(lines, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, v := range lines {
+		err := writeICSLine(w, v)
+		if err != nil {
+			return fmt.Errorf("failed to write ics: %v", err)
+		}
+	}
+	return nil
+}
+
+// icsProperty is a content line of iCalendar
+type icsProperty struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// icsComponent is a component of iCalendar such as VEVENT
+type icsComponent struct {
+	Name       string
+	Properties []*icsProperty
+	Components []*icsComponent
+	// Line is line number that the component begins
+	Line int
+}
+
+// Property returns the first property of specified name, or nil
+func (c *icsComponent) Property(name string) *icsProperty {
+	for _, v := range c.Properties {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// Value returns value of the first property of specified name
+func (c *icsComponent) Value(name string) string {
+	if p := c.Property(name); p != nil {
+		return p.Value
+	}
+	return ""
+}
+
+// unfoldICS returns unfolded content lines with their line numbers
+func unfoldICS(r io.Reader) ([]string, []int, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		lines   []string
+		numbers []int
+	)
+	for i, v := range strings.Split(strings.ReplaceAll(string(b), "\r\n", "\n"), "\n") {
+		if (strings.HasPrefix(v, " ") || strings.HasPrefix(v, "\t")) && len(lines) != 0 {
+			lines[len(lines)-1] += v[1:]
+			continue
+		}
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+		lines = append(lines, v)
+		numbers = append(numbers, i+1)
+	}
+	return lines, numbers, nil
+}
+
+// parseICSProperty parses a content line like NAME;PARAM=VALUE:VALUE
+func parseICSProperty(s string) (*icsProperty, error) {
+	p := &icsProperty{Params: map[string]string{}}
+
+	// find the colon that separates value, skipping quoted parameter values
+	quoted := false
+	colon := -1
+	for i := 0; i < len(s) && colon < 0; i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if !quoted {
+				colon = i
+			}
+		}
+	}
+	if colon < 0 {
+		return nil, fmt.Errorf("invalid content line: %s", s)
+	}
+	p.Value = s[colon+1:]
+
+	var params []string
+	quoted = false
+	start := 0
+	head := s[:colon]
+	for i := 0; i < len(head); i++ {
+		switch head[i] {
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				params = append(params, head[start:i])
+				start = i + 1
+			}
+		}
+	}
+	params = append(params, head[start:])
+
+	p.Name = strings.ToUpper(params[0])
+	for _, v := range params[1:] {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid parameter %s of %s", v, p.Name)
+		}
+		p.Params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+	}
+	return p, nil
+}
+
+// parseICS parses an iCalendar and returns its top level components
+func parseICS(r io.Reader) ([]*icsComponent, error) {
+	lines, numbers, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		roots []*icsComponent
+		stack []*icsComponent
+	)
+	for i, v := range lines {
+		p, err := parseICSProperty(v)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", numbers[i], err)
+		}
+
+		switch p.Name {
+		case "BEGIN":
+			c := &icsComponent{Name: strings.ToUpper(p.Value), Line: numbers[i]}
+			if len(stack) == 0 {
+				roots = append(roots, c)
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Components = append(parent.Components, c)
+			}
+			stack = append(stack, c)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(p.Value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", numbers[i], p.Value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("line %d: property %s out of component", numbers[i], p.Name)
+			}
+			c := stack[len(stack)-1]
+			c.Properties = append(c.Properties, p)
+		}
+	}
+	if len(stack) != 0 {
+		return nil, fmt.Errorf("%s is not closed", stack[len(stack)-1].Name)
+	}
+	return roots, nil
+}
+
+var icsUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, `;`, `\,`, `,`, `\n`, "\n", `\N`, "\n")
+
+// splitICSText splits a list of TEXT values separated by commas
+func splitICSText(s string) []string {
+	var (
+		ret []string
+		b   strings.Builder
+	)
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			b.WriteString(icsUnescaper.Replace(s[i : i+2]))
+			i++
+		case s[i] == ',':
+			ret = append(ret, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return append(ret, b.String())
+}
+
+// parseICSTime parses a DATE-TIME property.
+// times without "Z" are in the timezone of TZID parameter, or in loc if TZID is not given.
+// true is returned for DATE values of all-day events.
+func parseICSTime(p *icsProperty, loc *time.Location) (time.Time, bool, error) {
+	if p.Params["VALUE"] == "DATE" || len(p.Value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", p.Value, loc)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(p.Value, "Z") {
+		t, err := time.Parse(icsLayout, p.Value)
+		return t, false, err
+	}
+
+	if tzid := p.Params["TZID"]; tzid != "" {
+		l, err := time.LoadLocation(strings.TrimPrefix(tzid, "/"))
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("unknown TZID %s: %v", tzid, err)
+		}
+		loc = l
+	}
+	t, err := time.ParseInLocation("20060102T150405", p.Value, loc)
+	return t, false, err
+}
+
+// parseICSDuration parses a DURATION value like PT1H30M or P1D
+func parseICSDuration(s string) (time.Duration, error) {
+	orig := s
+	neg := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, fmt.Errorf("invalid duration: %s", orig)
+	}
+	s = s[1:]
+
+	var (
+		d      time.Duration
+		inTime bool
+		num    string
+	)
+	units := map[string]time.Duration{
+		"W": 7 * 24 * time.Hour,
+		"D": 24 * time.Hour,
+		"H": time.Hour,
+		"M": time.Minute,
+		"S": time.Second,
+	}
+	for _, r := range s {
+		switch {
+		case r == 'T':
+			inTime = true
+		case r >= '0' && r <= '9':
+			num += string(r)
+		default:
+			unit, ok := units[string(r)]
+			if !ok || num == "" || (inTime && (r == 'W' || r == 'D')) || (!inTime && (r == 'H' || r == 'M' || r == 'S')) {
+				return 0, fmt.Errorf("invalid duration: %s", orig)
+			}
+			n, err := strconv.Atoi(num)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration: %s", orig)
+			}
+			d += time.Duration(n) * unit
+			num = ""
+		}
+	}
+	if num != "" {
+		return 0, fmt.Errorf("invalid duration: %s", orig)
+	}
+
+	if neg {
+		d = -d
+	}
+	return d, nil
+}
+
+// icsRule is a simple recurrence rule.
+// FREQ, INTERVAL, COUNT, UNTIL and BYDAY of WEEKLY are supported.
+type icsRule struct {
+	Freq     string
+	Interval int
+	Count    int
+	Until    time.Time
+	ByDay    []time.Weekday
+}
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+func parseICSRule(s string, loc *time.Location) (*icsRule, error) {
+	r := &icsRule{Interval: 1}
+	for _, v := range strings.Split(s, ";") {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid RRULE: %s", s)
+		}
+
+		var err error
+		switch strings.ToUpper(kv[0]) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(kv[1])
+			switch r.Freq {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+			default:
+				return nil, fmt.Errorf("unsupported FREQ of RRULE: %s", kv[1])
+			}
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(kv[1])
+			if err != nil || r.Interval <= 0 {
+				return nil, fmt.Errorf("invalid INTERVAL of RRULE: %s", kv[1])
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(kv[1])
+			if err != nil || r.Count <= 0 {
+				return nil, fmt.Errorf("invalid COUNT of RRULE: %s", kv[1])
+			}
+		case "UNTIL":
+			r.Until, _, err = parseICSTime(&icsProperty{Value: kv[1]}, loc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid UNTIL of RRULE: %s", kv[1])
+			}
+		case "BYDAY":
+			for _, d := range strings.Split(kv[1], ",") {
+				wd, ok := icsWeekdays[strings.ToUpper(d)]
+				if !ok {
+					return nil, fmt.Errorf("unsupported BYDAY of RRULE: %s", kv[1])
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "WKST":
+			// weeks are assumed to start on Monday
+		default:
+			return nil, fmt.Errorf("unsupported %s of RRULE", kv[0])
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, fmt.Errorf("RRULE must have FREQ: %s", s)
+	}
+	if len(r.ByDay) != 0 && r.Freq != "WEEKLY" {
+		return nil, fmt.Errorf("BYDAY of RRULE is supported only for WEEKLY: %s", s)
+	}
+	return r, nil
+}
+
+// maxOccurrences limits expansion of recurrences without COUNT and UNTIL
+const maxOccurrences = 10000
+
+// occurrences returns start times of recurrences of specified rule
+// that start before end. start is the first occurrence.
+func (r *icsRule) occurrences(start, end time.Time) []time.Time {
+	var ret []time.Time
+
+	n := 0
+	add := func(t time.Time) bool {
+		if t.Before(start) {
+			return true
+		}
+		if !t.Before(end) || (!r.Until.IsZero() && t.After(r.Until)) ||
+			(r.Count != 0 && n >= r.Count) || n >= maxOccurrences {
+			return false
+		}
+		n++
+		ret = append(ret, t)
+		return true
+	}
+
+	y, m, d := start.Date()
+	hh, mm, ss := start.Clock()
+	loc := start.Location()
+
+	for i := 0; ; i++ {
+		k := i * r.Interval
+		switch r.Freq {
+		case "DAILY":
+			if !add(time.Date(y, m, d+k, hh, mm, ss, 0, loc)) {
+				return ret
+			}
+		case "WEEKLY":
+			if len(r.ByDay) == 0 {
+				if !add(time.Date(y, m, d+7*k, hh, mm, ss, 0, loc)) {
+					return ret
+				}
+				continue
+			}
+			// days of the week that starts on Monday
+			monday := d - (int(start.Weekday())+6)%7 + 7*k
+			for offset := 0; offset < 7; offset++ {
+				t := time.Date(y, m, monday+offset, hh, mm, ss, 0, loc)
+				if !containsWeekday(r.ByDay, t.Weekday()) {
+					continue
+				}
+				if !add(t) {
+					return ret
+				}
+			}
+		case "MONTHLY":
+			t := time.Date(y, m+time.Month(k), d, hh, mm, ss, 0, loc)
+			// months without the day are skipped
+			if t.Day() == d && !add(t) {
+				return ret
+			}
+		case "YEARLY":
+			t := time.Date(y+k, m, d, hh, mm, ss, 0, loc)
+			if t.Day() == d && !add(t) {
+				return ret
+			}
+		}
+		if i >= maxOccurrences*7 {
+			return ret
+		}
+	}
+}
+
+func containsWeekday(ws []time.Weekday, w time.Weekday) bool {
+	for _, v := range ws {
+		if v == w {
+			return true
+		}
+	}
+	return false
+}
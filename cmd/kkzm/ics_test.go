@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExportICS(t *testing.T) {
+	startedAt := time.Date(2024, 3, 1, 9, 0, 0, 0, time.FixedZone("JST", 9*60*60))
+
+	es := []*entry{
+		{
+			ID:        1,
+			Desc:      "write docs, again; #doc",
+			Tags:      []string{"#doc", "#en"},
+			StartedAt: startedAt,
+			StoppedAt: startedAt.Add(90 * time.Minute),
+		},
+		{
+			// on-going entries are not exported
+			ID:        2,
+			Desc:      "on-going",
+			StartedAt: startedAt.Add(2 * time.Hour),
+		},
+		{
+			ID:        3,
+			Desc:      strings.Repeat("long ", 20),
+			StartedAt: startedAt.Add(3 * time.Hour),
+			StoppedAt: startedAt.Add(4 * time.Hour),
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	err := exportICS(buf, es, &transferOptions{Location: time.UTC})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	want := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"PRODID:-//pankona//kokizami " + Version + "//EN\r\n" +
+		"CALSCALE:GREGORIAN\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:kizami-1@kokizami\r\n" +
+		"DTSTAMP:20240301T013000Z\r\n" +
+		"DTSTART:20240301T000000Z\r\n" +
+		"DTEND:20240301T013000Z\r\n" +
+		"SUMMARY:write docs\\, again\\; #doc\r\n" +
+		"CATEGORIES:doc,en\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:kizami-3@kokizami\r\n" +
+		"DTSTAMP:20240301T040000Z\r\n" +
+		"DTSTART:20240301T030000Z\r\n" +
+		"DTEND:20240301T040000Z\r\n" +
+		"SUMMARY:long long long long long long long long long long long long long lo\r\n" +
+		" ng long long long long long long \r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	if diff := cmp.Diff(buf.String(), want); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pankona/kokizami"
+	"github.com/urfave/cli"
+)
+
+// defaultListLimit is number of kizamis shown by list
+// if no filter is specified
+const defaultListLimit = 20
+
+// rangeFlags returns flags to filter kizamis
+func rangeFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "since",
+			Usage: "show tasks started at or after specified time (yyyy-mm-dd [hh:mm], hh:mm, or relative like -24h)",
+		},
+		cli.StringFlag{
+			Name:  "until",
+			Usage: "show tasks started before specified time (yyyy-mm-dd [hh:mm], hh:mm, or relative like -24h)",
+		},
+		cli.StringSliceFlag{
+			Name:  "tag",
+			Usage: "show tasks that have specified tag. can be specified multiple times",
+		},
+		cli.StringFlag{
+			Name:  "desc",
+			Usage: "show tasks whose description contains specified string",
+		},
+		cli.BoolFlag{
+			Name:  "running",
+			Usage: "show on-going tasks only",
+		},
+	}
+}
+
+func listFlags() []cli.Flag {
+	return append(rangeFlags(),
+		cli.IntFlag{
+			Name:  "n, limit",
+			Usage: fmt.Sprintf("specify max number of tasks to show, counted from the newest (default: %d if no filter is specified)", defaultListLimit),
+		},
+		cli.IntFlag{
+			Name:  "offset",
+			Usage: "specify number of newest tasks to skip",
+		},
+		cli.BoolFlag{
+			Name:  "a, all",
+			Usage: "show all tasks",
+		},
+		cli.BoolFlag{
+			Name:  "r, reverse",
+			Usage: "show from the newest",
+		},
+	)
+}
+
+// filterFromFlags builds a filter of kizamis from list flags.
+// last defaultLimit kizamis are shown if no filter is specified.
+// defaultLimit 0 means no limit.
+func filterFromFlags(c *cli.Context, now time.Time, defaultLimit int) (*kokizami.KizamiFilter, error) {
+	f := &kokizami.KizamiFilter{
+		Desc:    c.String("desc"),
+		Running: c.Bool("running"),
+		Limit:   c.Int("limit"),
+		Offset:  c.Int("offset"),
+		Reverse: c.Bool("reverse"),
+	}
+
+	var err error
+	if c.IsSet("since") {
+		f.Since, err = parseTimeValue(c.String("since"), time.Time{}, now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --since: %v", err)
+		}
+	}
+	if c.IsSet("until") {
+		f.Until, err = parseTimeValue(c.String("until"), time.Time{}, now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --until: %v", err)
+		}
+	}
+
+	for _, v := range c.StringSlice("tag") {
+		if !strings.HasPrefix(v, "#") {
+			v = "#" + v
+		}
+		f.Tags = append(f.Tags, v)
+	}
+
+	if c.Bool("all") {
+		if c.IsSet("limit") {
+			return nil, fmt.Errorf("--all and --limit can not be specified at once")
+		}
+		return f, nil
+	}
+
+	if !c.IsSet("limit") && f.Since.IsZero() && f.Until.IsZero() &&
+		len(f.Tags) == 0 && f.Desc == "" && !f.Running {
+		f.Limit = defaultLimit
+	}
+
+	return f, nil
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"time"
 
 	"github.com/pankona/kokizami"
 	"github.com/pankona/kokizami/cmd/kkzm/repo"
@@ -46,15 +47,30 @@ func main() {
 			return fmt.Errorf("failed to open DB: %v", err)
 		}
 
-		err = repo.CreateTables(db)
-		if err != nil {
-			return fmt.Errorf("failed to create tables: %v", err)
+		app.Metadata["configDir"] = configDir
+		app.Metadata["db"] = db
+
+		// migrations are applied explicitly by "kkzm db" subcommands
+		if ctx.Args().First() != "db" {
+			_, err = repo.Migrate(db)
+			if err != nil {
+				return fmt.Errorf("failed to migrate database: %v", err)
+			}
+		}
+
+		loc := time.Local
+		if tz := ctx.String("timezone"); tz != "" {
+			loc, err = time.LoadLocation(tz)
+			if err != nil {
+				return fmt.Errorf("failed to load timezone %s: %v", tz, err)
+			}
 		}
 
 		kkzm := &kokizami.Kokizami{
 			KizamiRepo:  repo.NewKizamiRepo(db),
 			TagRepo:     repo.NewTagRepo(db),
 			SummaryRepo: repo.NewSummaryRepo(db),
+			Location:    loc,
 		}
 
 		app.Metadata["kkzm"] = kkzm
@@ -66,7 +82,7 @@ func main() {
 		return db.Close()
 	}
 
-	err := app.Run(os.Args)
+	err := run(app, os.Args)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -74,6 +90,12 @@ func main() {
 	os.Exit(0)
 }
 
+// run runs app with command line arguments.
+// negative values of flags are joined to the flags before parsing.
+func run(app *cli.App, args []string) error {
+	return app.Run(joinNegativeValues(args, boolFlagNames(app.Flags, app.Commands, args[1:])))
+}
+
 func openDB(dbPath string) (*sql.DB, error) {
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
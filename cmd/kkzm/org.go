@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// org groups of headings
+const (
+	orgGroupTag  = "tag"
+	orgGroupDesc = "desc"
+)
+
+// orgNoTag is heading of kizamis without tags
+const orgNoTag = "No tag"
+
+// orgClock is an interval of a CLOCK line. Stop is zero for an open clock.
+type orgClock struct {
+	Start time.Time
+	Stop  time.Time
+}
+
+// orgClocks returns intervals of specified entry.
+// a paused entry has intervals for each segment.
+func orgClocks(e *entry) []orgClock {
+	if len(e.Segments) == 0 {
+		return []orgClock{{Start: e.StartedAt, Stop: e.StoppedAt}}
+	}
+
+	ret := make([]orgClock, len(e.Segments))
+	for i, v := range e.Segments {
+		ret[i].Start = v.StartedAt
+		if v.StoppedAt.Unix() != 0 {
+			ret[i].Stop = v.StoppedAt
+		}
+	}
+	return ret
+}
+
+func orgTimestamp(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format("[2006-01-02 Mon 15:04]")
+}
+
+// orgClockLine returns a CLOCK line like:
+//
+//	CLOCK: [2024-03-01 Fri 09:00]--[2024-03-01 Fri 10:30] =>  1:30
+//
+// times are truncated to minutes as Org mode does.
+func orgClockLine(c orgClock, loc *time.Location) string {
+	start := c.Start.Truncate(time.Minute)
+	if c.Stop.IsZero() {
+		return "CLOCK: " + orgTimestamp(start, loc)
+	}
+
+	stop := c.Stop.Truncate(time.Minute)
+	d := stop.Sub(start)
+	return fmt.Sprintf("CLOCK: %s--%s => %2d:%02d",
+		orgTimestamp(start, loc), orgTimestamp(stop, loc), int(d.Hours()), int(d.Minutes())%60)
+}
+
+func writeOrgLogbook(w io.Writer, es []*entry, loc *time.Location) error {
+	_, err := fmt.Fprintln(w, ":LOGBOOK:")
+	if err != nil {
+		return err
+	}
+
+	// newer clocks come first as Org mode does
+	for i := len(es) - 1; i >= 0; i-- {
+		cs := orgClocks(es[i])
+		for j := len(cs) - 1; j >= 0; j-- {
+			_, err = fmt.Fprintln(w, orgClockLine(cs[j], loc))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = fmt.Fprintln(w, ":END:")
+	return err
+}
+
+// orgTags returns Org tags like ":tag1:tag2:" of specified labels
+func orgTags(labels []string) string {
+	ss := make([]string, len(labels))
+	for i, v := range labels {
+		ss[i] = strings.TrimPrefix(v, "#")
+	}
+	return ":" + strings.Join(ss, ":") + ":"
+}
+
+// groupByTitle returns titles of headings in order of appearance and entries of each title
+func groupByTitle(es []*entry, title func(*entry) string) ([]string, map[string][]*entry) {
+	var titles []string
+	m := map[string][]*entry{}
+	for _, v := range es {
+		t := title(v)
+		if _, ok := m[t]; !ok {
+			titles = append(titles, t)
+		}
+		m[t] = append(m[t], v)
+	}
+	return titles, m
+}
+
+// exportOrg writes entries as an Org document that has CLOCK lines in LOGBOOK drawers.
+// entries are grouped by tag and then by desc, or only by desc.
+// an entry that has multiple tags appears once under its first tag,
+// and its other tags are written as Org tags of its heading.
+func exportOrg(w io.Writer, es []*entry, opts *transferOptions) error {
+	writeDescs := func(es []*entry, stars string, title func(*entry) string) error {
+		titles, m := groupByTitle(es, title)
+		for _, t := range titles {
+			_, err := fmt.Fprintf(w, "%s %s\n", stars, t)
+			if err != nil {
+				return err
+			}
+			err = writeOrgLogbook(w, m[t], opts.Location)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch opts.OrgGroup {
+	case "", orgGroupTag:
+	case orgGroupDesc:
+		return writeDescs(es, "*", func(e *entry) string { return e.Desc })
+	default:
+		return fmt.Errorf("unknown org group %s. should be %s or %s", opts.OrgGroup, orgGroupTag, orgGroupDesc)
+	}
+
+	var tags []string
+	m := map[string][]*entry{}
+	for _, e := range es {
+		if len(e.Tags) == 0 {
+			m[""] = append(m[""], e)
+			continue
+		}
+		m[e.Tags[0]] = append(m[e.Tags[0]], e)
+	}
+	for k := range m {
+		tags = append(tags, k)
+	}
+	sort.Strings(tags)
+
+	for _, t := range tags {
+		heading := t
+		if heading == "" {
+			heading = orgNoTag
+		}
+		_, err := fmt.Fprintf(w, "* %s\n", heading)
+		if err != nil {
+			return err
+		}
+		err = writeDescs(m[t], "**", func(e *entry) string {
+			if len(e.Tags) < 2 {
+				return e.Desc
+			}
+			return e.Desc + " " + orgTags(e.Tags[1:])
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	orgHeading  = regexp.MustCompile(`^(\*+)\s+(.*?)\s*$`)
+	orgHeadTags = regexp.MustCompile(`\s+(:[^\s:]+(?::[^\s:]+)*:)$`)
+	orgKeyword  = regexp.MustCompile(`^(?:TODO|DONE)\s+`)
+	orgTime     = `\[(\d{4}-\d{2}-\d{2})(?:\s+[^\d\s\]][^\s\]]*)?\s+(\d{1,2}:\d{2})\]`
+	orgClockRe  = regexp.MustCompile(`^\s*CLOCK:\s*` + orgTime + `(?:--` + orgTime + `)?`)
+)
+
+// orgHeadingTitle returns title and tags of a heading.
+// titles like "#tag" and Org tags like ":tag1:tag2:" are taken as tags.
+func orgHeadingTitle(s string) (string, []string) {
+	var tags []string
+	if m := orgHeadTags.FindStringSubmatch(s); m != nil {
+		for _, v := range strings.Split(strings.Trim(m[1], ":"), ":") {
+			tags = append(tags, "#"+v)
+		}
+		s = s[:len(s)-len(m[0])]
+	}
+	s = orgKeyword.ReplaceAllString(strings.TrimSpace(s), "")
+
+	if strings.HasPrefix(s, "#") && !strings.ContainsAny(s, " \t") {
+		tags = append(tags, s)
+	}
+	return s, tags
+}
+
+// importOrg reads CLOCK lines of an Org document.
+// desc is the title of the heading that has the CLOCK line, and tags are
+// taken from the heading and its ancestors. the same clock under several
+// headings of tags is imported once with all the tags.
+// clocks of 0:00 are ignored.
+func importOrg(r io.Reader, opts *transferOptions) ([]*entry, error) {
+	type heading struct {
+		level int
+		title string
+		tags  []string
+	}
+
+	var (
+		es       []*entry
+		errs     rowErrors
+		line     int
+		headings []heading
+		seen     = map[string]*entry{}
+	)
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line++
+		text := s.Text()
+
+		if m := orgHeading.FindStringSubmatch(text); m != nil {
+			level := len(m[1])
+			for len(headings) != 0 && headings[len(headings)-1].level >= level {
+				headings = headings[:len(headings)-1]
+			}
+			title, tags := orgHeadingTitle(m[2])
+			headings = append(headings, heading{level: level, title: title, tags: tags})
+			continue
+		}
+
+		if !strings.HasPrefix(strings.TrimSpace(text), "CLOCK:") {
+			continue
+		}
+
+		m := orgClockRe.FindStringSubmatch(text)
+		if m == nil {
+			errs = append(errs, &rowError{Line: line, Err: fmt.Errorf("invalid CLOCK line: %s", strings.TrimSpace(text))})
+			continue
+		}
+		if len(headings) == 0 {
+			errs = append(errs, &rowError{Line: line, Err: fmt.Errorf("CLOCK line must be under a heading")})
+			continue
+		}
+
+		e := &entry{
+			Line:      line,
+			Precision: time.Minute,
+		}
+		var err error
+		e.StartedAt, err = parseDateTime(m[1]+" "+m[2], opts.Location)
+		if err == nil && m[3] != "" {
+			e.StoppedAt, err = parseDateTime(m[3]+" "+m[4], opts.Location)
+		}
+		if err != nil {
+			errs = append(errs, &rowError{Line: line, Err: err})
+			continue
+		}
+		if e.StoppedAt.Equal(e.StartedAt) {
+			// clocks shorter than a minute have no time to import
+			continue
+		}
+
+		var tags []string
+		for _, h := range headings {
+			tags = append(tags, h.tags...)
+		}
+		e.Tags = mergeTags(tags)
+		e.Desc = descWithTags(headings[len(headings)-1].title, e.Tags)
+
+		key := headings[len(headings)-1].title + "\x00" + e.StartedAt.String() + "\x00" + e.StoppedAt.String()
+		if v, ok := seen[key]; ok {
+			v.Tags = mergeTags(v.Tags, e.Tags)
+			v.Desc = descWithTags(v.Desc, v.Tags)
+			continue
+		}
+		seen[key] = e
+		es = append(es, e)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(es, func(i, j int) bool {
+		return es[i].StartedAt.Before(es[j].StartedAt)
+	})
+
+	if len(errs) != 0 {
+		return es, errs
+	}
+	return es, nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pankona/kokizami"
+)
+
+func TestExportOrg(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	startedAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	es := []*entry{
+		{
+			Desc:      "write docs #doc #en",
+			Tags:      []string{"#doc", "#en"},
+			StartedAt: startedAt,
+			StoppedAt: startedAt.Add(90*time.Minute + 30*time.Second),
+		},
+		{
+			Desc:      "lunch",
+			StartedAt: startedAt.Add(3 * time.Hour),
+			StoppedAt: startedAt.Add(14 * time.Hour),
+			Segments: []kokizami.Segment{
+				{StartedAt: startedAt.Add(3 * time.Hour), StoppedAt: startedAt.Add(4 * time.Hour)},
+				{StartedAt: startedAt.Add(5 * time.Hour), StoppedAt: time.Unix(0, 0).UTC()},
+			},
+		},
+	}
+
+	tcs := []struct {
+		inGroup string
+		want    string
+	}{
+		{
+			inGroup: orgGroupTag,
+			want: "* No tag\n" +
+				"** lunch\n" +
+				":LOGBOOK:\n" +
+				"CLOCK: [2024-03-01 Fri 14:00]\n" +
+				"CLOCK: [2024-03-01 Fri 12:00]--[2024-03-01 Fri 13:00] =>  1:00\n" +
+				":END:\n" +
+				"* #doc\n" +
+				"** write docs #doc #en :en:\n" +
+				":LOGBOOK:\n" +
+				"CLOCK: [2024-03-01 Fri 09:00]--[2024-03-01 Fri 10:30] =>  1:30\n" +
+				":END:\n",
+		},
+		{
+			inGroup: orgGroupDesc,
+			want: "* write docs #doc #en\n" +
+				":LOGBOOK:\n" +
+				"CLOCK: [2024-03-01 Fri 09:00]--[2024-03-01 Fri 10:30] =>  1:30\n" +
+				":END:\n" +
+				"* lunch\n" +
+				":LOGBOOK:\n" +
+				"CLOCK: [2024-03-01 Fri 14:00]\n" +
+				"CLOCK: [2024-03-01 Fri 12:00]--[2024-03-01 Fri 13:00] =>  1:00\n" +
+				":END:\n",
+		},
+	}
+
+	for i, tc := range tcs {
+		buf := &bytes.Buffer{}
+		err := exportOrg(buf, es, &transferOptions{Location: loc, OrgGroup: tc.inGroup})
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if diff := cmp.Diff(buf.String(), tc.want); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+}
+
+func TestImportOrg(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+
+	in := "#+TITLE: notes\n" +
+		"* #doc\n" +
+		"** write docs #en\n" +
+		":LOGBOOK:\n" +
+		"CLOCK: [2024-03-01 Fri 09:00]--[2024-03-01 Fri 10:30] =>  1:30\n" +
+		":END:\n" +
+		"* #en\n" +
+		"** write docs #en\n" +
+		"   :LOGBOOK:\n" +
+		"   CLOCK: [2024-03-01 Fri 09:00]--[2024-03-01 Fri 10:30] =>  1:30\n" +
+		"   :END:\n" +
+		"* TODO review kkzm :work:review:\n" +
+		":LOGBOOK:\n" +
+		"CLOCK: [2024-03-01 金 14:00]\n" +
+		"CLOCK: [2024-03-01 Fri 11:00]--[2024-03-01 Fri 11:00] =>  0:00\n" +
+		"CLOCK: [2024-03-01 Fri 12:00]--[2024-03-01 Fri 13:00] =>  1:00\n" +
+		":END:\n"
+
+	ret, err := importOrg(strings.NewReader(in), &transferOptions{Location: loc})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	type result struct {
+		Desc      string
+		Tags      []string
+		StartedAt time.Time
+		StoppedAt time.Time
+	}
+	got := make([]result, len(ret))
+	for i, v := range ret {
+		got[i] = result{v.Desc, v.Tags, v.StartedAt, v.StoppedAt}
+	}
+	want := []result{
+		{
+			Desc:      "write docs #en #doc",
+			Tags:      []string{"#doc", "#en"},
+			StartedAt: time.Date(2024, 3, 1, 9, 0, 0, 0, loc),
+			StoppedAt: time.Date(2024, 3, 1, 10, 30, 0, 0, loc),
+		},
+		{
+			Desc:      "review kkzm #work #review",
+			Tags:      []string{"#work", "#review"},
+			StartedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, loc),
+			StoppedAt: time.Date(2024, 3, 1, 13, 0, 0, 0, loc),
+		},
+		{
+			Desc:      "review kkzm #work #review",
+			Tags:      []string{"#work", "#review"},
+			StartedAt: time.Date(2024, 3, 1, 14, 0, 0, 0, loc),
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	_, err = importOrg(strings.NewReader("CLOCK: [2024-03-01 Fri 09:00]\n* a\nCLOCK: [2024-03-01]\n"), &transferOptions{Location: loc})
+	errs, ok := err.(rowErrors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("unexpected result: [got] %v [want] errors on line 1 and 3", err)
+	}
+}
+
+func TestOrgRoundTrip(t *testing.T) {
+	startedAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	es := []*entry{
+		{
+			Desc:      "write docs",
+			Tags:      []string{"#doc", "#en", "#review"},
+			StartedAt: startedAt,
+			StoppedAt: startedAt.Add(90 * time.Minute),
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	err := exportOrg(buf, es, &transferOptions{Location: time.UTC})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	// an entry of multiple tags is written and read once
+	ret, err := importOrg(buf, &transferOptions{Location: time.UTC})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(ret) != 1 {
+		t.Fatalf("unexpected result: [got] %v [want] 1 entry", len(ret))
+	}
+	if diff := cmp.Diff(ret[0].Tags, es[0].Tags); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+}
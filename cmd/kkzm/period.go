@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// period represents a half-open time range [from, to) to summarize
+type period struct {
+	label string
+	from  time.Time
+	to    time.Time
+}
+
+func periodFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "m, month",
+			Usage: "specify year and month to show summary (yyyy-mm). default is this month",
+		},
+		cli.StringFlag{
+			Name:  "d, day",
+			Usage: "specify a day to show summary (yyyy-mm-dd or \"today\")",
+		},
+		cli.StringFlag{
+			Name:  "w, week",
+			Usage: "specify an ISO week to show summary (yyyy-Www or \"this\")",
+		},
+		cli.StringFlag{
+			Name:  "q, quarter",
+			Usage: "specify a quarter to show summary (yyyy-Qn)",
+		},
+		cli.StringFlag{
+			Name:  "y, year",
+			Usage: "specify a year to show summary (yyyy)",
+		},
+		cli.StringFlag{
+			Name:  "from",
+			Usage: "specify beginning of range to show summary (yyyy-mm-dd [hh:mm])",
+		},
+		cli.StringFlag{
+			Name:  "to",
+			Usage: "specify end of range (exclusive) to show summary (yyyy-mm-dd [hh:mm]). default is tomorrow",
+		},
+	}
+}
+
+// periodFromFlags returns a period specified by command line flags.
+// month is used if no other period is specified.
+func periodFromFlags(c *cli.Context, now time.Time, loc *time.Location) (*period, error) {
+	var specified []string
+	for _, v := range []string{"month", "day", "week", "quarter", "year"} {
+		if c.IsSet(v) {
+			specified = append(specified, v)
+		}
+	}
+	if c.IsSet("from") || c.IsSet("to") {
+		specified = append(specified, "from/to")
+	}
+	if len(specified) > 1 {
+		return nil, fmt.Errorf("only one of period can be specified: %s", strings.Join(specified, ", "))
+	}
+
+	now = now.In(loc)
+	switch {
+	case c.IsSet("day"):
+		return dayPeriod(c.String("day"), now, loc)
+	case c.IsSet("week"):
+		return weekPeriod(c.String("week"), now, loc)
+	case c.IsSet("quarter"):
+		return quarterPeriod(c.String("quarter"), loc)
+	case c.IsSet("year"):
+		return yearPeriod(c.String("year"), loc)
+	case c.IsSet("from") || c.IsSet("to"):
+		return customPeriod(c.String("from"), c.String("to"), now, loc)
+	case c.String("month") != "":
+		return monthPeriod(c.String("month"), loc)
+	default:
+		// this month is computed in loc, not in the zone of the machine
+		return monthPeriod(now.Format("2006-01"), loc)
+	}
+}
+
+func dayPeriod(s string, now time.Time, loc *time.Location) (*period, error) {
+	var (
+		from time.Time
+		err  error
+	)
+	switch s {
+	case "today":
+		from = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	case "yesterday":
+		from = time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, loc)
+	default:
+		from, err = time.ParseInLocation("2006-01-02", s, loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid day format. should be yyyy-mm-dd: %v", err)
+		}
+	}
+
+	return &period{
+		label: from.Format("2006-01-02"),
+		from:  from,
+		to:    from.AddDate(0, 0, 1),
+	}, nil
+}
+
+// isoWeekStart returns Monday of specified ISO week
+func isoWeekStart(year, week int, loc *time.Location) time.Time {
+	// January 4th is always in the first week of ISO week year
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, loc)
+	offset := (int(jan4.Weekday()) + 6) % 7 // days since Monday
+	return jan4.AddDate(0, 0, -offset+(week-1)*7)
+}
+
+func weekPeriod(s string, now time.Time, loc *time.Location) (*period, error) {
+	var year, week int
+	if s == "this" {
+		year, week = now.ISOWeek()
+	} else {
+		ss := strings.Split(s, "-W")
+		if len(ss) != 2 {
+			return nil, fmt.Errorf("invalid week format. should be yyyy-Www: %s", s)
+		}
+		var err error
+		year, err = strconv.Atoi(ss[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid week format. should be yyyy-Www: %v", err)
+		}
+		week, err = strconv.Atoi(ss[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid week format. should be yyyy-Www: %v", err)
+		}
+		if week < 1 || week > 53 {
+			return nil, fmt.Errorf("invalid week number: %d", week)
+		}
+	}
+
+	from := isoWeekStart(year, week, loc)
+	if y, w := from.ISOWeek(); y != year || w != week {
+		return nil, fmt.Errorf("week %d does not exist in %d", week, year)
+	}
+
+	return &period{
+		label: fmt.Sprintf("%04d-W%02d", year, week),
+		from:  from,
+		to:    from.AddDate(0, 0, 7),
+	}, nil
+}
+
+func monthPeriod(s string, loc *time.Location) (*period, error) {
+	from, err := time.ParseInLocation("2006-01", s, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid argument format. should be yyyy-mm: %v", err)
+	}
+
+	return &period{
+		label: s,
+		from:  from,
+		to:    from.AddDate(0, 1, 0),
+	}, nil
+}
+
+func quarterPeriod(s string, loc *time.Location) (*period, error) {
+	ss := strings.Split(s, "-Q")
+	if len(ss) != 2 {
+		return nil, fmt.Errorf("invalid quarter format. should be yyyy-Qn: %s", s)
+	}
+	year, err := strconv.Atoi(ss[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid quarter format. should be yyyy-Qn: %v", err)
+	}
+	quarter, err := strconv.Atoi(ss[1])
+	if err != nil || quarter < 1 || quarter > 4 {
+		return nil, fmt.Errorf("invalid quarter format. should be yyyy-Qn: %s", s)
+	}
+
+	from := time.Date(year, time.Month((quarter-1)*3+1), 1, 0, 0, 0, 0, loc)
+	return &period{
+		label: fmt.Sprintf("%04d-Q%d", year, quarter),
+		from:  from,
+		to:    from.AddDate(0, 3, 0),
+	}, nil
+}
+
+func yearPeriod(s string, loc *time.Location) (*period, error) {
+	from, err := time.ParseInLocation("2006", s, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid year format. should be yyyy: %v", err)
+	}
+
+	return &period{
+		label: s,
+		from:  from,
+		to:    from.AddDate(1, 0, 0),
+	}, nil
+}
+
+func customPeriod(from, to string, now time.Time, loc *time.Location) (*period, error) {
+	if from == "" {
+		return nil, fmt.Errorf("--from must be specified with --to")
+	}
+
+	f, err := parseDateTime(from, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	t := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, loc)
+	if to != "" {
+		t, err = parseDateTime(to, loc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !f.Before(t) {
+		return nil, fmt.Errorf("--from (%s) must be before --to (%s)", from, to)
+	}
+
+	return &period{
+		label: f.Format("2006-01-02 15:04") + " - " + t.Format("2006-01-02 15:04"),
+		from:  f,
+		to:    t,
+	}, nil
+}
+
+// parseDateTime parses a date or date and time in specified location
+func parseDateTime(s string, loc *time.Location) (time.Time, error) {
+	layouts := []string{
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04",
+		"2006-01-02",
+	}
+	for _, layout := range layouts {
+		t, err := time.ParseInLocation(layout, s, loc)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date format. should be yyyy-mm-dd [hh:mm[:ss]]: %s", s)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+func TestWeekPeriod(t *testing.T) {
+	tcs := []struct {
+		in       string
+		wantFrom time.Time
+		wantErr  bool
+	}{
+		{
+			in:       "2024-W01",
+			wantFrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// ISO week 1 of 2021 starts on 2021-01-04
+			in:       "2021-W01",
+			wantFrom: time.Date(2021, 1, 4, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// ISO week 1 of 2020 starts on 2019-12-30
+			in:       "2020-W01",
+			wantFrom: time.Date(2019, 12, 30, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			in:       "2020-W53",
+			wantFrom: time.Date(2020, 12, 28, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			in:      "2021-W53",
+			wantErr: true,
+		},
+		{
+			in:      "2021-10",
+			wantErr: true,
+		},
+	}
+
+	for i, tc := range tcs {
+		p, err := weekPeriod(tc.in, time.Now(), time.UTC)
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("[No.%d] unexpected result: [got] nil [want] some error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if !p.from.Equal(tc.wantFrom) || !p.to.Equal(tc.wantFrom.AddDate(0, 0, 7)) {
+			t.Fatalf("[No.%d] unexpected result: [got] %v - %v [want] %v", i, p.from, p.to, tc.wantFrom)
+		}
+	}
+}
+
+func TestQuarterPeriod(t *testing.T) {
+	p, err := quarterPeriod("2024-Q4", time.UTC)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	wantFrom := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	wantTo := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if !p.from.Equal(wantFrom) || !p.to.Equal(wantTo) {
+		t.Fatalf("unexpected result: [got] %v - %v [want] %v - %v", p.from, p.to, wantFrom, wantTo)
+	}
+
+	_, err = quarterPeriod("2024-Q5", time.UTC)
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+}
+
+func TestPeriodFromFlagsThisMonth(t *testing.T) {
+	set := flag.NewFlagSet("summary", flag.ContinueOnError)
+	for _, f := range periodFlags() {
+		f.Apply(set)
+	}
+	c := cli.NewContext(nil, set, nil)
+
+	// it is still March in UTC, but already April in the location
+	now := time.Date(2024, 3, 31, 20, 0, 0, 0, time.UTC)
+	loc := time.FixedZone("UTC+9", 9*60*60)
+
+	p, err := periodFromFlags(c, now, loc)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	wantFrom := time.Date(2024, 4, 1, 0, 0, 0, 0, loc)
+	wantTo := time.Date(2024, 5, 1, 0, 0, 0, 0, loc)
+	if p.label != "2024-04" || !p.from.Equal(wantFrom) || !p.to.Equal(wantTo) {
+		t.Errorf("unexpected result: [got] %v %v - %v [want] 2024-04 %v - %v", p.label, p.from, p.to, wantFrom, wantTo)
+	}
+}
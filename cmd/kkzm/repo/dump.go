@@ -0,0 +1,149 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/pankona/kokizami/models"
+)
+
+// DumpFormat identifies a dump of kokizami
+const DumpFormat = "kokizami-dump"
+
+// Dump is a full snapshot of the database.
+// Rows are held with their original IDs so that restoring a dump
+// reproduces the database exactly, including untagged kizamis,
+// orphan tags and running kizamis that are not stopped yet.
+type Dump struct {
+	Format        string             `json:"format"`
+	SchemaVersion int                `json:"schema_version"`
+	CreatedAt     time.Time          `json:"created_at"`
+	Kizamis       []*models.Kizami   `json:"kizamis"`
+	Tags          []*models.Tag      `json:"tags"`
+	Relations     []*models.Relation `json:"relations"`
+	Segments      []*models.Segment  `json:"segments"`
+}
+
+// NewDump takes a snapshot of the database within a transaction
+func NewDump(db *sql.DB) (*Dump, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	// nothing to commit since the transaction is read only
+	defer func() {
+		e := tx.Rollback()
+		if e != nil {
+			models.XOLog(fmt.Sprintf("failed to rollback: %v", e))
+		}
+	}()
+
+	d := &Dump{
+		Format:    DumpFormat,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	d.SchemaVersion, err = SchemaVersion(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema version: %v", err)
+	}
+
+	d.Kizamis, err = models.AllKizami(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dump kizamis: %v", err)
+	}
+
+	d.Tags, err = models.AllTags(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dump tags: %v", err)
+	}
+
+	d.Relations, err = models.AllRelations(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dump relations: %v", err)
+	}
+
+	d.Segments, err = models.AllSegments(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dump segments: %v", err)
+	}
+
+	return d, nil
+}
+
+// Restore restores a dump into an empty database within a transaction.
+// It refuses dumps that are taken from a newer schema than the database.
+func Restore(db *sql.DB, d *Dump) error {
+	if d.Format != DumpFormat {
+		return fmt.Errorf("not a dump of kokizami. format is %q", d.Format)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = restore(tx, d)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return fmt.Errorf("failed to rollback: %v (original error: %v)", e, err)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func restore(tx *sql.Tx, d *Dump) error {
+	version, err := SchemaVersion(tx)
+	if err != nil {
+		return err
+	}
+	if d.SchemaVersion > version {
+		return fmt.Errorf("dump schema version %d is newer than database schema version %d. please upgrade kkzm",
+			d.SchemaVersion, version)
+	}
+
+	empty, err := models.IsEmpty(tx)
+	if err != nil {
+		return err
+	}
+	if !empty {
+		return fmt.Errorf("database is not empty. dump can be restored only into an empty database")
+	}
+
+	for _, v := range d.Kizamis {
+		v.StartedAt.Time = v.StartedAt.UTC()
+		v.StoppedAt.Time = v.StoppedAt.UTC()
+		err = v.InsertWithID(tx)
+		if err != nil {
+			return fmt.Errorf("failed to restore kizami %d: %v", v.ID, err)
+		}
+	}
+
+	for _, v := range d.Tags {
+		err = v.InsertWithID(tx)
+		if err != nil {
+			return fmt.Errorf("failed to restore tag %d: %v", v.ID, err)
+		}
+	}
+
+	for _, v := range d.Relations {
+		err = v.InsertWithID(tx)
+		if err != nil {
+			return fmt.Errorf("failed to restore relation %d: %v", v.ID, err)
+		}
+	}
+
+	for _, v := range d.Segments {
+		v.StartedAt.Time = v.StartedAt.UTC()
+		v.StoppedAt.Time = v.StoppedAt.UTC()
+		err = v.InsertWithID(tx)
+		if err != nil {
+			return fmt.Errorf("failed to restore segment %d: %v", v.ID, err)
+		}
+	}
+
+	return nil
+}
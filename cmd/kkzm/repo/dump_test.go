@@ -0,0 +1,163 @@
+package repo_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pankona/kokizami"
+	"github.com/pankona/kokizami/cmd/kkzm/repo"
+	"github.com/pankona/kokizami/cmd/kkzm/repo/repotest"
+)
+
+// migratedDB returns a migrated temporary database
+func migratedDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db := repotest.OpenDB(t)
+	_, err := repo.Migrate(db)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	return db
+}
+
+// dumpOf returns a dump of a database that has tagged, paused,
+// running kizamis and an orphan tag
+func dumpOf(t *testing.T, db *sql.DB) *repo.Dump {
+	t.Helper()
+
+	kkzm := &kokizami.Kokizami{
+		KizamiRepo:  repo.NewKizamiRepo(db),
+		TagRepo:     repo.NewTagRepo(db),
+		SummaryRepo: repo.NewSummaryRepo(db),
+		Location:    time.UTC,
+	}
+
+	startedAt := time.Now().UTC().Add(-3 * time.Hour).Truncate(time.Second)
+	k, err := kkzm.Add("write docs #doc", startedAt, startedAt.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = kkzm.TagByDesc(k.ID, k.Desc)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = kkzm.AddTags([]string{"#orphan"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	k, err = kkzm.Start("review")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = kkzm.Pause(k.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	_, err = kkzm.Start("on-going")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	d, err := repo.NewDump(db)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	return d
+}
+
+func jsonOf(t *testing.T, d *repo.Dump) string {
+	t.Helper()
+
+	b, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	return string(b)
+}
+
+// viaJSON returns a dump that is encoded and decoded as written to a file
+func viaJSON(t *testing.T, d *repo.Dump) *repo.Dump {
+	t.Helper()
+
+	ret := &repo.Dump{}
+	err := json.Unmarshal([]byte(jsonOf(t, d)), ret)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	return ret
+}
+
+func TestDumpRoundTrip(t *testing.T) {
+	d := dumpOf(t, migratedDB(t))
+	if len(d.Kizamis) != 3 || len(d.Tags) != 2 || len(d.Relations) != 1 || len(d.Segments) != 1 {
+		t.Fatalf("unexpected result: [got] %d kizamis, %d tags, %d relations, %d segments [want] 3, 2, 1, 1",
+			len(d.Kizamis), len(d.Tags), len(d.Relations), len(d.Segments))
+	}
+
+	db := migratedDB(t)
+	err := repo.Restore(db, viaJSON(t, d))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	ret, err := repo.NewDump(db)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	// dumps are compared as written to files since models have unexported fields
+	ret.CreatedAt = d.CreatedAt
+	if diff := cmp.Diff(jsonOf(t, ret), jsonOf(t, d)); diff != "" {
+		t.Errorf("unexpected result (-got +want):\n%s", diff)
+	}
+}
+
+func TestRestoreNotEmpty(t *testing.T) {
+	d := dumpOf(t, migratedDB(t))
+
+	db := migratedDB(t)
+	dumpOf(t, db)
+	err := repo.Restore(db, viaJSON(t, d))
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] error")
+	}
+}
+
+func TestRestoreError(t *testing.T) {
+	tcs := []struct {
+		modify func(d *repo.Dump)
+	}{
+		{
+			// dump of a newer schema
+			modify: func(d *repo.Dump) { d.SchemaVersion = repo.LatestSchemaVersion() + 1 },
+		},
+		{
+			modify: func(d *repo.Dump) { d.Format = "other" },
+		},
+	}
+
+	for i, tc := range tcs {
+		d := viaJSON(t, dumpOf(t, migratedDB(t)))
+		tc.modify(d)
+
+		db := migratedDB(t)
+		err := repo.Restore(db, d)
+		if err == nil {
+			t.Errorf("[No.%d] unexpected result: [got] nil [want] error", i)
+			continue
+		}
+
+		// nothing is restored
+		ret, err := repo.NewDump(db)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if len(ret.Kizamis) != 0 || len(ret.Tags) != 0 {
+			t.Errorf("[No.%d] unexpected result: [got] %v, %v [want] empty", i, ret.Kizamis, ret.Tags)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package repo
 
 import (
-	"database/sql"
 	"fmt"
 	"time"
 
@@ -12,13 +11,13 @@ import (
 
 // KizamiRepo is an implementation of KizamiRepository using sqlite3
 type KizamiRepo struct {
-	db  *sql.DB
+	db  models.XODB
 	now func() time.Time
 }
 
-// NewKizamiRepo returns an KizamiRepo
+// NewKizamiRepo returns an KizamiRepo on specified *sql.DB or *sql.Tx
 // as an implementation of KizamiRepository
-func NewKizamiRepo(db *sql.DB) *KizamiRepo {
+func NewKizamiRepo(db models.XODB) *KizamiRepo {
 	return &KizamiRepo{
 		db:  db,
 		now: time.Now,
@@ -39,6 +38,15 @@ func toKizami(m *models.Kizami) *kokizami.Kizami {
 	}
 }
 
+func toSegment(m *models.Segment) *kokizami.Segment {
+	return &kokizami.Segment{
+		ID:        m.ID,
+		KizamiID:  m.KizamiID,
+		StartedAt: m.StartedAt.Time,
+		StoppedAt: m.StoppedAt.Time,
+	}
+}
+
 func initialTime() time.Time {
 	t, err := time.Parse("2006-01-02 15:04:05", "1970-01-01 00:00:00")
 	if err != nil {
@@ -49,10 +57,15 @@ func initialTime() time.Time {
 
 // Insert inserts a kizami with specified desc
 func (r *KizamiRepo) Insert(desc string) (*kokizami.Kizami, error) {
+	return r.InsertWithTimes(desc, r.now().UTC(), initialTime())
+}
+
+// InsertWithTimes inserts a kizami with specified desc, started_at and stopped_at
+func (r *KizamiRepo) InsertWithTimes(desc string, startedAt, stoppedAt time.Time) (*kokizami.Kizami, error) {
 	m := &models.Kizami{
 		Desc:      desc,
-		StartedAt: SqTime(r.now().UTC()),
-		StoppedAt: SqTime(initialTime()),
+		StartedAt: SqTime(startedAt),
+		StoppedAt: SqTime(stoppedAt),
 	}
 
 	err := m.Insert(r.db)
@@ -70,27 +83,19 @@ func (r *KizamiRepo) FindAll() ([]*kokizami.Kizami, error) {
 		return nil, err
 	}
 
-	ks := make([]kokizami.Kizami, len(ms))
-	for i, v := range ms {
-		ks[i].ID = v.ID
-		ks[i].Desc = v.Desc
-		ks[i].StartedAt = v.StartedAt.Time
-		ks[i].StoppedAt = v.StoppedAt.Time
-	}
-
-	ret := make([]*kokizami.Kizami, len(ms))
-	for i := range ms {
-		ret[i] = &ks[i]
+	ss, err := models.AllSegments(r.db)
+	if err != nil {
+		return nil, err
 	}
 
-	return ret, nil
+	return withSegments(ms, ss), nil
 }
 
 // Update updates a kizami with specified kizami
 func (r *KizamiRepo) Update(k *kokizami.Kizami) error {
 	m, err := models.KizamiByID(r.db, k.ID)
 	if err != nil {
-		return err
+		return notFound(err, "kizami", k.ID)
 	}
 
 	m.Desc = k.Desc
@@ -103,6 +108,11 @@ func (r *KizamiRepo) Update(k *kokizami.Kizami) error {
 // Delete deletes specified kizami
 func (r *KizamiRepo) Delete(k *kokizami.Kizami) error {
 	m, err := models.KizamiByID(r.db, k.ID)
+	if err != nil {
+		return notFound(err, "kizami", k.ID)
+	}
+
+	err = models.DeleteSegmentsByKizamiID(r.db, k.ID)
 	if err != nil {
 		return err
 	}
@@ -113,11 +123,17 @@ func (r *KizamiRepo) Delete(k *kokizami.Kizami) error {
 // FindByID finds a kizami by specified ID
 func (r *KizamiRepo) FindByID(id int) (*kokizami.Kizami, error) {
 	m, err := models.KizamiByID(r.db, id)
+	if err != nil {
+		return nil, notFound(err, "kizami", id)
+	}
+
+	k := toKizami(m)
+	k.Segments, err = r.findSegments(id)
 	if err != nil {
 		return nil, err
 	}
 
-	return toKizami(m), nil
+	return k, nil
 }
 
 // FindByStoppedAt finds kizamis that has specified stopped at
@@ -127,12 +143,57 @@ func (r *KizamiRepo) FindByStoppedAt(t time.Time) ([]*kokizami.Kizami, error) {
 		return nil, err
 	}
 
+	return r.toKizamis(ms)
+}
+
+// FindByFilter finds kizamis that match specified filter
+func (r *KizamiRepo) FindByFilter(f *kokizami.KizamiFilter) ([]*kokizami.Kizami, error) {
+	ms, err := models.KizamisByFilter(r.db, &models.KizamiFilter{
+		Since:   f.Since,
+		Until:   f.Until,
+		Tags:    f.Tags,
+		Desc:    f.Desc,
+		Running: f.Running,
+		Limit:   f.Limit,
+		Offset:  f.Offset,
+		Reverse: f.Reverse,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.toKizamis(ms)
+}
+
+// toKizamis converts models to kizamis with their segments
+func (r *KizamiRepo) toKizamis(ms []*models.Kizami) ([]*kokizami.Kizami, error) {
+	ids := make([]int, len(ms))
+	for i, v := range ms {
+		ids[i] = v.ID
+	}
+
+	ss, err := models.SegmentsByKizamiIDs(r.db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return withSegments(ms, ss), nil
+}
+
+// withSegments converts models to kizamis with segments among specified ones
+func withSegments(ms []*models.Kizami, ss []*models.Segment) []*kokizami.Kizami {
+	segments := map[int][]kokizami.Segment{}
+	for _, v := range ss {
+		segments[v.KizamiID] = append(segments[v.KizamiID], *toSegment(v))
+	}
+
 	ks := make([]kokizami.Kizami, len(ms))
 	for i, v := range ms {
 		ks[i].ID = v.ID
 		ks[i].Desc = v.Desc
 		ks[i].StartedAt = v.StartedAt.Time
 		ks[i].StoppedAt = v.StoppedAt.Time
+		ks[i].Segments = segments[v.ID]
 	}
 
 	ret := make([]*kokizami.Kizami, len(ms))
@@ -140,7 +201,7 @@ func (r *KizamiRepo) FindByStoppedAt(t time.Time) ([]*kokizami.Kizami, error) {
 		ret[i] = &ks[i]
 	}
 
-	return ret, nil
+	return ret
 }
 
 // Tagging make relation between kizami and tags
@@ -158,3 +219,52 @@ func (r *KizamiRepo) Tagging(kizamiID int, tagIDs []int) error {
 func (r *KizamiRepo) Untagging(kizamiID int) error {
 	return models.DeleteRelationsByKizamiID(r.db, kizamiID)
 }
+
+func (r *KizamiRepo) findSegments(kizamiID int) ([]kokizami.Segment, error) {
+	ms, err := models.SegmentsByKizamiID(r.db, kizamiID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ms) == 0 {
+		return nil, nil
+	}
+
+	ret := make([]kokizami.Segment, len(ms))
+	for i := range ms {
+		ret[i] = *toSegment(ms[i])
+	}
+
+	return ret, nil
+}
+
+// InsertSegment inserts a segment of a kizami
+func (r *KizamiRepo) InsertSegment(s *kokizami.Segment) error {
+	m := &models.Segment{
+		KizamiID:  s.KizamiID,
+		StartedAt: SqTime(s.StartedAt),
+		StoppedAt: SqTime(s.StoppedAt),
+	}
+
+	err := m.Insert(r.db)
+	if err != nil {
+		return err
+	}
+
+	s.ID = m.ID
+	return nil
+}
+
+// UpdateSegment updates a segment with specified segment
+func (r *KizamiRepo) UpdateSegment(s *kokizami.Segment) error {
+	m, err := models.SegmentByID(r.db, s.ID)
+	if err != nil {
+		return notFound(err, "segment", s.ID)
+	}
+
+	m.KizamiID = s.KizamiID
+	m.StartedAt = SqTime(s.StartedAt)
+	m.StoppedAt = SqTime(s.StoppedAt)
+
+	return m.Update(r.db)
+}
@@ -0,0 +1,130 @@
+package repo_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pankona/kokizami"
+	"github.com/pankona/kokizami/cmd/kkzm/repo"
+	"github.com/pankona/kokizami/cmd/kkzm/repo/repotest"
+)
+
+func TestFindByFilterSegments(t *testing.T) {
+	k := repotest.OpenTemp(t)
+
+	paused := map[int]bool{}
+	for i := 0; i < 4; i++ {
+		ki, err := k.Start("hoge")
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+		if i%2 == 0 {
+			continue
+		}
+		if err := k.Pause(ki.ID); err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+		paused[ki.ID] = true
+	}
+
+	ks, err := k.ListByFilter(&kokizami.KizamiFilter{})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(ks) != 4 {
+		t.Fatalf("unexpected result: [got] %v [want] 4", len(ks))
+	}
+	for _, v := range ks {
+		if got, want := len(v.Segments) == 1, paused[v.ID]; got != want || v.IsPaused() != want {
+			t.Errorf("[ID %d] unexpected result: [got] %v [want] %v", v.ID, v.Segments, want)
+		}
+	}
+}
+
+func TestUpdateSegmentNotFound(t *testing.T) {
+	k := repotest.OpenTemp(t)
+
+	err := k.KizamiRepo.UpdateSegment(&kokizami.Segment{ID: 999})
+	if !errors.Is(err, kokizami.ErrNotFound) {
+		t.Errorf("unexpected result: [got] %v [want] %v", err, kokizami.ErrNotFound)
+	}
+}
+
+func TestEditOnGoing(t *testing.T) {
+	k := repotest.OpenTemp(t)
+
+	ki, err := k.Start("hoge")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if err := k.Pause(ki.ID); err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if err := k.Resume(ki.ID); err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if err := k.Stop(ki.ID); err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	// the stopped kizami is made on-going by stop of the initial time
+	stopped, err := k.Get(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	stopped.StoppedAt = time.Unix(0, 0).UTC()
+	ret, err := k.Edit(stopped)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if !ret.IsRunning() || len(ret.Segments) != 2 {
+		t.Errorf("unexpected result: [got] %+v [want] running kizami of 2 segments", ret)
+	}
+}
+
+func TestElapsedOfMonth(t *testing.T) {
+	k := repotest.OpenTemp(t)
+	r := k.SummaryRepo.(*repo.SummaryRepo)
+
+	for _, v := range []struct {
+		desc                 string
+		startedAt, stoppedAt time.Time
+	}{
+		{"hoge #a", time.Date(2024, 3, 31, 23, 0, 0, 0, time.UTC), time.Date(2024, 4, 1, 1, 0, 0, 0, time.UTC)},
+		{"fuga #a", time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC), time.Date(2024, 4, 1, 10, 0, 0, 0, time.UTC)},
+	} {
+		ki, err := k.Add(v.desc, v.startedAt, v.stoppedAt)
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+		if err := k.TagByDesc(ki.ID, ki.Desc); err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+	}
+
+	// kizamis started in the month are counted entirely
+	byDesc, err := r.ElapsedOfMonthByDesc("2024-03")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	want := []*kokizami.Elapsed{{Tag: "#a", Desc: "hoge #a", Count: 1, Elapsed: 2 * time.Hour}}
+	if diff := cmp.Diff(byDesc, want); diff != "" {
+		t.Errorf("unexpected result: (-got +want) %s", diff)
+	}
+
+	byTag, err := r.ElapsedOfMonthByTag("2024-04")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	want = []*kokizami.Elapsed{{Tag: "#a", Desc: "fuga #a", Count: 1, Elapsed: time.Hour}}
+	if diff := cmp.Diff(byTag, want); diff != "" {
+		t.Errorf("unexpected result: (-got +want) %s", diff)
+	}
+
+	_, err = r.ElapsedOfMonthByTag("2024/04")
+	if !errors.Is(err, kokizami.ErrInvalid) {
+		t.Errorf("unexpected result: [got] %v [want] %v", err, kokizami.ErrInvalid)
+	}
+}
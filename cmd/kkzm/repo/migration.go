@@ -0,0 +1,138 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/pankona/kokizami/models"
+)
+
+// Migration represents a schema change of the database.
+// Up is applied within a transaction.
+type Migration struct {
+	Version int
+	Desc    string
+	Up      func(db models.XODB) error
+}
+
+// migrations is the ordered list of schema changes.
+// A new schema change must be appended to the tail with the next version.
+// Never modify or remove migrations that are already released.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Desc:    "create kizami, tag and relation tables",
+		Up:      CreateTables,
+	},
+	{
+		Version: 2,
+		Desc:    "create segment table to record paused kizamis",
+		Up:      models.CreateSegmentTable,
+	},
+}
+
+// LatestSchemaVersion returns the schema version this binary expects
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// AppliedMigrations returns migrations that have been applied to the database
+func AppliedMigrations(db models.XODB) ([]*models.SchemaMigration, error) {
+	exists, err := models.SchemaMigrationTableExists(db)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return []*models.SchemaMigration{}, nil
+	}
+	return models.AllSchemaMigrations(db)
+}
+
+// SchemaVersion returns the current schema version of the database.
+// 0 is returned if no migration has been applied yet.
+func SchemaVersion(db models.XODB) (int, error) {
+	ms, err := AppliedMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+
+	var version int
+	for _, v := range ms {
+		if v.Version > version {
+			version = v.Version
+		}
+	}
+	return version, nil
+}
+
+// PendingMigrations returns migrations that are not applied to the database yet
+func PendingMigrations(db models.XODB) ([]Migration, error) {
+	version, err := SchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if version > LatestSchemaVersion() {
+		return nil, fmt.Errorf("database schema version %d is newer than supported version %d. please upgrade kkzm",
+			version, LatestSchemaVersion())
+	}
+
+	ret := []Migration{}
+	for _, v := range migrations {
+		if v.Version > version {
+			ret = append(ret, v)
+		}
+	}
+	return ret, nil
+}
+
+// Migrate applies all pending migrations in order within a transaction.
+// It refuses to run against a database that is newer than this binary.
+// Applied migrations are returned.
+func Migrate(db *sql.DB) ([]Migration, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := migrate(tx)
+	if err != nil {
+		if e := tx.Rollback(); e != nil {
+			return nil, fmt.Errorf("failed to rollback: %v (original error: %v)", e, err)
+		}
+		return nil, err
+	}
+
+	return applied, tx.Commit()
+}
+
+func migrate(tx *sql.Tx) ([]Migration, error) {
+	ms, err := PendingMigrations(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ms) == 0 {
+		return ms, nil
+	}
+
+	err = models.CreateSchemaMigrationTable(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema_migration table: %v", err)
+	}
+
+	now := time.Now().UTC()
+	for _, m := range ms {
+		err = m.Up(tx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply migration %d (%s): %v", m.Version, m.Desc, err)
+		}
+
+		err = models.InsertSchemaMigration(tx, m.Version, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record migration %d: %v", m.Version, err)
+		}
+	}
+
+	return ms, nil
+}
@@ -0,0 +1,105 @@
+package repo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pankona/kokizami/cmd/kkzm/repo"
+	"github.com/pankona/kokizami/cmd/kkzm/repo/repotest"
+	"github.com/pankona/kokizami/models"
+)
+
+func versions(ms []repo.Migration) []int {
+	ret := []int{}
+	for _, v := range ms {
+		ret = append(ret, v.Version)
+	}
+	return ret
+}
+
+func TestMigrate(t *testing.T) {
+	latest := repo.LatestSchemaVersion()
+
+	// fresh database is migrated to the latest version
+	db := repotest.OpenDB(t)
+	applied, err := repo.Migrate(db)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(applied) != latest || applied[len(applied)-1].Version != latest {
+		t.Errorf("unexpected result: [got] %v [want] 1..%d", versions(applied), latest)
+	}
+	version, err := repo.SchemaVersion(db)
+	if err != nil || version != latest {
+		t.Errorf("unexpected result: [got] %v, %v [want] %v, nil", version, err, latest)
+	}
+
+	// migrating twice is no-op
+	applied, err = repo.Migrate(db)
+	if err != nil || len(applied) != 0 {
+		t.Errorf("unexpected result: [got] %v, %v [want] [], nil", versions(applied), err)
+	}
+	ms, err := repo.AppliedMigrations(db)
+	if err != nil || len(ms) != latest {
+		t.Errorf("unexpected result: [got] %v, %v [want] %d migrations", len(ms), err, latest)
+	}
+}
+
+func TestMigrateFromV1(t *testing.T) {
+	db := repotest.OpenDB(t)
+
+	// a database created by the binary of version 1
+	err := repo.CreateTables(db)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = models.CreateSchemaMigrationTable(db)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = models.InsertSchemaMigration(db, 1, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	applied, err := repo.Migrate(db)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(applied) == 0 || applied[0].Version != 2 {
+		t.Errorf("unexpected result: [got] %v [want] from 2", versions(applied))
+	}
+
+	// segment table is available
+	_, err = models.AllSegments(db)
+	if err != nil {
+		t.Errorf("unexpected result: [got] %v [want] nil", err)
+	}
+	version, err := repo.SchemaVersion(db)
+	if err != nil || version != repo.LatestSchemaVersion() {
+		t.Errorf("unexpected result: [got] %v, %v [want] %v, nil", version, err, repo.LatestSchemaVersion())
+	}
+}
+
+func TestMigrateNewerSchema(t *testing.T) {
+	db := repotest.OpenDB(t)
+	_, err := repo.Migrate(db)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	newer := repo.LatestSchemaVersion() + 1
+	err = models.InsertSchemaMigration(db, newer, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	applied, err := repo.Migrate(db)
+	if err == nil {
+		t.Errorf("unexpected result: [got] %v, nil [want] error", versions(applied))
+	}
+	_, err = repo.PendingMigrations(db)
+	if err == nil {
+		t.Errorf("unexpected result: [got] nil [want] error")
+	}
+}
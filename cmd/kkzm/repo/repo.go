@@ -1,11 +1,21 @@
 package repo
 
 import (
+	"database/sql"
 	"fmt"
 
+	"github.com/pankona/kokizami"
 	"github.com/pankona/kokizami/models"
 )
 
+// notFound converts sql.ErrNoRows of a row of specified ID to kokizami.ErrNotFound
+func notFound(err error, table string, id int) error {
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%s [%d] %w", table, id, kokizami.ErrNotFound)
+	}
+	return err
+}
+
 // CreateTables creates tables that are needed to implement
 // each repositories
 func CreateTables(db models.XODB) error {
@@ -0,0 +1,52 @@
+// Package repotest provides temporary sqlite databases for tests
+package repotest
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pankona/kokizami"
+	"github.com/pankona/kokizami/cmd/kkzm/repo"
+)
+
+// OpenDB opens an empty sqlite database in a temporary directory.
+// it is closed and removed when the test finishes.
+func OpenDB(t testing.TB) *sql.DB {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "kokizami")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "db"))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+	return db
+}
+
+// OpenTemp returns a Kokizami in UTC on a migrated temporary database
+func OpenTemp(t testing.TB) *kokizami.Kokizami {
+	t.Helper()
+
+	db := OpenDB(t)
+	_, err := repo.Migrate(db)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	return &kokizami.Kokizami{
+		KizamiRepo:  repo.NewKizamiRepo(db),
+		TagRepo:     repo.NewTagRepo(db),
+		SummaryRepo: repo.NewSummaryRepo(db),
+		Location:    time.UTC,
+	}
+}
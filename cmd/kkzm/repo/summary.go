@@ -1,7 +1,8 @@
 package repo
 
 import (
-	"database/sql"
+	"fmt"
+	"time"
 
 	"github.com/pankona/kokizami"
 	"github.com/pankona/kokizami/models"
@@ -9,17 +10,28 @@ import (
 
 // SummaryRepo is an implementation of SummaryRepository
 type SummaryRepo struct {
-	db *sql.DB
+	db models.XODB
 }
 
 // NewSummaryRepo returns a struct that implements SummaryRepository with sqlite3
-func NewSummaryRepo(db *sql.DB) *SummaryRepo {
+func NewSummaryRepo(db models.XODB) *SummaryRepo {
 	return &SummaryRepo{db: db}
 }
 
-// ElapsedOfMonthByDesc returns an array of Elapsed time to summarize them by desc
-func (r *SummaryRepo) ElapsedOfMonthByDesc(yyyymm string) ([]*kokizami.Elapsed, error) {
-	ms, err := models.ElapsedOfMonthByDesc(r.db, yyyymm)
+func toElapsedQuery(q *kokizami.SummaryQuery) *models.ElapsedQuery {
+	return &models.ElapsedQuery{
+		From:    q.From,
+		To:      q.To,
+		Prorate: q.Prorate,
+
+		IncludeRunning: q.IncludeRunning,
+		Now:            q.Now,
+	}
+}
+
+// ElapsedByDesc returns an array of Elapsed time in specified query to summarize them by desc
+func (r *SummaryRepo) ElapsedByDesc(q *kokizami.SummaryQuery) ([]*kokizami.Elapsed, error) {
+	ms, err := models.ElapsedByDesc(r.db, toElapsedQuery(q))
 	if err != nil {
 		return nil, err
 	}
@@ -30,6 +42,7 @@ func (r *SummaryRepo) ElapsedOfMonthByDesc(yyyymm string) ([]*kokizami.Elapsed,
 		es[i].Desc = ms[i].Desc
 		es[i].Count = ms[i].Count
 		es[i].Elapsed = ms[i].Elapsed
+		es[i].Running = ms[i].Running
 	}
 
 	ret := make([]*kokizami.Elapsed, len(es))
@@ -40,9 +53,9 @@ func (r *SummaryRepo) ElapsedOfMonthByDesc(yyyymm string) ([]*kokizami.Elapsed,
 	return ret, nil
 }
 
-// ElapsedOfMonthByTag returns an array of Elapsed time to summarize them by tag
-func (r *SummaryRepo) ElapsedOfMonthByTag(yyyymm string) ([]*kokizami.Elapsed, error) {
-	ms, err := models.ElapsedOfMonthByTag(r.db, yyyymm)
+// ElapsedByTag returns an array of Elapsed time in specified query to summarize them by tag
+func (r *SummaryRepo) ElapsedByTag(q *kokizami.SummaryQuery) ([]*kokizami.Elapsed, error) {
+	ms, err := models.ElapsedByTag(r.db, toElapsedQuery(q))
 	if err != nil {
 		return nil, err
 	}
@@ -53,6 +66,7 @@ func (r *SummaryRepo) ElapsedOfMonthByTag(yyyymm string) ([]*kokizami.Elapsed, e
 		es[i].Desc = ms[i].Desc
 		es[i].Count = ms[i].Count
 		es[i].Elapsed = ms[i].Elapsed
+		es[i].Running = ms[i].Running
 	}
 
 	ret := make([]*kokizami.Elapsed, len(es))
@@ -62,3 +76,54 @@ func (r *SummaryRepo) ElapsedOfMonthByTag(yyyymm string) ([]*kokizami.Elapsed, e
 
 	return ret, nil
 }
+
+// ElapsedOfDays returns total elapsed time in each of specified days of the query
+func (r *SummaryRepo) ElapsedOfDays(q *kokizami.SummaryQuery, days []time.Time) ([]*kokizami.DailyElapsed, error) {
+	ms, err := models.ElapsedOfDays(r.db, toElapsedQuery(q), days)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*kokizami.DailyElapsed, len(ms))
+	for i := range ms {
+		ret[i] = &kokizami.DailyElapsed{
+			Date:    days[i],
+			Elapsed: ms[i].Elapsed,
+			Running: ms[i].Running,
+		}
+	}
+
+	return ret, nil
+}
+
+// monthQuery returns a query of Kizamis started in specified month in UTC
+// as summaries of ElapsedOfMonthByDesc and ElapsedOfMonthByTag did
+func monthQuery(yyyymm string) (*kokizami.SummaryQuery, error) {
+	from, err := time.Parse("2006-01", yyyymm)
+	if err != nil {
+		return nil, fmt.Errorf("%v (should be yyyy-mm): %w", err, kokizami.ErrInvalid)
+	}
+	return &kokizami.SummaryQuery{From: from, To: from.AddDate(0, 1, 0)}, nil
+}
+
+// ElapsedOfMonthByDesc returns an array of Elapsed time to summarize them by desc
+//
+// Deprecated: use ElapsedByDesc with a range of the month.
+func (r *SummaryRepo) ElapsedOfMonthByDesc(yyyymm string) ([]*kokizami.Elapsed, error) {
+	q, err := monthQuery(yyyymm)
+	if err != nil {
+		return nil, err
+	}
+	return r.ElapsedByDesc(q)
+}
+
+// ElapsedOfMonthByTag returns an array of Elapsed time to summarize them by tag
+//
+// Deprecated: use ElapsedByTag with a range of the month.
+func (r *SummaryRepo) ElapsedOfMonthByTag(yyyymm string) ([]*kokizami.Elapsed, error) {
+	q, err := monthQuery(yyyymm)
+	if err != nil {
+		return nil, err
+	}
+	return r.ElapsedByTag(q)
+}
@@ -0,0 +1,250 @@
+package repo_test
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pankona/kokizami"
+	"github.com/pankona/kokizami/cmd/kkzm/repo/repotest"
+)
+
+// at returns time of specified day of March 2024 in UTC
+func at(day, hour int) time.Time {
+	return time.Date(2024, 3, day, hour, 0, 0, 0, time.UTC)
+}
+
+// setupSummary returns a Kokizami with kizamis around 2024-03-01 in UTC
+func setupSummary(t *testing.T) *kokizami.Kokizami {
+	t.Helper()
+	k := repotest.OpenTemp(t)
+
+	on := time.Unix(0, 0).UTC()
+	kizamis := []struct {
+		desc      string
+		startedAt time.Time
+		stoppedAt time.Time
+		segments  [][2]time.Time
+	}{
+		// across the beginning of the day
+		{desc: "a1 #a", startedAt: at(0, 23), stoppedAt: at(1, 1)},
+		// across the end of the day
+		{desc: "a2 #a", startedAt: at(1, 23), stoppedAt: at(2, 2)},
+		// worked 1h, paused 1h and worked 1h
+		{desc: "b #b", startedAt: at(1, 9), stoppedAt: at(1, 12), segments: [][2]time.Time{{at(1, 9), at(1, 10)}, {at(1, 11), at(1, 12)}}},
+		// running
+		{desc: "c1 #c", startedAt: at(1, 20), stoppedAt: on},
+		// worked 1h, paused 1h and running again
+		{desc: "c2 #c", startedAt: at(1, 13), stoppedAt: on, segments: [][2]time.Time{{at(1, 13), at(1, 14)}, {at(1, 15), on}}},
+		// worked 1h and paused
+		{desc: "c3 #c", startedAt: at(1, 16), stoppedAt: on, segments: [][2]time.Time{{at(1, 16), at(1, 17)}}},
+	}
+	for _, v := range kizamis {
+		ki, err := k.KizamiRepo.InsertWithTimes(v.desc, v.startedAt, v.stoppedAt)
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+		for _, s := range v.segments {
+			err := k.KizamiRepo.InsertSegment(&kokizami.Segment{KizamiID: ki.ID, StartedAt: s[0], StoppedAt: s[1]})
+			if err != nil {
+				t.Fatalf("unexpected result: [got] %v [want] nil", err)
+			}
+		}
+		if err := k.TagByDesc(ki.ID, ki.Desc); err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+	}
+	return k
+}
+
+// elapsedByDesc returns elapsed time of the query sorted by desc
+func elapsedByDesc(t *testing.T, k *kokizami.Kokizami, q *kokizami.SummaryQuery) []*kokizami.Elapsed {
+	t.Helper()
+	es, err := k.SummaryRepo.ElapsedByDesc(q)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	sort.Slice(es, func(i, j int) bool { return es[i].Desc < es[j].Desc })
+	return es
+}
+
+func TestElapsedProrate(t *testing.T) {
+	k := setupSummary(t)
+
+	tcs := []struct {
+		inProrate bool
+		want      []*kokizami.Elapsed
+	}{
+		{
+			// kizamis started in the day are counted entirely
+			inProrate: false,
+			want: []*kokizami.Elapsed{
+				{Tag: "#a", Desc: "a2 #a", Count: 1, Elapsed: 3 * time.Hour},
+				{Tag: "#b", Desc: "b #b", Count: 1, Elapsed: 2 * time.Hour},
+			},
+		},
+		{
+			// kizamis are clipped to the day
+			inProrate: true,
+			want: []*kokizami.Elapsed{
+				{Tag: "#a", Desc: "a1 #a", Count: 1, Elapsed: 1 * time.Hour},
+				{Tag: "#a", Desc: "a2 #a", Count: 1, Elapsed: 1 * time.Hour},
+				{Tag: "#b", Desc: "b #b", Count: 1, Elapsed: 2 * time.Hour},
+			},
+		},
+	}
+
+	for i, tc := range tcs {
+		got := elapsedByDesc(t, k, &kokizami.SummaryQuery{From: at(1, 0), To: at(2, 0), Prorate: tc.inProrate})
+		if diff := cmp.Diff(got, tc.want); diff != "" {
+			t.Errorf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+
+	// segments are clipped, and the pause between them is not counted
+	got := elapsedByDesc(t, k, &kokizami.SummaryQuery{
+		From:    at(1, 9).Add(30 * time.Minute),
+		To:      at(1, 11).Add(30 * time.Minute),
+		Prorate: true,
+	})
+	want := []*kokizami.Elapsed{{Tag: "#b", Desc: "b #b", Count: 1, Elapsed: 1 * time.Hour}}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("unexpected result: (-got +want) %s", diff)
+	}
+}
+
+func TestElapsedRunning(t *testing.T) {
+	k := setupSummary(t)
+
+	tcs := []struct {
+		inProrate bool
+		inNow     time.Time
+		want      []*kokizami.Elapsed
+	}{
+		{
+			// on-going kizamis are counted until now
+			inProrate: false,
+			inNow:     at(1, 22),
+			want: []*kokizami.Elapsed{
+				{Tag: "#a", Desc: "a2 #a", Count: 1, Elapsed: 3 * time.Hour},
+				{Tag: "#b", Desc: "b #b", Count: 1, Elapsed: 2 * time.Hour},
+				{Tag: "#c", Desc: "c1 #c", Count: 1, Elapsed: 2 * time.Hour, Running: true},
+				{Tag: "#c", Desc: "c2 #c", Count: 1, Elapsed: 8 * time.Hour, Running: true},
+				// paused kizami is not running
+				{Tag: "#c", Desc: "c3 #c", Count: 1, Elapsed: 1 * time.Hour},
+			},
+		},
+		{
+			// on-going kizamis are clipped to the day
+			inProrate: true,
+			inNow:     at(2, 3),
+			want: []*kokizami.Elapsed{
+				{Tag: "#a", Desc: "a1 #a", Count: 1, Elapsed: 1 * time.Hour},
+				{Tag: "#a", Desc: "a2 #a", Count: 1, Elapsed: 1 * time.Hour},
+				{Tag: "#b", Desc: "b #b", Count: 1, Elapsed: 2 * time.Hour},
+				{Tag: "#c", Desc: "c1 #c", Count: 1, Elapsed: 4 * time.Hour, Running: true},
+				{Tag: "#c", Desc: "c2 #c", Count: 1, Elapsed: 10 * time.Hour, Running: true},
+				{Tag: "#c", Desc: "c3 #c", Count: 1, Elapsed: 1 * time.Hour},
+			},
+		},
+	}
+
+	for i, tc := range tcs {
+		got := elapsedByDesc(t, k, &kokizami.SummaryQuery{
+			From:           at(1, 0),
+			To:             at(2, 0),
+			Prorate:        tc.inProrate,
+			IncludeRunning: true,
+			Now:            tc.inNow,
+		})
+		if diff := cmp.Diff(got, tc.want); diff != "" {
+			t.Errorf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+
+	// a tag is running if any of its kizamis is running
+	es, err := k.SummaryRepo.ElapsedByTag(&kokizami.SummaryQuery{
+		From:           at(1, 0),
+		To:             at(2, 0),
+		IncludeRunning: true,
+		Now:            at(1, 22),
+	})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	var got *kokizami.Elapsed
+	for _, v := range es {
+		if v.Tag == "#c" {
+			got = v
+		}
+	}
+	if got == nil || got.Count != 3 || got.Elapsed != 11*time.Hour || !got.Running {
+		t.Errorf("unexpected result: [got] %+v [want] 3 running kizamis of 11h", got)
+	}
+}
+
+func TestElapsedOfDays(t *testing.T) {
+	k := setupSummary(t)
+
+	// kizamis of the same desc are counted separately,
+	// and a kizami of many tags is counted once
+	for _, v := range []struct {
+		desc      string
+		startedAt time.Time
+		stoppedAt time.Time
+	}{
+		{desc: "same #x", startedAt: at(2, 9), stoppedAt: at(2, 12)},
+		{desc: "same #y", startedAt: at(2, 13), stoppedAt: at(2, 15)},
+		{desc: "many #x #y", startedAt: at(2, 16), stoppedAt: at(2, 17)},
+	} {
+		ki, err := k.Add(v.desc, v.startedAt, v.stoppedAt)
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+		if err := k.TagByDesc(ki.ID, ki.Desc); err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+	}
+
+	days := []time.Time{at(1, 0), at(2, 0), at(3, 0)}
+	tcs := []struct {
+		inQuery *kokizami.SummaryQuery
+		want    []*kokizami.DailyElapsed
+	}{
+		{
+			inQuery: &kokizami.SummaryQuery{From: at(1, 0), To: at(4, 0)},
+			want: []*kokizami.DailyElapsed{
+				{Date: days[0], Elapsed: 5 * time.Hour},
+				{Date: days[1], Elapsed: 6 * time.Hour},
+				{Date: days[2]},
+			},
+		},
+		{
+			inQuery: &kokizami.SummaryQuery{From: at(1, 0), To: at(4, 0), Prorate: true, IncludeRunning: true, Now: at(2, 20)},
+			want: []*kokizami.DailyElapsed{
+				{Date: days[0], Elapsed: 19 * time.Hour, Running: true},
+				{Date: days[1], Elapsed: 48 * time.Hour, Running: true},
+				{Date: days[2]},
+			},
+		},
+	}
+
+	for i, tc := range tcs {
+		got, err := k.SummaryRepo.ElapsedOfDays(tc.inQuery, days)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if diff := cmp.Diff(got, tc.want); diff != "" {
+			t.Errorf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+
+	ret, err := k.DailySummaryBetween(at(2, 0), at(3, 0), kokizami.WithProrate(false))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(ret) != 1 || ret[0].Elapsed != 6*time.Hour {
+		t.Errorf("unexpected result: [got] %+v [want] 6h of a day", ret)
+	}
+}
@@ -1,19 +1,17 @@
 package repo
 
 import (
-	"database/sql"
-
 	"github.com/pankona/kokizami"
 	"github.com/pankona/kokizami/models"
 )
 
 // TagRepo is an implementation of TagRepository
 type TagRepo struct {
-	db *sql.DB
+	db models.XODB
 }
 
 // NewTagRepo returns an implementation of TagRepository with sqlite3
-func NewTagRepo(db *sql.DB) *TagRepo {
+func NewTagRepo(db models.XODB) *TagRepo {
 	return &TagRepo{db: db}
 }
 
@@ -28,7 +26,7 @@ func toTag(m *models.Tag) *kokizami.Tag {
 func (t *TagRepo) FindByID(id int) (*kokizami.Tag, error) {
 	tag, err := models.TagByID(t.db, id)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err, "tag", id)
 	}
 
 	ret := &kokizami.Tag{
@@ -116,7 +114,7 @@ func (t *TagRepo) Insert(labels []string) error {
 func (t *TagRepo) Delete(id int) error {
 	m, err := models.TagByID(t.db, id)
 	if err != nil {
-		return err
+		return notFound(err, "tag", id)
 	}
 
 	return m.Delete(t.db)
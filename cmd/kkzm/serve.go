@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pankona/kokizami/client"
+	"github.com/pankona/kokizami/server"
+	"github.com/urfave/cli"
+)
+
+// defaultListen is the address that serve listens by default.
+// it is loopback only since the API has no authentication.
+const defaultListen = "127.0.0.1:7777"
+
+func serveFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "listen",
+			Value: defaultListen,
+			Usage: "specify address to listen",
+		},
+		cli.StringSliceFlag{
+			Name:  "allow-origin",
+			Usage: "specify origin allowed to access the API from browsers (e.g. chrome-extension://<id>). can be specified multiple times",
+		},
+		cli.StringSliceFlag{
+			Name:  "allow-host",
+			Usage: "specify name of this machine allowed in Host header besides loopback names and IP addresses. can be specified multiple times",
+		},
+	}
+}
+
+func serveCommand() cli.Command {
+	return cli.Command{
+		Name:   "serve",
+		Usage:  "serve JSON REST API of tasks over HTTP",
+		Action: CmdServe,
+		Flags:  serveFlags(),
+	}
+}
+
+// CmdServe serves the API until interrupted
+// kokizami serve --listen 127.0.0.1:7777
+func CmdServe(c *cli.Context) error {
+	return listenAndServe(c, nil)
+}
+
+// listenAndServe serves the API, and dashboard on "/" if it is not nil, until interrupted
+func listenAndServe(c *cli.Context, dashboard http.Handler) error {
+	// requests are served concurrently. serialize accesses to sqlite
+	// so that writes do not fail with "database is locked".
+	database(c).SetMaxOpenConns(1)
+
+	s := server.New(kkzm(c), Version)
+	s.AllowOrigins = c.StringSlice("allow-origin")
+	s.AllowHosts = c.StringSlice("allow-host")
+
+	// repositories are served for remote stores of kokizami/client.
+	// they are not allowed from browsers since CORS is not applied.
+	h := client.NewHandler(kkzm(c))
+	h.AllowHosts = s.AllowHosts
+	mux := http.NewServeMux()
+	mux.Handle(client.Prefix+"/", h)
+	if dashboard != nil {
+		mux.Handle(server.Prefix+"/", s)
+		mux.Handle("/", dashboard)
+	} else {
+		mux.Handle("/", s)
+	}
+
+	// event streams never finish by themselves. they are closed
+	// by canceling the context of requests on shutdown.
+	ctx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+
+	srv := &http.Server{
+		Addr:              c.String("listen"),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	if dashboard != nil {
+		fmt.Fprintf(os.Stderr, "serving dashboard on http://%s/\n", srv.Addr)
+	} else {
+		fmt.Fprintf(os.Stderr, "serving API on http://%s%s\n", srv.Addr, server.Prefix)
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("failed to serve: %v", err)
+	case <-sig:
+	}
+
+	cancelRequests()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
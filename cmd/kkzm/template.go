@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pankona/kokizami"
+	"github.com/urfave/cli"
+)
+
+// templateExt is extension of named templates in templates directory
+const templateExt = ".tmpl"
+
+func templateFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "template",
+			Usage: "specify Go template to show each item (e.g. '{{.ID}} {{.Desc}} {{duration .Elapsed}}')",
+		},
+		cli.StringFlag{
+			Name:  "template-name",
+			Usage: "specify name of template in ~/.config/kokizami/templates (e.g. daily for daily.tmpl)",
+		},
+	}
+}
+
+// kizamiData is data passed to templates of list
+type kizamiData struct {
+	ID        int
+	Desc      string
+	Tags      []string
+	StartedAt time.Time
+	// StoppedAt is zero if the kizami is running or paused
+	StoppedAt time.Time
+	Elapsed   time.Duration
+	Running   bool
+	Paused    bool
+}
+
+func newKizamiData(k *kokizami.Kizami, tags []*kokizami.Tag) *kizamiData {
+	d := &kizamiData{
+		ID:        k.ID,
+		Desc:      k.Desc,
+		Tags:      []string{},
+		StartedAt: k.StartedAt,
+		Elapsed:   round(k.Elapsed(), time.Second),
+		Running:   k.IsRunning(),
+		Paused:    k.IsPaused(),
+	}
+	for _, v := range tags {
+		d.Tags = append(d.Tags, v.Label)
+	}
+	if !d.Running && !d.Paused {
+		d.StoppedAt = k.StoppedAt
+	}
+	return d
+}
+
+// elapsedData is data passed to templates of summary.
+// Descs holds totals of each desc in the tag.
+type elapsedData struct {
+	Tag     string
+	Desc    string
+	Count   int
+	Elapsed time.Duration
+	Running bool
+	Descs   []*elapsedData
+}
+
+func newElapsedData(tags, descs []*kokizami.Elapsed) []*elapsedData {
+	ret := []*elapsedData{}
+	for _, t := range tags {
+		d := &elapsedData{
+			Tag:     t.Tag,
+			Count:   t.Count,
+			Elapsed: t.Elapsed,
+			Running: t.Running,
+			Descs:   []*elapsedData{},
+		}
+		for _, v := range descs {
+			if v.Tag == t.Tag {
+				d.Descs = append(d.Descs, &elapsedData{
+					Tag:     v.Tag,
+					Desc:    v.Desc,
+					Count:   v.Count,
+					Elapsed: v.Elapsed,
+					Running: v.Running,
+				})
+			}
+		}
+		sort.SliceStable(d.Descs, func(i, j int) bool {
+			return d.Descs[i].Desc < d.Descs[j].Desc
+		})
+		ret = append(ret, d)
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Tag < ret[j].Tag
+	})
+	return ret
+}
+
+// reportData is data passed to "header" and "footer" templates.
+// Items holds all items. From, To and Label are set for summary.
+type reportData struct {
+	Label string
+	From  time.Time
+	To    time.Time
+	Items interface{}
+}
+
+// templateFuncs returns helper functions available in templates
+func templateFuncs(loc *time.Location) template.FuncMap {
+	return template.FuncMap{
+		// duration formats duration rounded to seconds (e.g. 1h30m0s)
+		"duration": func(d time.Duration) string {
+			return round(d, time.Second).String()
+		},
+		// hours formats duration in hours (e.g. 1.50)
+		"hours": func(d time.Duration) string {
+			return fmt.Sprintf("%.2f", d.Hours())
+		},
+		// localtime formats time in local timezone.
+		// layout is optional and "2006-01-02 15:04:05" by default.
+		"localtime": func(t time.Time, layout ...string) string {
+			if t.IsZero() {
+				return ""
+			}
+			l := "2006-01-02 15:04:05"
+			if len(layout) != 0 {
+				l = layout[0]
+			}
+			return t.In(loc).Format(l)
+		},
+		// join joins strings with sep (e.g. {{.Tags | join ", "}})
+		"join": func(sep string, ss []string) string {
+			return strings.Join(ss, sep)
+		},
+		// sum returns total elapsed time of items
+		"sum": sumElapsed,
+	}
+}
+
+func sumElapsed(items interface{}) (time.Duration, error) {
+	var sum time.Duration
+	switch vs := items.(type) {
+	case []*kizamiData:
+		for _, v := range vs {
+			sum += v.Elapsed
+		}
+	case []*elapsedData:
+		for _, v := range vs {
+			sum += v.Elapsed
+		}
+	case []time.Duration:
+		for _, v := range vs {
+			sum += v
+		}
+	default:
+		return 0, fmt.Errorf("sum: unsupported type %T", items)
+	}
+	return sum, nil
+}
+
+// templateFromFlags returns template specified by --template or --template-name.
+// nil is returned if neither is specified.
+func templateFromFlags(c *cli.Context, loc *time.Location) (*template.Template, error) {
+	var (
+		name = "inline"
+		text = c.String("template")
+	)
+
+	switch {
+	case c.IsSet("template") && c.IsSet("template-name"):
+		return nil, fmt.Errorf("--template and --template-name can not be specified at once")
+	case c.IsSet("template-name"):
+		name = c.String("template-name")
+		b, err := readNamedTemplate(configDir(c), name)
+		if err != nil {
+			return nil, err
+		}
+		// each item is followed by a newline anyway
+		text = strings.TrimSuffix(string(b), "\n")
+	case !c.IsSet("template"):
+		return nil, nil
+	}
+
+	t, err := template.New(name).Funcs(templateFuncs(loc)).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %v", err)
+	}
+	return t, nil
+}
+
+// outputTemplate returns template to show items if specified.
+// templates can not be used with formats other than table.
+func outputTemplate(c *cli.Context, format string, loc *time.Location) (*template.Template, error) {
+	t, err := templateFromFlags(c, loc)
+	if err != nil {
+		return nil, err
+	}
+	if t != nil && format != formatTable {
+		return nil, fmt.Errorf("template can not be used with format %s", format)
+	}
+	return t, nil
+}
+
+func readNamedTemplate(dir, name string) ([]byte, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("invalid template name: %s", name)
+	}
+
+	path := filepath.Join(dir, "templates", name)
+	if filepath.Ext(name) != templateExt {
+		path += templateExt
+	}
+
+	b, err := ioutil.ReadFile(path) // #nosec
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("template %s is not found on %s", name, path)
+		}
+		return nil, fmt.Errorf("failed to read template %s: %v", name, err)
+	}
+	return b, nil
+}
+
+func configDir(c *cli.Context) string {
+	return c.App.Metadata["configDir"].(string)
+}
+
+// executeTemplate executes template for each item.
+// "header" and "footer" templates are executed with all items
+// before and after items if they are defined.
+func executeTemplate(w io.Writer, t *template.Template, report *reportData, items []interface{}) error {
+	execute := func(name string, data interface{}) error {
+		err := t.ExecuteTemplate(w, name, data)
+		if err != nil {
+			return fmt.Errorf("failed to execute template: %v", err)
+		}
+		_, err = fmt.Fprintln(w)
+		return err
+	}
+
+	if t.Lookup("header") != nil {
+		err := execute("header", report)
+		if err != nil {
+			return err
+		}
+	}
+	for _, v := range items {
+		err := execute(t.Name(), v)
+		if err != nil {
+			return err
+		}
+	}
+	if t.Lookup("footer") != nil {
+		return execute("footer", report)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pankona/kokizami"
+)
+
+func TestExecuteTemplate(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	startedAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	ds := []*kizamiData{
+		newKizamiData(&kokizami.Kizami{
+			ID:        1,
+			Desc:      "write docs #doc #en",
+			StartedAt: startedAt,
+			StoppedAt: startedAt.Add(90 * time.Minute),
+		}, []*kokizami.Tag{{ID: 1, Label: "#doc"}, {ID: 2, Label: "#en"}}),
+		newKizamiData(&kokizami.Kizami{
+			ID:        2,
+			Desc:      "review",
+			StartedAt: startedAt.Add(2 * time.Hour),
+			StoppedAt: startedAt.Add(150 * time.Minute),
+		}, nil),
+	}
+	items := make([]interface{}, len(ds))
+	for i, v := range ds {
+		items[i] = v
+	}
+
+	tcs := []struct {
+		inText  string
+		want    string
+		wantErr bool
+	}{
+		{
+			inText: `{{.ID}} {{.Desc}} {{duration .Elapsed}}`,
+			want:   "1 write docs #doc #en 1h30m0s\n2 review 30m0s\n",
+		},
+		{
+			inText: `{{localtime .StartedAt "15:04"}}-{{localtime .StoppedAt "15:04"}} [{{.Tags | join ","}}]`,
+			want:   "09:00-10:30 [#doc,#en]\n11:00-11:30 []\n",
+		},
+		{
+			inText: `{{define "header"}}report{{end}}{{define "footer"}}total {{sum .Items}} ({{sum .Items | hours}}h){{end}}` +
+				`{{.ID}}`,
+			want: "report\n1\n2\ntotal 2h0m0s (2.00h)\n",
+		},
+		{
+			inText:  `{{.NoSuchField}}`,
+			wantErr: true,
+		},
+	}
+
+	for i, tc := range tcs {
+		tmpl, err := template.New("test").Funcs(templateFuncs(loc)).Parse(tc.inText)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+
+		buf := &bytes.Buffer{}
+		err = executeTemplate(buf, tmpl, &reportData{Items: ds}, items)
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("[No.%d] unexpected result: [got] nil [want] some error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if diff := cmp.Diff(buf.String(), tc.want); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+}
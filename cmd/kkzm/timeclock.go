@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pankona/kokizami"
+	"github.com/urfave/cli"
+)
+
+// timeclockLayout is layout of times in timeclock files
+const timeclockLayout = "2006/01/02 15:04:05"
+
+// defaultAccount is account of kizamis without tags in timeclock files
+const defaultAccount = "untagged"
+
+func accountFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "account",
+			Usage: "specify account of a tag for timeclock (e.g. --account dev=work:development). can be specified multiple times",
+		},
+		cli.StringFlag{
+			Name:  "default-account",
+			Value: defaultAccount,
+			Usage: "specify account of tasks without tags for timeclock",
+		},
+	}
+}
+
+// accountsFromFlags returns mapping from tags to accounts specified by --account
+func accountsFromFlags(c *cli.Context) (map[string]string, error) {
+	ret := map[string]string{}
+	for _, v := range c.StringSlice("account") {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[1]) == "" {
+			return nil, fmt.Errorf("invalid --account %s. should be like tag=account", v)
+		}
+		tags := mergeTags([]string{kv[0]})
+		if len(tags) == 0 {
+			return nil, fmt.Errorf("invalid --account %s. should be like tag=account", v)
+		}
+		ret[tags[0]] = strings.TrimSpace(kv[1])
+	}
+	return ret, nil
+}
+
+// timeclockAccount returns account of specified entry.
+// account is derived from the first tag written in desc.
+func timeclockAccount(e *entry, opts *transferOptions) string {
+	tags := mergeTags(kokizami.ExtractTags(e.Desc), e.Tags)
+	if len(tags) == 0 {
+		return opts.defaultAccount()
+	}
+	if v, ok := opts.Accounts[tags[0]]; ok {
+		return v
+	}
+	return strings.TrimPrefix(tags[0], "#")
+}
+
+// timeclockInterval is a worked interval of an entry.
+// StoppedAt is zero if the interval is on-going.
+type timeclockInterval struct {
+	Entry     *entry
+	StartedAt time.Time
+	StoppedAt time.Time
+}
+
+// timeclockIntervals returns worked intervals of entries in order of start.
+// entries that have been paused have an interval per segment.
+// timeclock can not have consecutive clock-ins, so that on-going intervals
+// are stopped at the start of the next interval, and only the last one
+// is kept on-going.
+func timeclockIntervals(es []*entry) []*timeclockInterval {
+	var ret []*timeclockInterval
+	for _, e := range es {
+		if len(e.Segments) == 0 {
+			ret = append(ret, &timeclockInterval{Entry: e, StartedAt: e.StartedAt, StoppedAt: e.StoppedAt})
+			continue
+		}
+		for _, s := range e.Segments {
+			v := &timeclockInterval{Entry: e, StartedAt: s.StartedAt, StoppedAt: s.StoppedAt}
+			if v.StoppedAt.Unix() == 0 {
+				v.StoppedAt = time.Time{}
+			}
+			ret = append(ret, v)
+		}
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].StartedAt.Before(ret[j].StartedAt)
+	})
+
+	for i := 0; i+1 < len(ret); i++ {
+		if ret[i].StoppedAt.IsZero() {
+			ret[i].StoppedAt = ret[i+1].StartedAt
+		}
+	}
+	return ret
+}
+
+// exportTimeclock writes entries as pairs of clock-in and clock-out like:
+//
+//	i 2024/03/01 09:00:00 work:dev  write docs #dev
+//	o 2024/03/01 10:30:00
+//
+// a pair is written per worked interval of paused entries, and
+// only the last interval is written as a clock-in without clock-out if on-going.
+func exportTimeclock(w io.Writer, es []*entry, opts *transferOptions) error {
+	for _, v := range timeclockIntervals(es) {
+		_, err := fmt.Fprintf(w, "i %s %s  %s\n",
+			v.StartedAt.In(opts.Location).Format(timeclockLayout), timeclockAccount(v.Entry, opts), v.Entry.Desc)
+		if err != nil {
+			return err
+		}
+
+		if v.StoppedAt.IsZero() {
+			continue
+		}
+		_, err = fmt.Fprintf(w, "o %s\n", v.StoppedAt.In(opts.Location).Format(timeclockLayout))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// timeclockLine matches clock-in and clock-out lines.
+// account and description are separated by two or more spaces or a tab.
+var timeclockLine = regexp.MustCompile(`^([ioO])\s+(\d{4}[/-]\d{2}[/-]\d{2})\s+(\d{2}:\d{2}(?::\d{2})?)(?:\s+(.*?))?\s*$`)
+
+var timeclockSeparator = regexp.MustCompile(`\t|\s{2,}`)
+
+// importTimeclock reads pairs of clock-in and clock-out of timeclock files.
+// account of clock-in is mapped to a tag, and its description becomes desc.
+// clock-in without clock-out at the end is imported as an on-going entry.
+func importTimeclock(r io.Reader, opts *transferOptions) ([]*entry, error) {
+	tags := map[string]string{}
+	for k, v := range opts.Accounts {
+		tags[v] = k
+	}
+
+	var (
+		es   []*entry
+		errs rowErrors
+		line int
+		in   *entry
+	)
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line++
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.ContainsAny(text[:1], ";#*") {
+			continue
+		}
+
+		m := timeclockLine.FindStringSubmatch(text)
+		if m == nil {
+			errs = append(errs, &rowError{Line: line, Err: fmt.Errorf("should be like i yyyy/mm/dd hh:mm:ss account  description: %s", text)})
+			continue
+		}
+
+		t, err := parseTimeclockTime(m[2]+" "+m[3], opts.Location)
+		if err != nil {
+			errs = append(errs, &rowError{Line: line, Err: err})
+			continue
+		}
+
+		if m[1] == "i" {
+			if in != nil {
+				errs = append(errs, &rowError{Line: in.Line, Err: fmt.Errorf("clock-in without clock-out")})
+			}
+			in = &entry{
+				StartedAt: t,
+				Line:      line,
+			}
+			setTimeclockAccount(in, m[4], tags, opts.defaultAccount())
+			continue
+		}
+
+		if in == nil {
+			errs = append(errs, &rowError{Line: line, Err: fmt.Errorf("clock-out without clock-in")})
+			continue
+		}
+		in.StoppedAt = t
+		es = append(es, in)
+		in = nil
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	if in != nil {
+		es = append(es, in)
+	}
+
+	if len(errs) != 0 {
+		return es, errs
+	}
+	return es, nil
+}
+
+// setTimeclockAccount sets desc and tags of entry from "account  description"
+func setTimeclockAccount(e *entry, s string, tags map[string]string, defaultAccount string) {
+	ss := timeclockSeparator.Split(s, 2)
+	account := strings.TrimSpace(ss[0])
+	desc := ""
+	if len(ss) == 2 {
+		desc = strings.TrimSpace(ss[1])
+	}
+
+	switch {
+	case account == "" || account == defaultAccount:
+	case tags[account] != "":
+		e.Tags = []string{tags[account]}
+	case normalizeTag(account) != "":
+		e.Tags = []string{normalizeTag(account)}
+	}
+
+	e.Desc = descWithTags(desc, e.Tags)
+	if e.Desc == "" {
+		e.Desc = account
+	}
+}
+
+func parseTimeclockTime(s string, loc *time.Location) (time.Time, error) {
+	s = strings.Replace(s, "-", "/", 2)
+	for _, layout := range []string{timeclockLayout, "2006/01/02 15:04"} {
+		t, err := time.ParseInLocation(layout, s, loc)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time. should be yyyy/mm/dd hh:mm[:ss]: %s", s)
+}
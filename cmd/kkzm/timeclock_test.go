@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pankona/kokizami"
+	"github.com/urfave/cli"
+)
+
+func TestExportTimeclock(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	startedAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	es := []*entry{
+		{
+			Desc:      "write docs #dev #doc",
+			Tags:      []string{"#doc", "#dev"},
+			StartedAt: startedAt,
+			StoppedAt: startedAt.Add(90 * time.Minute),
+		},
+		{
+			Desc:      "lunch",
+			StartedAt: startedAt.Add(3 * time.Hour),
+			StoppedAt: startedAt.Add(4 * time.Hour),
+		},
+		{
+			Desc:      "review #review",
+			Tags:      []string{"#review"},
+			StartedAt: startedAt.Add(5 * time.Hour),
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	err := exportTimeclock(buf, es, &transferOptions{
+		Location: loc,
+		Accounts: map[string]string{"#dev": "work:development"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	want := "i 2024/03/01 09:00:00 work:development  write docs #dev #doc\n" +
+		"o 2024/03/01 10:30:00\n" +
+		"i 2024/03/01 12:00:00 untagged  lunch\n" +
+		"o 2024/03/01 13:00:00\n" +
+		"i 2024/03/01 14:00:00 review  review #review\n"
+	if diff := cmp.Diff(buf.String(), want); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+}
+
+func TestExportTimeclockIntervals(t *testing.T) {
+	startedAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	es := []*entry{
+		{
+			Desc:      "running",
+			StartedAt: startedAt,
+		},
+		{
+			// paused
+			Desc:      "write docs",
+			StartedAt: startedAt.Add(time.Hour),
+			StoppedAt: startedAt.Add(4 * time.Hour),
+			Segments: []kokizami.Segment{
+				{StartedAt: startedAt.Add(time.Hour), StoppedAt: startedAt.Add(2 * time.Hour)},
+				{StartedAt: startedAt.Add(3 * time.Hour), StoppedAt: startedAt.Add(4 * time.Hour)},
+			},
+		},
+		{
+			// resumed
+			Desc:      "review",
+			StartedAt: startedAt.Add(5 * time.Hour),
+			Segments: []kokizami.Segment{
+				{StartedAt: startedAt.Add(5 * time.Hour), StoppedAt: startedAt.Add(6 * time.Hour)},
+				{StartedAt: startedAt.Add(7 * time.Hour), StoppedAt: time.Unix(0, 0).UTC()},
+			},
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	err := exportTimeclock(buf, es, &transferOptions{
+		Location: time.UTC,
+	})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	// on-going intervals are clocked out at the next clock-in but the last
+	want := "i 2024/03/01 00:00:00 untagged  running\n" +
+		"o 2024/03/01 01:00:00\n" +
+		"i 2024/03/01 01:00:00 untagged  write docs\n" +
+		"o 2024/03/01 02:00:00\n" +
+		"i 2024/03/01 03:00:00 untagged  write docs\n" +
+		"o 2024/03/01 04:00:00\n" +
+		"i 2024/03/01 05:00:00 untagged  review\n" +
+		"o 2024/03/01 06:00:00\n" +
+		"i 2024/03/01 07:00:00 untagged  review\n"
+	if diff := cmp.Diff(buf.String(), want); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+}
+
+func TestImportTimeclock(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	opts := &transferOptions{
+		Location: loc,
+		Accounts: map[string]string{"#dev": "work:development"},
+	}
+
+	in := "; comment\n" +
+		"i 2024/03/01 09:00:00 work:development  write docs\n" +
+		"o 2024/03/01 10:30:00\n" +
+		"\n" +
+		"i 2024-03-01 12:00 untagged\tlunch\n" +
+		"o 2024/03/01 13:00:00\n" +
+		"i 2024/03/01 14:00:00 client a  review #review\n"
+
+	ret, err := importTimeclock(strings.NewReader(in), opts)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	type result struct {
+		Desc      string
+		Tags      []string
+		StartedAt time.Time
+		StoppedAt time.Time
+		Line      int
+	}
+	got := make([]result, len(ret))
+	for i, v := range ret {
+		got[i] = result{v.Desc, v.Tags, v.StartedAt, v.StoppedAt, v.Line}
+	}
+	want := []result{
+		{
+			Desc:      "write docs #dev",
+			Tags:      []string{"#dev"},
+			StartedAt: time.Date(2024, 3, 1, 9, 0, 0, 0, loc),
+			StoppedAt: time.Date(2024, 3, 1, 10, 30, 0, 0, loc),
+			Line:      2,
+		},
+		{
+			Desc:      "lunch",
+			StartedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, loc),
+			StoppedAt: time.Date(2024, 3, 1, 13, 0, 0, 0, loc),
+			Line:      5,
+		},
+		{
+			// clock-in without clock-out is on-going
+			Desc:      "review #review #client_a",
+			Tags:      []string{"#client_a"},
+			StartedAt: time.Date(2024, 3, 1, 14, 0, 0, 0, loc),
+			Line:      7,
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	in = "i 2024/03/01 09:00:00 a\n" +
+		"i 2024/03/01 10:00:00 b\n" +
+		"o 2024/03/01 11:00:00\n" +
+		"o 2024/03/01 12:00:00\n" +
+		"x 2024/03/01 12:00:00\n"
+	_, err = importTimeclock(strings.NewReader(in), opts)
+	errs, ok := err.(rowErrors)
+	if !ok || len(errs) != 3 {
+		t.Fatalf("unexpected result: [got] %v [want] errors on line 1, 4 and 5", err)
+	}
+}
+
+func TestAccountsFromFlags(t *testing.T) {
+	tcs := []struct {
+		in      []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			in:   []string{"--account", "dev=work:development", "--account", "#doc = work:docs"},
+			want: map[string]string{"#dev": "work:development", "#doc": "work:docs"},
+		},
+		{in: []string{"--account", "dev"}, wantErr: true},
+		{in: []string{"--account", "dev="}, wantErr: true},
+		{in: []string{"--account", "=work"}, wantErr: true},
+		{in: []string{"--account", "#=work"}, wantErr: true},
+	}
+
+	for i, tc := range tcs {
+		set := flag.NewFlagSet("export", flag.ContinueOnError)
+		for _, f := range accountFlags() {
+			f.Apply(set)
+		}
+		if err := set.Parse(tc.in); err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+
+		got, err := accountsFromFlags(cli.NewContext(nil, set, nil))
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("[No.%d] unexpected result: [got] nil [want] some error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if diff := cmp.Diff(got, tc.want); diff != "" {
+			t.Errorf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// parseTimeValue parses a time specified on command line.
+// Following formats are accepted:
+//
+//	+15m, -1h             ... relative to base (or now if base is not set)
+//	15:04, 15:04:05       ... today's time in the location of now
+//	2006-01-02 15:04[:05] ... date and time in the location of now
+func parseTimeValue(s string, base, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-") {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid relative time. should be like +15m or -1h: %v", err)
+		}
+		if base.IsZero() || base.Unix() == 0 {
+			base = now
+		}
+		return base.Add(d), nil
+	}
+
+	loc := now.Location()
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		t, err := time.ParseInLocation(layout, s, loc)
+		if err == nil {
+			return time.Date(now.Year(), now.Month(), now.Day(),
+				t.Hour(), t.Minute(), t.Second(), 0, loc), nil
+		}
+	}
+
+	t, err := parseDateTime(s, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time. should be hh:mm[:ss], yyyy-mm-dd hh:mm[:ss], +15m or -1h: %s", s)
+	}
+	return t, nil
+}
+
+// joinNegativeValues joins a long flag and its following value that
+// starts with "-", such as "--stop -15m" or "--stop -", into "--stop=-15m".
+// Otherwise the value is taken as another flag while parsing arguments.
+// boolFlags are not joined since they take no value, e.g. "--dry-run -".
+func joinNegativeValues(args []string, boolFlags map[string]bool) []string {
+	ret := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		v := args[i]
+		if v == "--" {
+			ret = append(ret, args[i:]...)
+			break
+		}
+		if strings.HasPrefix(v, "--") && !strings.Contains(v, "=") && !boolFlags[v[2:]] &&
+			i+1 < len(args) && isNegativeValue(args[i+1]) {
+			ret = append(ret, v+"="+args[i+1])
+			i++
+			continue
+		}
+		ret = append(ret, v)
+	}
+	return ret
+}
+
+// boolFlagNames returns names of boolean flags of specified flags, and of
+// the command invoked by args (without the program name) among cmds.
+// flags of other commands are not included, since a flag may be boolean
+// in one command and take a value in another, e.g. --stop of start and edit.
+func boolFlagNames(flags []cli.Flag, cmds []cli.Command, args []string) map[string]bool {
+	ret := map[string]bool{}
+	flags = append([]cli.Flag{cli.HelpFlag, cli.VersionFlag}, flags...)
+	for {
+		bools := map[string]bool{}
+		for _, f := range flags {
+			switch f.(type) {
+			case cli.BoolFlag, cli.BoolTFlag:
+				for _, name := range strings.Split(f.GetName(), ",") {
+					bools[strings.TrimSpace(name)] = true
+				}
+			}
+		}
+		for k := range bools {
+			ret[k] = true
+		}
+
+		cmd, rest := invokedCommand(cmds, args, bools)
+		if cmd == nil {
+			return ret
+		}
+		flags, cmds, args = append([]cli.Flag{cli.HelpFlag}, cmd.Flags...), cmd.Subcommands, rest
+	}
+}
+
+// invokedCommand returns the command named by the first argument which is
+// not a flag, and arguments after it. values of non-boolean flags are skipped.
+func invokedCommand(cmds []cli.Command, args []string, bools map[string]bool) (*cli.Command, []string) {
+	for i := 0; i < len(args); i++ {
+		v := args[i]
+		if v == "--" {
+			return nil, nil
+		}
+		if strings.HasPrefix(v, "-") && v != "-" {
+			if name := strings.TrimLeft(v, "-"); !strings.Contains(name, "=") && !bools[name] {
+				i++
+			}
+			continue
+		}
+		for j := range cmds {
+			if cmds[j].HasName(v) {
+				return &cmds[j], args[i+1:]
+			}
+		}
+		return nil, nil
+	}
+	return nil, nil
+}
+
+func isNegativeValue(s string) bool {
+	if s == "-" {
+		return true
+	}
+	return len(s) >= 2 && s[0] == '-' && s[1] >= '0' && s[1] <= '9'
+}
+
+func atFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "at",
+			Usage: "specify time instead of now (hh:mm, yyyy-mm-dd hh:mm, or relative like -20m)",
+		},
+		cli.StringFlag{
+			Name:  "ago",
+			Usage: "specify how long ago instead of now (e.g. 20m, 1h30m)",
+		},
+	}
+}
+
+// timeFromFlags returns time specified by --at or --ago.
+// false is returned if neither is specified.
+func timeFromFlags(c *cli.Context, now time.Time) (time.Time, bool, error) {
+	switch {
+	case c.IsSet("at") && c.IsSet("ago"):
+		return time.Time{}, false, fmt.Errorf("--at and --ago can not be specified at once")
+	case c.IsSet("at"):
+		t, err := parseTimeValue(c.String("at"), time.Time{}, now)
+		return t, true, err
+	case c.IsSet("ago"):
+		d, err := time.ParseDuration(c.String("ago"))
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid duration for --ago: %v", err)
+		}
+		if d < 0 {
+			return time.Time{}, false, fmt.Errorf("--ago must not be negative: %s", c.String("ago"))
+		}
+		return now.Add(-d), true, nil
+	default:
+		return now, false, nil
+	}
+}
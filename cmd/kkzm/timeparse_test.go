@@ -0,0 +1,209 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pankona/kokizami/cmd/kkzm/repo/repotest"
+	"github.com/urfave/cli"
+)
+
+func TestParseTimeValue(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+	base := time.Date(2024, 2, 28, 9, 0, 0, 0, time.Local)
+
+	tcs := []struct {
+		in      string
+		inBase  time.Time
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			in:     "+15m",
+			inBase: base,
+			want:   base.Add(15 * time.Minute),
+		},
+		{
+			in:     "-1h",
+			inBase: base,
+			want:   base.Add(-1 * time.Hour),
+		},
+		{
+			// relative to now if base is not set
+			in:     "-1h",
+			inBase: time.Unix(0, 0).UTC(),
+			want:   now.Add(-1 * time.Hour),
+		},
+		{
+			in:     "09:15",
+			inBase: base,
+			want:   time.Date(2024, 3, 1, 9, 15, 0, 0, time.Local),
+		},
+		{
+			in:     "2024-02-29 18:30",
+			inBase: base,
+			want:   time.Date(2024, 2, 29, 18, 30, 0, 0, time.Local),
+		},
+		{
+			in:      "+15",
+			inBase:  base,
+			wantErr: true,
+		},
+		{
+			in:      "yesterday",
+			inBase:  base,
+			wantErr: true,
+		},
+	}
+
+	for i, tc := range tcs {
+		ret, err := parseTimeValue(tc.in, tc.inBase, now)
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("[No.%d] unexpected result: [got] nil [want] some error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if !ret.Equal(tc.want) {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] %v", i, ret, tc.want)
+		}
+	}
+}
+
+func TestParseTimeValueLocation(t *testing.T) {
+	// times are parsed in the location of now, i.e. --timezone
+	loc := time.FixedZone("UTC+9", 9*60*60)
+	now := time.Date(2024, 3, 1, 1, 0, 0, 0, loc)
+
+	tcs := []struct {
+		in   string
+		want time.Time
+	}{
+		{in: "09:15", want: time.Date(2024, 3, 1, 0, 15, 0, 0, time.UTC)},
+		{in: "2024-02-29 18:30", want: time.Date(2024, 2, 29, 9, 30, 0, 0, time.UTC)},
+	}
+
+	for i, tc := range tcs {
+		ret, err := parseTimeValue(tc.in, time.Time{}, now)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if !ret.Equal(tc.want) {
+			t.Errorf("[No.%d] unexpected result: [got] %v [want] %v", i, ret, tc.want)
+		}
+	}
+}
+
+func TestJoinNegativeValues(t *testing.T) {
+	in := []string{"kkzm", "edit", "3", "--stop", "-15m", "--start", "+1h", "--desc", "foo", "-s", "-1", "--stop", "-"}
+	want := []string{"kkzm", "edit", "3", "--stop=-15m", "--start", "+1h", "--desc", "foo", "-s", "-1", "--stop=-"}
+
+	if diff := cmp.Diff(joinNegativeValues(in, nil), want); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+
+	// boolean flags take no value
+	in = []string{"kkzm", "import", "--dry-run", "-"}
+	boolFlags := boolFlagNames(nil, []cli.Command{importCommand()}, in[1:])
+	if diff := cmp.Diff(joinNegativeValues(in, boolFlags), in); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+}
+
+func TestBoolFlagNames(t *testing.T) {
+	tcs := []struct {
+		in   []string
+		want map[string]bool
+	}{
+		{
+			// --stop is boolean only in start and restart
+			in:   []string{"--tz", "UTC", "edit", "3", "--stop", "-15m"},
+			want: map[string]bool{"verbose": true, "help": true, "h": true, "version": true, "v": true},
+		},
+		{
+			in:   []string{"--verbose", "start", "--stop", "foo"},
+			want: map[string]bool{"verbose": true, "help": true, "h": true, "version": true, "v": true, "s": true, "stop": true},
+		},
+		{
+			in:   []string{"import-ics", "--dry-run", "-"},
+			want: map[string]bool{"verbose": true, "help": true, "h": true, "version": true, "v": true, "force": true, "dry-run": true},
+		},
+		{
+			// flags of subcommands
+			in:   []string{"db", "migrate"},
+			want: map[string]bool{"verbose": true, "help": true, "h": true, "version": true, "v": true},
+		},
+	}
+
+	for i, tc := range tcs {
+		got := boolFlagNames(globalFlags(), commands(), tc.in)
+		if diff := cmp.Diff(got, tc.want); diff != "" {
+			t.Errorf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+}
+
+func TestRunNegativeValues(t *testing.T) {
+	k := repotest.OpenTemp(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	app := cli.NewApp()
+	app.Flags = globalFlags()
+	app.Commands = commands()
+	app.Metadata = map[string]interface{}{"kkzm": k}
+
+	added, err := k.Add("foo", now.Add(-2*time.Hour), now.Add(-1*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	id := strconv.Itoa(added.ID)
+
+	// relative stop of edit
+	err = run(app, []string{"kkzm", "edit", id, "--stop", "-15m"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	ki, err := k.Get(added.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if want := now.Add(-75 * time.Minute); !ki.StoppedAt.Equal(want) {
+		t.Errorf("unexpected result: [got] %v [want] %v", ki.StoppedAt, want)
+	}
+
+	// on-going stop of edit
+	err = run(app, []string{"kkzm", "edit", id, "--stop", "-"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	ki, err = k.Get(added.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if !ki.IsRunning() {
+		t.Errorf("unexpected result: [got] %v [want] running", ki.StoppedAt)
+	}
+
+	// boolean stop of start stops the running one
+	err = run(app, []string{"kkzm", "start", "--at", "-20m", "--stop", "bar"})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	ks, err := k.List()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	for _, v := range ks {
+		if v.Desc == "foo" && v.IsRunning() || v.Desc == "bar" && !v.IsRunning() {
+			t.Errorf("unexpected result: [got] %v running %v [want] only bar is running", v.Desc, v.IsRunning())
+		}
+		if v.Desc == "bar" && v.StartedAt.After(now.Add(-19*time.Minute)) {
+			t.Errorf("unexpected result: [got] %v [want] 20 minutes ago", v.StartedAt)
+		}
+	}
+}
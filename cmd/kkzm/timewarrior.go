@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pankona/kokizami"
+)
+
+// timewarriorLayout is layout of times in Timewarrior data files and export JSON
+const timewarriorLayout = "20060102T150405Z"
+
+// timewarriorInterval is an interval of Timewarrior.
+// It is the element of JSON that "timew export" writes.
+type timewarriorInterval struct {
+	ID         int      `json:"id,omitempty"`
+	Start      string   `json:"start"`
+	End        string   `json:"end,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Annotation string   `json:"annotation,omitempty"`
+}
+
+// toTimewarriorTags returns Timewarrior tags of specified kokizami tags.
+// Timewarrior tags have no "#" prefix.
+func toTimewarriorTags(tags []string) []string {
+	ret := make([]string, len(tags))
+	for i, v := range tags {
+		ret[i] = strings.TrimPrefix(v, "#")
+	}
+	return ret
+}
+
+// fromTimewarrior returns an entry of specified interval.
+// annotation becomes desc, and tags that are not written in
+// the annotation are appended to desc as "#tag".
+func fromTimewarrior(tw *timewarriorInterval) (*entry, error) {
+	e := &entry{}
+
+	var err error
+	e.StartedAt, err = time.Parse(timewarriorLayout, tw.Start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start: %s", tw.Start)
+	}
+	if tw.End != "" {
+		e.StoppedAt, err = time.Parse(timewarriorLayout, tw.End)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end: %s", tw.End)
+		}
+	}
+
+	for _, v := range tw.Tags {
+		if t := normalizeTag(v); t != "" {
+			e.Tags = append(e.Tags, t)
+		}
+	}
+	e.Desc = descWithTags(tw.Annotation, e.Tags)
+
+	return e, nil
+}
+
+// descWithTags appends tags that are not written in desc to desc
+func descWithTags(desc string, tags []string) string {
+	written := map[string]bool{}
+	for _, v := range kokizami.ExtractTags(desc) {
+		written[v] = true
+	}
+
+	ss := []string{}
+	if desc = strings.TrimSpace(desc); desc != "" {
+		ss = append(ss, desc)
+	}
+	for _, v := range tags {
+		if !written[v] {
+			written[v] = true
+			ss = append(ss, v)
+		}
+	}
+	return strings.Join(ss, " ")
+}
+
+func toTimewarrior(e *entry) *timewarriorInterval {
+	tw := &timewarriorInterval{
+		ID:         e.ID,
+		Start:      e.StartedAt.UTC().Format(timewarriorLayout),
+		Tags:       toTimewarriorTags(e.Tags),
+		Annotation: e.Desc,
+	}
+	if !e.StoppedAt.IsZero() {
+		tw.End = e.StoppedAt.UTC().Format(timewarriorLayout)
+	}
+	return tw
+}
+
+// exportTimewarriorJSON writes entries as JSON of "timew export"
+func exportTimewarriorJSON(w io.Writer, es []*entry, _ *transferOptions) error {
+	tws := make([]*timewarriorInterval, len(es))
+	for i, v := range es {
+		tws[i] = toTimewarrior(v)
+	}
+
+	b, err := json.MarshalIndent(tws, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+// importTimewarriorJSON reads JSON written by "timew export"
+func importTimewarriorJSON(r io.Reader, _ *transferOptions) ([]*entry, error) {
+	var tws []*timewarriorInterval
+	err := json.NewDecoder(r).Decode(&tws)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse timewarrior json: %v", err)
+	}
+
+	var (
+		es   []*entry
+		errs rowErrors
+	)
+	for i, v := range tws {
+		e, err := fromTimewarrior(v)
+		if err != nil {
+			// there is no line in JSON. report index of intervals instead.
+			errs = append(errs, &rowError{Line: i + 1, Err: err})
+			continue
+		}
+		e.Line = i + 1
+		es = append(es, e)
+	}
+
+	if len(errs) != 0 {
+		return es, errs
+	}
+	return es, nil
+}
+
+// exportTimewarrior writes entries as lines of Timewarrior data files like:
+//
+//	inc 20240301T000000Z - 20240301T013000Z # tag1 "tag 2" # "annotation"
+func exportTimewarrior(w io.Writer, es []*entry, _ *transferOptions) error {
+	for _, e := range es {
+		tw := toTimewarrior(e)
+
+		line := "inc " + tw.Start
+		if tw.End != "" {
+			line += " - " + tw.End
+		}
+		if len(tw.Tags) != 0 || tw.Annotation != "" {
+			line += " #"
+			for _, v := range tw.Tags {
+				line += " " + quoteTimewarriorTag(v)
+			}
+		}
+		if tw.Annotation != "" {
+			line += " # " + strconv.Quote(tw.Annotation)
+		}
+
+		_, err := fmt.Fprintln(w, line)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func quoteTimewarriorTag(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\"#") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// importTimewarrior reads lines of Timewarrior data files
+func importTimewarrior(r io.Reader, _ *transferOptions) ([]*entry, error) {
+	var (
+		es   []*entry
+		errs rowErrors
+		line int
+	)
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line++
+		text := strings.TrimSpace(s.Text())
+		if text == "" {
+			continue
+		}
+
+		tw, err := parseTimewarriorLine(text)
+		if err != nil {
+			errs = append(errs, &rowError{Line: line, Err: err})
+			continue
+		}
+
+		e, err := fromTimewarrior(tw)
+		if err != nil {
+			errs = append(errs, &rowError{Line: line, Err: err})
+			continue
+		}
+		e.Line = line
+		es = append(es, e)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(errs) != 0 {
+		return es, errs
+	}
+	return es, nil
+}
+
+// timewarriorToken is a word of a line of Timewarrior data files
+type timewarriorToken struct {
+	value  string
+	quoted bool
+}
+
+func parseTimewarriorLine(s string) (*timewarriorInterval, error) {
+	ts, err := tokenizeTimewarrior(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(ts) < 2 || ts[0].value != "inc" || ts[0].quoted {
+		return nil, fmt.Errorf("should be like inc <start> - <end> # <tags> # \"<annotation>\": %s", s)
+	}
+
+	tw := &timewarriorInterval{Start: ts[1].value}
+	ts = ts[2:]
+	if len(ts) >= 2 && ts[0].value == "-" && !ts[0].quoted {
+		tw.End = ts[1].value
+		ts = ts[2:]
+	}
+
+	isHash := func(t timewarriorToken) bool {
+		return t.value == "#" && !t.quoted
+	}
+
+	if len(ts) == 0 {
+		return tw, nil
+	}
+	if !isHash(ts[0]) {
+		return nil, fmt.Errorf("unexpected %s. tags should follow \"#\"", ts[0].value)
+	}
+	ts = ts[1:]
+
+	for len(ts) != 0 && !isHash(ts[0]) {
+		tw.Tags = append(tw.Tags, ts[0].value)
+		ts = ts[1:]
+	}
+
+	if len(ts) != 0 {
+		// annotation follows the second "#"
+		var ss []string
+		for _, v := range ts[1:] {
+			ss = append(ss, v.value)
+		}
+		tw.Annotation = strings.Join(ss, " ")
+	}
+
+	return tw, nil
+}
+
+func tokenizeTimewarrior(s string) ([]timewarriorToken, error) {
+	var ts []timewarriorToken
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return ts, nil
+		}
+
+		if s[0] != '"' {
+			i := strings.IndexAny(s, " \t")
+			if i < 0 {
+				i = len(s)
+			}
+			ts = append(ts, timewarriorToken{value: s[:i]})
+			s = s[i:]
+			continue
+		}
+
+		// find closing quote that is not escaped
+		end := -1
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\\' {
+				i++
+				continue
+			}
+			if s[i] == '"' {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated quote: %s", s)
+		}
+
+		v, err := strconv.Unquote(s[:end+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid quoted string %s: %v", s[:end+1], err)
+		}
+		ts = append(ts, timewarriorToken{value: v, quoted: true})
+		s = s[end+1:]
+	}
+}
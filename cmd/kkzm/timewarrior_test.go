@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseTimewarriorLine(t *testing.T) {
+	tcs := []struct {
+		in      string
+		want    *timewarriorInterval
+		wantErr bool
+	}{
+		{
+			in:   `inc 20240301T000000Z - 20240301T013000Z # dev "code review" # "review \"kkzm\" PR"`,
+			want: &timewarriorInterval{Start: "20240301T000000Z", End: "20240301T013000Z", Tags: []string{"dev", "code review"}, Annotation: `review "kkzm" PR`},
+		},
+		{
+			in:   `inc 20240301T000000Z # dev`,
+			want: &timewarriorInterval{Start: "20240301T000000Z", Tags: []string{"dev"}},
+		},
+		{
+			in:   `inc 20240301T000000Z - 20240301T013000Z # # "no tags"`,
+			want: &timewarriorInterval{Start: "20240301T000000Z", End: "20240301T013000Z", Annotation: "no tags"},
+		},
+		{
+			in:   `inc 20240301T000000Z - 20240301T013000Z`,
+			want: &timewarriorInterval{Start: "20240301T000000Z", End: "20240301T013000Z"},
+		},
+		{
+			in:      `exc monday`,
+			wantErr: true,
+		},
+		{
+			in:      `inc 20240301T000000Z # "unterminated`,
+			wantErr: true,
+		},
+	}
+
+	for i, tc := range tcs {
+		ret, err := parseTimewarriorLine(tc.in)
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("[No.%d] unexpected result: [got] nil [want] some error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if diff := cmp.Diff(ret, tc.want); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+}
+
+func TestTimewarriorRoundTrip(t *testing.T) {
+	startedAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	es := []*entry{
+		{
+			Desc:      "write docs #doc",
+			Tags:      []string{"#doc", "#en"},
+			StartedAt: startedAt,
+			StoppedAt: startedAt.Add(90 * time.Minute),
+		},
+		{
+			Desc:      `say "hello"`,
+			Tags:      []string{},
+			StartedAt: startedAt.Add(2 * time.Hour),
+		},
+	}
+
+	for _, format := range []string{"timewarrior", "timewarrior-json"} {
+		buf := &bytes.Buffer{}
+		err := exporters[format](buf, es, &transferOptions{Location: time.UTC})
+		if err != nil {
+			t.Fatalf("[%s] unexpected result: [got] %v [want] nil", format, err)
+		}
+
+		ret, err := importers[format](buf, &transferOptions{Location: time.UTC})
+		if err != nil {
+			t.Fatalf("[%s] unexpected result: [got] %v [want] nil", format, err)
+		}
+		if len(ret) != len(es) {
+			t.Fatalf("[%s] unexpected result: [got] %d entries [want] %d", format, len(ret), len(es))
+		}
+
+		// tags that are not in desc are appended to desc
+		wantDescs := []string{"write docs #doc #en", `say "hello"`}
+		for i := range ret {
+			if ret[i].Desc != wantDescs[i] ||
+				!ret[i].StartedAt.Equal(es[i].StartedAt) || !ret[i].StoppedAt.Equal(es[i].StoppedAt) ||
+				strings.Join(ret[i].Tags, " ") != strings.Join(es[i].Tags, " ") {
+				t.Fatalf("[%s][No.%d] unexpected result: [got] %+v [want] %+v", format, i, ret[i], es[i])
+			}
+		}
+	}
+}
+
+func TestFromTimewarriorTags(t *testing.T) {
+	e, err := fromTimewarrior(&timewarriorInterval{
+		Start:      "20240301T000000Z",
+		Tags:       []string{"#dev", "", "code review"},
+		Annotation: "review",
+	})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if diff := cmp.Diff(e.Tags, []string{"#dev", "#code_review"}); diff != "" {
+		t.Fatalf("unexpected result: (-got +want) %s", diff)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// reportNoDescription is desc of time entries without description and project
+const reportNoDescription = "(no description)"
+
+// reportColumns are columns of detailed reports that are required.
+// Project and Tags columns are optional.
+var reportColumns = []string{"description", "start date", "start time", "end date", "end time"}
+
+// importReport reads CSV of detailed reports of Toggl Track and Clockify.
+// Project and Tags become tags and Description becomes desc.
+// times are taken as in opts.Location, since the reports are written in
+// the timezone of the user's profile.
+func importReport(r io.Reader, opts *transferOptions) ([]*entry, error) {
+	// Clockify writes BOM at the beginning, that breaks quoted header
+	br := bufio.NewReader(r)
+	if b, err := br.Peek(3); err == nil && string(b) == "\ufeff" {
+		_, _ = br.Discard(3)
+	}
+
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("csv is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %v", err)
+	}
+
+	cols := map[string]int{}
+	for i, v := range header {
+		cols[strings.ToLower(strings.TrimSpace(v))] = i
+	}
+	for _, v := range reportColumns {
+		if _, ok := cols[v]; !ok {
+			return nil, fmt.Errorf("csv header must have %s column. is it a detailed report?", v)
+		}
+	}
+
+	var (
+		es   []*entry
+		errs rowErrors
+		line = 1
+	)
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			errs = append(errs, &rowError{Line: line, Err: err})
+			continue
+		}
+
+		e, err := reportEntry(rec, cols, opts)
+		if err != nil {
+			errs = append(errs, &rowError{Line: line, Err: err})
+			continue
+		}
+		e.Line = line
+		es = append(es, e)
+	}
+
+	if len(errs) != 0 {
+		return es, errs
+	}
+	return es, nil
+}
+
+func reportEntry(rec []string, cols map[string]int, opts *transferOptions) (*entry, error) {
+	field := func(name string) string {
+		i, ok := cols[name]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	e := &entry{}
+
+	var tags []string
+	if v := field("project"); v != "" {
+		tags = append(tags, v)
+	}
+	for _, v := range strings.Split(field("tags"), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			tags = append(tags, v)
+		}
+	}
+	for _, v := range tags {
+		if t := normalizeTag(v); t != "" {
+			e.Tags = append(e.Tags, t)
+		}
+	}
+	e.Tags = mergeTags(e.Tags)
+
+	e.Desc = descWithTags(field("description"), e.Tags)
+	if e.Desc == "" {
+		e.Desc = reportNoDescription
+	}
+
+	var err error
+	e.StartedAt, err = parseReportTime(field("start date"), field("start time"), opts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start: %v", err)
+	}
+	e.StoppedAt, err = parseReportTime(field("end date"), field("end time"), opts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end: %v", err)
+	}
+
+	return e, nil
+}
+
+// parseReportTime parses date and time of reports.
+// dates like 2024-03-01, 03/01/2024 and 01.03.2024 are accepted.
+// dates separated by "/" are month first unless opts.DayFirst is true.
+// times are 24-hour or 12-hour with AM/PM.
+func parseReportTime(date, clock string, opts *transferOptions) (time.Time, error) {
+	if date == "" || clock == "" {
+		return time.Time{}, fmt.Errorf("empty")
+	}
+
+	slash := "01/02/2006"
+	if opts.DayFirst {
+		slash = "02/01/2006"
+	}
+
+	for _, d := range []string{"2006-01-02", slash, "02.01.2006"} {
+		for _, c := range []string{"15:04:05", "15:04", "3:04:05 PM", "3:04 PM"} {
+			t, err := time.ParseInLocation(d+" "+c, date+" "+strings.ToUpper(clock), opts.Location)
+			if err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown date and time format: %s %s", date, clock)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestImportReport(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+
+	tcs := []struct {
+		in         string
+		inDayFirst bool
+		wantDescs  []string
+		wantTags   [][]string
+		wantStarts []time.Time
+		wantStops  []time.Time
+	}{
+		{
+			// Toggl Track
+			in: "User,Email,Client,Project,Task,Description,Billable,Start date,Start time,End date,End time,Duration,Tags,Amount ()\n" +
+				"me,me@example.com,ACME,Web site,,write docs,No,2024-03-01,09:00:00,2024-03-01,10:30:00,01:30:00,\"doc, en\",\n" +
+				"me,me@example.com,,,,,No,2024-03-01,23:30:00,2024-03-02,00:15:00,00:45:00,,\n",
+			wantDescs: []string{"write docs #Web_site #doc #en", "(no description)"},
+			wantTags:  [][]string{{"#Web_site", "#doc", "#en"}, nil},
+			wantStarts: []time.Time{
+				time.Date(2024, 3, 1, 9, 0, 0, 0, loc),
+				time.Date(2024, 3, 1, 23, 30, 0, 0, loc),
+			},
+			wantStops: []time.Time{
+				time.Date(2024, 3, 1, 10, 30, 0, 0, loc),
+				time.Date(2024, 3, 2, 0, 15, 0, 0, loc),
+			},
+		},
+		{
+			// Clockify with BOM and 12-hour times
+			in: "\ufeff\"Project\",\"Client\",\"Description\",\"Task\",\"User\",\"Group\",\"Email\",\"Tags\",\"Billable\",\"Start Date\",\"Start Time\",\"End Date\",\"End Time\",\"Duration (h)\",\"Duration (decimal)\"\n" +
+				"\"kokizami\",\"\",\"review #review\",\"\",\"me\",\"\",\"me@example.com\",\"review\",\"No\",\"03/01/2024\",\"01:00:00 PM\",\"03/01/2024\",\"02:00:00 PM\",\"01:00:00\",\"1.00\"\n",
+			wantDescs:  []string{"review #review #kokizami"},
+			wantTags:   [][]string{{"#kokizami", "#review"}},
+			wantStarts: []time.Time{time.Date(2024, 3, 1, 13, 0, 0, 0, loc)},
+			wantStops:  []time.Time{time.Date(2024, 3, 1, 14, 0, 0, 0, loc)},
+		},
+		{
+			// Clockify of day first dates
+			in: "Project,Description,Tags,Start Date,Start Time,End Date,End Time\n" +
+				",meeting,,01/03/2024,13:00,01/03/2024,14:00\n",
+			inDayFirst: true,
+			wantDescs:  []string{"meeting"},
+			wantTags:   [][]string{nil},
+			wantStarts: []time.Time{time.Date(2024, 3, 1, 13, 0, 0, 0, loc)},
+			wantStops:  []time.Time{time.Date(2024, 3, 1, 14, 0, 0, 0, loc)},
+		},
+	}
+
+	for i, tc := range tcs {
+		es, err := importReport(strings.NewReader(tc.in), &transferOptions{Location: loc, DayFirst: tc.inDayFirst})
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+
+		var descs []string
+		var tags [][]string
+		var starts, stops []time.Time
+		for _, v := range es {
+			descs = append(descs, v.Desc)
+			tags = append(tags, v.Tags)
+			starts = append(starts, v.StartedAt)
+			stops = append(stops, v.StoppedAt)
+		}
+		if diff := cmp.Diff(descs, tc.wantDescs); diff != "" {
+			t.Errorf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+		if diff := cmp.Diff(tags, tc.wantTags); diff != "" {
+			t.Errorf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+		if diff := cmp.Diff(starts, tc.wantStarts); diff != "" {
+			t.Errorf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+		if diff := cmp.Diff(stops, tc.wantStops); diff != "" {
+			t.Errorf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+}
+
+func TestImportReportMalformed(t *testing.T) {
+	in := "Project,Description,Tags,Start date,Start time,End date,End time\n" +
+		"p,ok,,2024-03-01,09:00:00,2024-03-01,10:00:00\n" +
+		"p,bad,,2024-03-01,,2024-03-01,10:00:00\n" +
+		"p,bad,,yesterday,09:00:00,2024-03-01,10:00:00\n"
+
+	ret, err := importReport(strings.NewReader(in), &transferOptions{Location: time.UTC})
+	errs, ok := err.(rowErrors)
+	if !ok {
+		t.Fatalf("unexpected result: [got] %v [want] rowErrors", err)
+	}
+	if len(errs) != 2 || errs[0].Line != 3 || errs[1].Line != 4 {
+		t.Fatalf("unexpected result: [got] %v [want] errors on line 3 and 4", errs)
+	}
+	if len(ret) != 1 || ret[0].Line != 2 {
+		t.Fatalf("unexpected result: [got] %v [want] an entry of line 2", ret)
+	}
+
+	// summary reports have no columns of times
+	_, err = importReport(strings.NewReader("Project,Duration\np,01:00:00\n"), &transferOptions{Location: time.UTC})
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] error")
+	}
+}
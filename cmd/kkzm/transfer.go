@@ -0,0 +1,493 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pankona/kokizami"
+	"github.com/urfave/cli"
+)
+
+// entry is a kizami with its tags to be exported or imported
+type entry struct {
+	// ID is ID of the kizami on the source. 0 if unknown.
+	ID        int
+	Desc      string
+	Tags      []string
+	StartedAt time.Time
+	// StoppedAt is zero if the kizami is running.
+	// it is end of the last segment if the kizami is paused.
+	StoppedAt time.Time
+	// Segments holds worked intervals if the kizami has been paused
+	Segments []kokizami.Segment
+
+	// Line is line number on the imported file for reporting
+	Line int
+	// Precision is precision of times on the imported file.
+	// times are compared with existing kizamis in this precision.
+	// 0 means a second.
+	Precision time.Duration
+}
+
+func (e *entry) precision() time.Duration {
+	if e.Precision == 0 {
+		return time.Second
+	}
+	return e.Precision
+}
+
+// transferOptions holds options of exporters and importers
+type transferOptions struct {
+	// Location is timezone of times without offset
+	Location *time.Location
+
+	// Accounts maps tags to accounts of timeclock, and
+	// DefaultAccount is account of kizamis without tags
+	Accounts       map[string]string
+	DefaultAccount string
+
+	// OrgGroup is how to group headings of Org documents
+	OrgGroup string
+
+	// DayFirst is true if dates like 01/03/2024 of reports are day first
+	DayFirst bool
+}
+
+func (o *transferOptions) defaultAccount() string {
+	if o.DefaultAccount == "" {
+		return defaultAccount
+	}
+	return o.DefaultAccount
+}
+
+// transferOptionsFromFlags returns options of exporters and importers specified by flags
+func transferOptionsFromFlags(c *cli.Context, loc *time.Location) (*transferOptions, error) {
+	accounts, err := accountsFromFlags(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &transferOptions{
+		Location:       loc,
+		Accounts:       accounts,
+		DefaultAccount: c.String("default-account"),
+		OrgGroup:       c.String("org-group"),
+		DayFirst:       c.Bool("day-first"),
+	}, nil
+}
+
+// exporter writes entries in a format
+type exporter func(w io.Writer, es []*entry, opts *transferOptions) error
+
+// importer reads entries in a format.
+// rowErrors is returned with well-formed entries if some rows are malformed.
+type importer func(r io.Reader, opts *transferOptions) ([]*entry, error)
+
+var exporters = map[string]exporter{
+	"csv":              exportCSV,
+	"ics":              exportICS,
+	"org":              exportOrg,
+	"timeclock":        exportTimeclock,
+	"timewarrior":      exportTimewarrior,
+	"timewarrior-json": exportTimewarriorJSON,
+}
+
+var importers = map[string]importer{
+	"clockify":         importReport,
+	"csv":              importCSV,
+	"org":              importOrg,
+	"timeclock":        importTimeclock,
+	"timewarrior":      importTimewarrior,
+	"timewarrior-json": importTimewarriorJSON,
+	"toggl":            importReport,
+}
+
+func formatNames(m interface{}) string {
+	var names []string
+	switch v := m.(type) {
+	case map[string]exporter:
+		for k := range v {
+			names = append(names, k)
+		}
+	case map[string]importer:
+		for k := range v {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func exportCommand() cli.Command {
+	return cli.Command{
+		Name:   "export",
+		Usage:  "export tasks with tags",
+		Action: CmdExport,
+		Flags: append([]cli.Flag{
+			cli.StringFlag{
+				Name:  "as",
+				Value: "csv",
+				Usage: "specify format to export (" + formatNames(exporters) + ")",
+			},
+			cli.StringFlag{
+				Name:  "o, output",
+				Usage: "specify file to write. default is stdout",
+			},
+			cli.StringFlag{
+				Name:  "org-group",
+				Value: orgGroupTag,
+				Usage: "specify headings of org (" + orgGroupTag + " or " + orgGroupDesc + ")",
+			},
+		}, append(rangeFlags(), accountFlags()...)...),
+	}
+}
+
+func importCommand() cli.Command {
+	return cli.Command{
+		Name:      "import",
+		Usage:     "import tasks with tags",
+		ArgsUsage: "[file (\"-\" for stdin)]",
+		Action:    CmdImport,
+		Flags: append([]cli.Flag{
+			cli.StringFlag{
+				Name:  "as",
+				Value: "csv",
+				Usage: "specify format to import (" + formatNames(importers) + ")",
+			},
+			cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "show tasks to be imported and conflicts with existing tasks without importing",
+			},
+			cli.BoolFlag{
+				Name:  "day-first",
+				Usage: "take dates like 01/03/2024 of toggl and clockify as day first",
+			},
+		}, accountFlags()...),
+	}
+}
+
+// CmdExport exports tasks
+// kokizami export --as csv > kizamis.csv
+func CmdExport(c *cli.Context) error {
+	export, ok := exporters[c.String("as")]
+	if !ok {
+		return fmt.Errorf("unknown format %s. should be one of %s", c.String("as"), formatNames(exporters))
+	}
+
+	f, err := filterFromFlags(c, currentTime(c), 0)
+	if err != nil {
+		return err
+	}
+
+	kkzm := kkzm(c)
+	opts, err := transferOptionsFromFlags(c, kkzm.Location)
+	if err != nil {
+		return err
+	}
+
+	es, err := entries(kkzm, f)
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if path := c.String("output"); path != "" {
+		file, err := os.Create(path) // #nosec
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %v", path, err)
+		}
+		defer func() {
+			e := file.Close()
+			if e != nil {
+				fmt.Fprintf(os.Stderr, "failed to close %s: %v\n", path, e)
+			}
+		}()
+		w = file
+	}
+
+	return export(w, es, opts)
+}
+
+// entries returns kizamis that match specified filter with their tags
+func entries(kkzm *kokizami.Kokizami, f *kokizami.KizamiFilter) ([]*entry, error) {
+	l, err := kkzm.ListByFilter(f)
+	if err != nil {
+		return nil, err
+	}
+
+	es := make([]*entry, len(l))
+	for i, v := range l {
+		ts, err := kkzm.TagsByKizamiID(v.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		es[i] = &entry{
+			ID:        v.ID,
+			Desc:      v.Desc,
+			Tags:      []string{},
+			StartedAt: v.StartedAt,
+			Segments:  v.Segments,
+		}
+		for _, t := range ts {
+			es[i].Tags = append(es[i].Tags, t.Label)
+		}
+		switch {
+		case v.IsPaused():
+			// paused kizamis are exported as stopped at the end of
+			// their last segment since they are not running
+			es[i].StoppedAt = v.Segments[len(v.Segments)-1].StoppedAt
+		case !v.IsRunning():
+			es[i].StoppedAt = v.StoppedAt
+		}
+	}
+	return es, nil
+}
+
+// CmdImport imports tasks
+// kokizami import kizamis.csv
+// kokizami import --dry-run kizamis.csv ... show conflicts without importing
+func CmdImport(c *cli.Context) error {
+	parse, ok := importers[c.String("as")]
+	if !ok {
+		return fmt.Errorf("unknown format %s. should be one of %s", c.String("as"), formatNames(importers))
+	}
+
+	args := c.Args()
+	if len(args) != 1 {
+		return fmt.Errorf("import needs one argument [file]")
+	}
+
+	kkzm := kkzm(c)
+	opts, err := transferOptionsFromFlags(c, kkzm.Location)
+	if err != nil {
+		return err
+	}
+
+	es, err := readEntries(args[0], parse, opts)
+	errs, ok := err.(rowErrors)
+	if err != nil && !ok {
+		return err
+	}
+
+	// entries are imported in a transaction not to leave
+	// a part of them if some of them are failed to import
+	return inTransaction(database(c), kkzm.Location, func(kkzm *kokizami.Kokizami) error {
+		return importEntries(os.Stdout, kkzm, es, errs, c.Bool("dry-run"))
+	})
+}
+
+// readEntries reads entries from specified file, or stdin if path is "-"
+func readEntries(path string, parse importer, opts *transferOptions) ([]*entry, error) {
+	if path == "-" {
+		return parse(os.Stdin, opts)
+	}
+
+	file, err := os.Open(path) // #nosec
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer func() {
+		e := file.Close()
+		if e != nil {
+			fmt.Fprintf(os.Stderr, "failed to close %s: %v\n", path, e)
+		}
+	}()
+
+	return parse(file, opts)
+}
+
+// rowError is an error of a malformed row
+type rowError struct {
+	Line int
+	Err  error
+}
+
+// rowErrors holds errors of malformed rows
+type rowErrors []*rowError
+
+func (e rowErrors) Error() string {
+	sort.SliceStable(e, func(i, j int) bool {
+		return e[i].Line < e[j].Line
+	})
+
+	lines := make([]string, len(e))
+	for i, v := range e {
+		lines[i] = fmt.Sprintf("line %d: %v", v.Line, v.Err)
+	}
+	return fmt.Sprintf("%d malformed row(s):\n  %s", len(e), strings.Join(lines, "\n  "))
+}
+
+// validateEntry returns error if specified entry can not be imported
+func validateEntry(e *entry, now time.Time) error {
+	switch {
+	case e.Desc == "":
+		return fmt.Errorf("desc must not be empty")
+	case e.StartedAt.IsZero():
+		return fmt.Errorf("started_at must not be empty")
+	case e.StartedAt.After(now):
+		return fmt.Errorf("started_at (%v) must not be in the future", e.StartedAt)
+	case e.StoppedAt.IsZero():
+		return nil
+	case !e.StartedAt.Before(e.StoppedAt):
+		return fmt.Errorf("started_at (%v) must be before stopped_at (%v)", e.StartedAt, e.StoppedAt)
+	case e.StoppedAt.After(now):
+		return fmt.Errorf("stopped_at (%v) must not be in the future", e.StoppedAt)
+	}
+	return nil
+}
+
+// findConflict returns an existing kizami that has the same desc and started_at
+// as specified entry. nil is returned if there is no such kizami.
+func findConflict(kkzm *kokizami.Kokizami, e *entry) (*kokizami.Kizami, error) {
+	precision := e.precision()
+	since := e.StartedAt.Truncate(precision)
+	l, err := kkzm.ListByFilter(&kokizami.KizamiFilter{
+		Since: since,
+		Until: since.Add(precision),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range l {
+		if v.Desc == e.Desc {
+			return v, nil
+		}
+	}
+	return nil, nil
+}
+
+// importEntries imports entries with their tags.
+// entries that conflict with existing kizamis or duplicate former entries
+// are reported and skipped.
+// nothing is imported if any entry is malformed or dryRun is true.
+// errs holds errors of rows that are failed to parse.
+func importEntries(w io.Writer, kkzm *kokizami.Kokizami, es []*entry, errs rowErrors, dryRun bool) error {
+	now := time.Now()
+
+	for _, e := range es {
+		err := validateEntry(e, now)
+		if err != nil {
+			errs = append(errs, &rowError{Line: e.Line, Err: err})
+		}
+	}
+	if len(errs) != 0 {
+		return errs
+	}
+
+	var imported, conflicted int
+	// lines of entries to be imported by desc and started_at
+	seen := map[string]int{}
+	for _, e := range es {
+		key := e.Desc + "\x00" + e.StartedAt.Truncate(e.precision()).UTC().String()
+		if line, ok := seen[key]; ok {
+			conflicted++
+			fmt.Fprintf(w, "line %d: duplicates line %d, skipped\n", e.Line, line)
+			continue
+		}
+		seen[key] = e.Line
+
+		k, err := findConflict(kkzm, e)
+		if err != nil {
+			return err
+		}
+		if k != nil {
+			conflicted++
+			fmt.Fprintf(w, "line %d: conflicts with existing task %d (%s), skipped\n", e.Line, k.ID, k.Desc)
+			continue
+		}
+
+		if dryRun {
+			imported++
+			continue
+		}
+
+		_, err = importEntry(kkzm, e)
+		if err != nil {
+			return fmt.Errorf("line %d: failed to import: %v", e.Line, err)
+		}
+		imported++
+	}
+
+	if dryRun {
+		fmt.Fprintf(w, "%d task(s) will be imported, %d conflict(s) (dry run)\n", imported, conflicted)
+		return nil
+	}
+	fmt.Fprintf(w, "%d task(s) imported, %d conflict(s) skipped\n", imported, conflicted)
+	return nil
+}
+
+// importEntry imports an entry with its tags and returns the imported kizami
+func importEntry(kkzm *kokizami.Kokizami, e *entry) (*kokizami.Kizami, error) {
+	var (
+		k   *kokizami.Kizami
+		err error
+	)
+	if e.StoppedAt.IsZero() {
+		k, err = kkzm.StartAt(e.Desc, e.StartedAt)
+	} else {
+		k, err = kkzm.Add(e.Desc, e.StartedAt, e.StoppedAt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tags := mergeTags(e.Tags, kokizami.ExtractTags(e.Desc))
+	if len(tags) == 0 {
+		return k, nil
+	}
+
+	err = kkzm.AddTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := kkzm.TagsByLabels(tags)
+	if err != nil {
+		return nil, err
+	}
+	tagIDs := make([]int, len(ts))
+	for i, v := range ts {
+		tagIDs[i] = v.ID
+	}
+
+	return k, kkzm.Tagging(k.ID, tagIDs)
+}
+
+// normalizeTag returns a label of kokizami for a tag of other tools.
+// leading "#" are trimmed and spaces are replaced with "_" since tags of
+// kokizami are words. "" is returned if nothing is left.
+func normalizeTag(s string) string {
+	s = strings.Join(strings.Fields(strings.TrimLeft(strings.TrimSpace(s), "#")), "_")
+	if s == "" {
+		return ""
+	}
+	return "#" + s
+}
+
+// mergeTags returns labels of tags without duplication.
+// "#" is prefixed to labels if missing.
+func mergeTags(tagss ...[]string) []string {
+	var ret []string
+	seen := map[string]bool{}
+	for _, tags := range tagss {
+		for _, v := range tags {
+			v = strings.TrimSpace(v)
+			if v == "" || v == "#" {
+				continue
+			}
+			if !strings.HasPrefix(v, "#") {
+				v = "#" + v
+			}
+			if !seen[v] {
+				seen[v] = true
+				ret = append(ret, v)
+			}
+		}
+	}
+	return ret
+}
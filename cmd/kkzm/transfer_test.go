@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pankona/kokizami"
+	"github.com/pankona/kokizami/cmd/kkzm/repo"
+	"github.com/pankona/kokizami/cmd/kkzm/repo/repotest"
+)
+
+func TestEntriesPaused(t *testing.T) {
+	kkzm := repotest.OpenTemp(t)
+
+	k, err := kkzm.Start("paused")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = kkzm.Pause(k.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	k, err = kkzm.Get(k.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	es, err := entries(kkzm, &kokizami.KizamiFilter{})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if len(es) != 1 {
+		t.Fatalf("unexpected result: [got] %v [want] 1 entry", len(es))
+	}
+	// paused kizami is exported as stopped at the end of its last segment
+	want := k.Segments[len(k.Segments)-1].StoppedAt
+	if es[0].StoppedAt.IsZero() || !es[0].StoppedAt.Equal(want) {
+		t.Errorf("unexpected result: [got] %v [want] %v", es[0].StoppedAt, want)
+	}
+}
+
+func TestImportEntriesInTransaction(t *testing.T) {
+	db := repotest.OpenDB(t)
+	_, err := repo.Migrate(db)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	startedAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	es := []*entry{
+		{Desc: "first", Tags: []string{"#doc"}, StartedAt: startedAt, StoppedAt: startedAt.Add(time.Hour), Line: 2},
+		{Desc: "second", StartedAt: startedAt.Add(2 * time.Hour), StoppedAt: startedAt.Add(3 * time.Hour), Line: 3},
+	}
+
+	// nothing is left if importing fails on the way
+	failure := errors.New("failure")
+	err = inTransaction(db, time.UTC, func(kkzm *kokizami.Kokizami) error {
+		err := importEntries(&bytes.Buffer{}, kkzm, es, nil, false)
+		if err != nil {
+			return err
+		}
+		return failure
+	})
+	if err != failure {
+		t.Fatalf("unexpected result: [got] %v [want] %v", err, failure)
+	}
+	kkzm := &kokizami.Kokizami{
+		KizamiRepo: repo.NewKizamiRepo(db),
+		TagRepo:    repo.NewTagRepo(db),
+		Location:   time.UTC,
+	}
+	ks, err := kkzm.List()
+	if err != nil || len(ks) != 0 {
+		t.Fatalf("unexpected result: [got] %v, %v [want] [], nil", ks, err)
+	}
+
+	err = inTransaction(db, time.UTC, func(kkzm *kokizami.Kokizami) error {
+		return importEntries(&bytes.Buffer{}, kkzm, es, nil, false)
+	})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	ks, err = kkzm.List()
+	if err != nil || len(ks) != len(es) {
+		t.Fatalf("unexpected result: [got] %v, %v [want] %d kizamis", ks, err, len(es))
+	}
+}
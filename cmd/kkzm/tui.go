@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/pankona/kokizami/cmd/kkzm/tui"
+	"github.com/urfave/cli"
+)
+
+func tuiCommand() cli.Command {
+	return cli.Command{
+		Name:   "tui",
+		Usage:  "interactive terminal UI of tasks",
+		Action: CmdTUI,
+		Flags: []cli.Flag{
+			cli.StringSliceFlag{
+				Name:  "tag",
+				Usage: "show tasks that have specified tag initially. can be specified multiple times",
+			},
+			cli.StringFlag{
+				Name:  "desc",
+				Usage: "show tasks whose description contains specified string initially",
+			},
+		},
+	}
+}
+
+// CmdTUI runs the full-screen terminal UI
+// kokizami tui [--tag tag] [--desc desc]
+func CmdTUI(c *cli.Context) error {
+	kkzm := kkzm(c)
+
+	var tags []string
+	for _, v := range c.StringSlice("tag") {
+		if !strings.HasPrefix(v, "#") {
+			v = "#" + v
+		}
+		tags = append(tags, v)
+	}
+
+	return tui.Run(kkzm, &tui.Options{
+		Location: kkzm.Location,
+		Desc:     c.String("desc"),
+		Tags:     tags,
+		Edit: func(id int) error {
+			_, err := editTaskWithEditor(kkzm, id)
+			return err
+		},
+	})
+}
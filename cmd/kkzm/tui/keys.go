@@ -0,0 +1,119 @@
+package tui
+
+import (
+	"unicode/utf8"
+)
+
+// keyCode is a kind of keys
+type keyCode int
+
+// kinds of keys. printable characters are keyRune.
+const (
+	keyRune keyCode = iota
+	keyCtrl
+	keyEnter
+	keyTab
+	keyBackspace
+	keyEsc
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyPgUp
+	keyPgDn
+	keyDelete
+)
+
+// key is a key pressed on the terminal.
+// r holds the character for keyRune, and the letter for keyCtrl (e.g. 'c' for Ctrl-C).
+type key struct {
+	code keyCode
+	r    rune
+}
+
+// escapeKeys are keys sent as escape sequences.
+// both of CSI (ESC [) and SS3 (ESC O) forms are accepted.
+var escapeKeys = map[string]keyCode{
+	"A":  keyUp,
+	"B":  keyDown,
+	"C":  keyRight,
+	"D":  keyLeft,
+	"H":  keyHome,
+	"F":  keyEnd,
+	"1~": keyHome,
+	"7~": keyHome,
+	"4~": keyEnd,
+	"8~": keyEnd,
+	"3~": keyDelete,
+	"5~": keyPgUp,
+	"6~": keyPgDn,
+	"Z":  keyTab,
+}
+
+// maxIncomplete is the longest incomplete sequence kept for the next read.
+// longer ones are not keys, and are dropped.
+const maxIncomplete = 16
+
+// parseKeys returns keys in bytes read from the terminal at once,
+// and the rest of an incomplete escape sequence or character at the end
+// which must be prepended to the next read.
+// ESC is a key by itself only if no sequence follows it,
+// and unknown sequences are ignored.
+func parseKeys(b []byte) ([]key, []byte) {
+	var ret []key
+	for len(b) > 0 {
+		switch c := b[0]; {
+		case c == 0x1b:
+			if len(b) == 1 || (b[1] != '[' && b[1] != 'O') {
+				ret = append(ret, key{code: keyEsc})
+				b = b[1:]
+				continue
+			}
+			// parameters and intermediates end with a final byte in 0x40-0x7e
+			i := 2
+			for i < len(b) && (b[i] < 0x40 || b[i] > 0x7e) {
+				i++
+			}
+			if i == len(b) {
+				return ret, incomplete(b)
+			}
+			if code, ok := escapeKeys[string(b[2:i+1])]; ok {
+				ret = append(ret, key{code: code})
+			}
+			b = b[i+1:]
+		case c == '\r' || c == '\n':
+			ret = append(ret, key{code: keyEnter})
+			b = b[1:]
+		case c == '\t':
+			ret = append(ret, key{code: keyTab})
+			b = b[1:]
+		case c == 0x7f || c == 0x08:
+			ret = append(ret, key{code: keyBackspace})
+			b = b[1:]
+		case c < 0x20:
+			ret = append(ret, key{code: keyCtrl, r: rune(c) + 'a' - 1})
+			b = b[1:]
+		default:
+			if !utf8.FullRune(b) {
+				return ret, incomplete(b)
+			}
+			r, n := utf8.DecodeRune(b)
+			if r != utf8.RuneError {
+				ret = append(ret, key{code: keyRune, r: r})
+			}
+			b = b[n:]
+		}
+	}
+	return ret, nil
+}
+
+// incomplete returns a copy of b to be kept until the next read,
+// or nil if b is too long to be a key
+func incomplete(b []byte) []byte {
+	if len(b) > maxIncomplete {
+		return nil
+	}
+	return append([]byte(nil), b...)
+}
@@ -0,0 +1,124 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseKeys(t *testing.T) {
+	tcs := []struct {
+		in       string
+		want     []key
+		wantRest string
+	}{
+		{
+			in:   "ab",
+			want: []key{{code: keyRune, r: 'a'}, {code: keyRune, r: 'b'}},
+		},
+		{
+			in:   "あ#",
+			want: []key{{code: keyRune, r: 'あ'}, {code: keyRune, r: '#'}},
+		},
+		{
+			in:   "\r\n\t\x7f\x08",
+			want: []key{{code: keyEnter}, {code: keyEnter}, {code: keyTab}, {code: keyBackspace}, {code: keyBackspace}},
+		},
+		{
+			in:   "\x03\x15",
+			want: []key{{code: keyCtrl, r: 'c'}, {code: keyCtrl, r: 'u'}},
+		},
+		{
+			in:   "\x1b",
+			want: []key{{code: keyEsc}},
+		},
+		{
+			in:   "\x1bq",
+			want: []key{{code: keyEsc}, {code: keyRune, r: 'q'}},
+		},
+		{
+			in:   "\x1b[A\x1b[B\x1bOC\x1bOD",
+			want: []key{{code: keyUp}, {code: keyDown}, {code: keyRight}, {code: keyLeft}},
+		},
+		{
+			in:   "\x1b[5~\x1b[6~\x1b[3~\x1b[1~\x1b[4~\x1b[Z",
+			want: []key{{code: keyPgUp}, {code: keyPgDn}, {code: keyDelete}, {code: keyHome}, {code: keyEnd}, {code: keyTab}},
+		},
+		{
+			// unknown sequences are ignored
+			in:   "\x1b[1;5Ax",
+			want: []key{{code: keyRune, r: 'x'}},
+		},
+		{
+			// incomplete sequences and characters are left for the next read
+			in:       "x\x1b[",
+			want:     []key{{code: keyRune, r: 'x'}},
+			wantRest: "\x1b[",
+		},
+		{
+			in:       "x\x1b[5",
+			want:     []key{{code: keyRune, r: 'x'}},
+			wantRest: "\x1b[5",
+		},
+		{
+			in:       "x\xe3\x81",
+			want:     []key{{code: keyRune, r: 'x'}},
+			wantRest: "\xe3\x81",
+		},
+		{
+			// too long sequences are dropped
+			in:   "x\x1b[1111111111111111",
+			want: []key{{code: keyRune, r: 'x'}},
+		},
+	}
+
+	for i, tc := range tcs {
+		got, rest := parseKeys([]byte(tc.in))
+		if diff := cmp.Diff(got, tc.want, cmp.AllowUnexported(key{})); diff != "" {
+			t.Errorf("[No.%d] unexpected result (-got +want):\n%s", i, diff)
+		}
+		if string(rest) != tc.wantRest {
+			t.Errorf("[No.%d] unexpected result: [got] %q [want] %q", i, rest, tc.wantRest)
+		}
+	}
+}
+
+func TestParseKeysSplit(t *testing.T) {
+	tcs := []struct {
+		in   []string
+		want []key
+	}{
+		{
+			in:   []string{"\x1b", "[A"},
+			want: []key{{code: keyEsc}, {code: keyRune, r: '['}, {code: keyRune, r: 'A'}},
+		},
+		{
+			in:   []string{"\x1b[", "A"},
+			want: []key{{code: keyUp}},
+		},
+		{
+			in:   []string{"a\x1b[5", "~b"},
+			want: []key{{code: keyRune, r: 'a'}, {code: keyPgUp}, {code: keyRune, r: 'b'}},
+		},
+		{
+			in:   []string{"\xe3", "\x81", "\x82\x1bO", "D"},
+			want: []key{{code: keyRune, r: 'あ'}, {code: keyLeft}},
+		},
+	}
+
+	for i, tc := range tcs {
+		var got []key
+		var rest []byte
+		for _, v := range tc.in {
+			var ks []key
+			ks, rest = parseKeys(append(rest, v...))
+			got = append(got, ks...)
+		}
+		if diff := cmp.Diff(got, tc.want, cmp.AllowUnexported(key{})); diff != "" {
+			t.Errorf("[No.%d] unexpected result (-got +want):\n%s", i, diff)
+		}
+		if len(rest) != 0 {
+			t.Errorf("[No.%d] unexpected result: [got] %q [want] empty", i, rest)
+		}
+	}
+}
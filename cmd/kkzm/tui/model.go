@@ -0,0 +1,588 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pankona/kokizami"
+)
+
+// listLimit is the maximum number of Kizamis in the list
+const listLimit = 1000
+
+// focus is a pane that receives keys
+type focus int
+
+const (
+	focusList focus = iota
+	focusTags
+)
+
+// prompt reads a line of text on the status line
+type prompt struct {
+	label  string
+	input  []rune
+	pos    int
+	submit func(s string) error
+}
+
+// confirm asks yes or no on the status line
+type confirm struct {
+	msg string
+	yes func() error
+}
+
+// model is the state of the terminal UI.
+// it is independent from the terminal, so that it can be tested.
+type model struct {
+	k    *kokizami.Kokizami
+	loc  *time.Location
+	now  func() time.Time
+	edit func(id int) error
+
+	// filter of the list
+	desc string
+	tags []string
+
+	kizamis []*kokizami.Kizami
+	allTags []*kokizami.Tag
+	summary []*kokizami.Elapsed
+	month   time.Time
+	byDesc  bool
+	// selected is ID of Kizami to select after loading
+	selected int
+
+	focus     focus
+	cursor    int
+	offset    int
+	tagCursor int
+	tagOffset int
+	// listRows and sidebar are the layout of last rendering
+	listRows int
+	sidebar  bool
+
+	prompt  *prompt
+	confirm *confirm
+	help    bool
+	message string
+	failed  bool
+
+	// suspend is run with the terminal restored, such as an external editor
+	suspend func() error
+	quit    bool
+}
+
+func newModel(k *kokizami.Kokizami, opts *Options) *model {
+	m := &model{
+		k:    k,
+		loc:  opts.Location,
+		now:  time.Now,
+		edit: opts.Edit,
+		desc: opts.Desc,
+		tags: opts.Tags,
+	}
+	if m.loc == nil {
+		m.loc = time.Local
+	}
+	now := m.now().In(m.loc)
+	m.month = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, m.loc)
+	return m
+}
+
+// load fetches Kizamis, tags and summary. the Kizami of m.selected,
+// or the current one if not specified, is kept selected if it remains.
+func (m *model) load() error {
+	selected := m.selected
+	m.selected = 0
+	if k := m.current(); selected == 0 && k != nil {
+		selected = k.ID
+	}
+
+	ks, err := m.k.ListByFilter(&kokizami.KizamiFilter{
+		Desc:    m.desc,
+		Tags:    m.tags,
+		Limit:   listLimit,
+		Reverse: true,
+	})
+	if err != nil {
+		return err
+	}
+	m.kizamis = ks
+
+	m.allTags, err = m.k.Tags()
+	if err != nil {
+		return err
+	}
+	sort.Slice(m.allTags, func(i, j int) bool { return m.allTags[i].Label < m.allTags[j].Label })
+
+	opt := kokizami.WithRunning(true)
+	if m.byDesc {
+		m.summary, err = m.k.SummaryByDesc(m.month.Format("2006-01"), opt)
+	} else {
+		m.summary, err = m.k.SummaryByTag(m.month.Format("2006-01"), opt)
+	}
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(m.summary, func(i, j int) bool { return m.summary[i].Elapsed > m.summary[j].Elapsed })
+
+	m.cursor = clamp(m.cursor, len(m.kizamis))
+	for i, v := range m.kizamis {
+		if v.ID == selected {
+			m.cursor = i
+			break
+		}
+	}
+	// "(all)" precedes tags
+	m.tagCursor = clamp(m.tagCursor, len(m.allTags)+1)
+	return nil
+}
+
+func clamp(i, n int) int {
+	if i >= n {
+		i = n - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	return i
+}
+
+// current returns the selected Kizami. nil is returned if the list is empty.
+func (m *model) current() *kokizami.Kizami {
+	if m.cursor < 0 || m.cursor >= len(m.kizamis) {
+		return nil
+	}
+	return m.kizamis[m.cursor]
+}
+
+func (m *model) setMessage(format string, a ...interface{}) {
+	m.message = fmt.Sprintf(format, a...)
+	m.failed = false
+}
+
+// done shows result of an operation and reloads the list if it succeeded
+func (m *model) done(err error) {
+	if err == nil {
+		err = m.load()
+	}
+	if err != nil {
+		m.message = err.Error()
+		m.failed = true
+	}
+}
+
+// filterString returns the filter as it is input
+func (m *model) filterString() string {
+	return strings.TrimSpace(strings.Join(m.tags, " ") + " " + m.desc)
+}
+
+// setFilter sets the filter from input. words starting with "#" are tags,
+// and the rest is a part of desc.
+func (m *model) setFilter(s string) error {
+	m.tags = kokizami.ExtractTags(s)
+	var words []string
+	for _, v := range strings.Fields(s) {
+		if !strings.HasPrefix(v, "#") || len(v) < 2 {
+			words = append(words, v)
+		}
+	}
+	m.desc = strings.Join(words, " ")
+	m.cursor, m.offset = 0, 0
+	return m.load()
+}
+
+// toggleTag adds specified tag to the filter, or removes it if it is filtered already
+func (m *model) toggleTag(label string) error {
+	for i, v := range m.tags {
+		if v == label {
+			m.tags = append(m.tags[:i:i], m.tags[i+1:]...)
+			return m.load()
+		}
+	}
+	m.tags = append(m.tags, label)
+	m.cursor, m.offset = 0, 0
+	return m.load()
+}
+
+func (m *model) start(desc string, stopOthers bool) error {
+	if stopOthers {
+		if err := m.k.StopAll(); err != nil {
+			return err
+		}
+	}
+	k, err := m.k.Start(desc)
+	if err != nil {
+		return err
+	}
+	err = m.k.TagByDesc(k.ID, desc)
+	if err != nil {
+		return err
+	}
+	m.selected = k.ID
+	m.cursor = 0
+	m.setMessage("started [%d] %s", k.ID, k.Desc)
+	return nil
+}
+
+func (m *model) stop(k *kokizami.Kizami) error {
+	if !k.IsRunning() && !k.IsPaused() {
+		return fmt.Errorf("kizami [%d] is stopped already", k.ID)
+	}
+	err := m.k.Stop(k.ID)
+	if err != nil {
+		return err
+	}
+	m.setMessage("stopped [%d] %s", k.ID, k.Desc)
+	return nil
+}
+
+func (m *model) togglePause(k *kokizami.Kizami) error {
+	if k.IsPaused() {
+		if err := m.k.Resume(k.ID); err != nil {
+			return err
+		}
+		m.setMessage("resumed [%d] %s", k.ID, k.Desc)
+		return nil
+	}
+	if err := m.k.Pause(k.ID); err != nil {
+		return err
+	}
+	m.setMessage("paused [%d] %s", k.ID, k.Desc)
+	return nil
+}
+
+func (m *model) editDesc(k *kokizami.Kizami, desc string) error {
+	edited := *k
+	edited.Desc = desc
+	_, err := m.k.Edit(&edited)
+	if err != nil {
+		return err
+	}
+	m.setMessage("edited [%d] %s", k.ID, desc)
+	return nil
+}
+
+func (m *model) delete(k *kokizami.Kizami) error {
+	err := m.k.Delete(k.ID)
+	if err != nil {
+		return err
+	}
+	m.setMessage("deleted [%d] %s", k.ID, k.Desc)
+	return nil
+}
+
+// moveMonth moves the month of summary by specified months
+func (m *model) moveMonth(n int) error {
+	m.month = m.month.AddDate(0, n, 0)
+	return m.load()
+}
+
+func (m *model) ask(label, input string, submit func(s string) error) {
+	r := []rune(input)
+	m.prompt = &prompt{label: label, input: r, pos: len(r), submit: submit}
+}
+
+// handle handles a key pressed
+func (m *model) handle(k key) {
+	switch {
+	case m.prompt != nil:
+		m.handlePrompt(k)
+		return
+	case m.confirm != nil:
+		c := m.confirm
+		m.confirm = nil
+		m.message = ""
+		if k.code == keyRune && (k.r == 'y' || k.r == 'Y') {
+			m.done(c.yes())
+		}
+		return
+	}
+
+	m.message = ""
+	if k.code == keyCtrl {
+		m.handleCtrl(k.r)
+		return
+	}
+	if m.focus == focusTags && m.handleTags(k) {
+		return
+	}
+	if m.focus == focusList && m.handleList(k) {
+		return
+	}
+
+	switch k.code {
+	case keyTab, keyLeft, keyRight:
+		m.toggleFocus()
+	case keyEsc:
+		m.help = false
+		if m.filterString() != "" {
+			m.done(m.setFilter(""))
+		}
+	case keyRune:
+		m.handleRune(k.r)
+	}
+}
+
+func (m *model) toggleFocus() {
+	if m.focus == focusList && m.sidebar {
+		m.focus = focusTags
+	} else {
+		m.focus = focusList
+	}
+}
+
+func (m *model) handleCtrl(r rune) {
+	switch r {
+	case 'c':
+		m.quit = true
+	case 'l':
+		m.done(nil)
+	case 'd', 'f':
+		m.page(1)
+	case 'u', 'b':
+		m.page(-1)
+	}
+}
+
+// handleRune handles keys available in any pane
+func (m *model) handleRune(r rune) {
+	switch r {
+	case 'q':
+		m.quit = true
+	case '?':
+		m.help = !m.help
+	case 'h', 'l':
+		m.toggleFocus()
+	case '/':
+		m.ask("filter: ", m.filterString(), m.setFilter)
+	case 's', 'S':
+		stopOthers := r == 'S'
+		m.ask("start: ", "", func(s string) error { return m.start(s, stopOthers) })
+	case 'X':
+		m.done(m.k.StopAll())
+		if !m.failed {
+			m.setMessage("stopped all")
+		}
+	case '[':
+		m.done(m.moveMonth(-1))
+	case ']':
+		m.done(m.moveMonth(1))
+	case 'b':
+		m.byDesc = !m.byDesc
+		m.done(nil)
+	}
+}
+
+// page moves the cursor by pages
+func (m *model) page(n int) {
+	rows := m.listRows
+	if rows < 1 {
+		rows = 1
+	}
+	m.cursor = clamp(m.cursor+n*rows, len(m.kizamis))
+}
+
+// handleList handles keys for the list. false is returned if k is not for the list.
+func (m *model) handleList(k key) bool {
+	switch k.code {
+	case keyUp:
+		m.cursor = clamp(m.cursor-1, len(m.kizamis))
+	case keyDown:
+		m.cursor = clamp(m.cursor+1, len(m.kizamis))
+	case keyHome:
+		m.cursor = 0
+	case keyEnd:
+		m.cursor = clamp(len(m.kizamis)-1, len(m.kizamis))
+	case keyPgUp:
+		m.page(-1)
+	case keyPgDn:
+		m.page(1)
+	case keyRune:
+		return m.handleListRune(k.r)
+	default:
+		return false
+	}
+	return true
+}
+
+func (m *model) handleListRune(r rune) bool {
+	switch r {
+	case 'k':
+		m.cursor = clamp(m.cursor-1, len(m.kizamis))
+		return true
+	case 'j':
+		m.cursor = clamp(m.cursor+1, len(m.kizamis))
+		return true
+	case 'g':
+		m.cursor = 0
+		return true
+	case 'G':
+		m.cursor = clamp(len(m.kizamis)-1, len(m.kizamis))
+		return true
+	}
+
+	k := m.current()
+	if k == nil {
+		return false
+	}
+	switch r {
+	case 'x':
+		m.done(m.stop(k))
+	case 'p':
+		m.done(m.togglePause(k))
+	case 'r', 'R':
+		m.done(m.start(k.Desc, r == 'R'))
+	case 'e':
+		m.ask(fmt.Sprintf("edit [%d]: ", k.ID), k.Desc, func(s string) error { return m.editDesc(k, s) })
+	case 'E':
+		if m.edit == nil {
+			return false
+		}
+		id := k.ID
+		m.suspend = func() error {
+			err := m.edit(id)
+			if err == nil {
+				m.setMessage("edited [%d]", id)
+			}
+			return err
+		}
+	case 'd':
+		m.confirm = &confirm{
+			msg: fmt.Sprintf("delete [%d] %s? (y/N)", k.ID, k.Desc),
+			yes: func() error { return m.delete(k) },
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// handleTags handles keys for the tag sidebar. false is returned if k is not for it.
+func (m *model) handleTags(k key) bool {
+	n := len(m.allTags) + 1
+	switch {
+	case k.code == keyUp || (k.code == keyRune && k.r == 'k'):
+		m.tagCursor = clamp(m.tagCursor-1, n)
+	case k.code == keyDown || (k.code == keyRune && k.r == 'j'):
+		m.tagCursor = clamp(m.tagCursor+1, n)
+	case k.code == keyHome || (k.code == keyRune && k.r == 'g'):
+		m.tagCursor = 0
+	case k.code == keyEnd || (k.code == keyRune && k.r == 'G'):
+		m.tagCursor = n - 1
+	case k.code == keyEnter || (k.code == keyRune && k.r == ' '):
+		if m.tagCursor == 0 {
+			m.tags = nil
+			m.done(m.load())
+			return true
+		}
+		m.done(m.toggleTag(m.allTags[m.tagCursor-1].Label))
+	default:
+		return false
+	}
+	return true
+}
+
+// handlePrompt edits input of the prompt
+func (m *model) handlePrompt(k key) {
+	p := m.prompt
+	switch k.code {
+	case keyRune:
+		p.input = append(p.input[:p.pos], append([]rune{k.r}, p.input[p.pos:]...)...)
+		p.pos++
+	case keyBackspace:
+		if p.pos > 0 {
+			p.input = append(p.input[:p.pos-1], p.input[p.pos:]...)
+			p.pos--
+		}
+	case keyDelete:
+		if p.pos < len(p.input) {
+			p.input = append(p.input[:p.pos], p.input[p.pos+1:]...)
+		}
+	case keyLeft:
+		p.pos = clamp(p.pos-1, len(p.input)+1)
+	case keyRight:
+		p.pos = clamp(p.pos+1, len(p.input)+1)
+	case keyHome:
+		p.pos = 0
+	case keyEnd:
+		p.pos = len(p.input)
+	case keyTab:
+		m.completeTag()
+	case keyEsc:
+		m.prompt = nil
+		m.message = ""
+	case keyEnter:
+		m.prompt = nil
+		m.message = ""
+		m.done(p.submit(strings.TrimSpace(string(p.input))))
+	case keyCtrl:
+		switch k.r {
+		case 'a':
+			p.pos = 0
+		case 'e':
+			p.pos = len(p.input)
+		case 'u':
+			p.input = p.input[p.pos:]
+			p.pos = 0
+		case 'k':
+			p.input = p.input[:p.pos]
+		case 'w':
+			i := p.pos
+			for i > 0 && p.input[i-1] == ' ' {
+				i--
+			}
+			for i > 0 && p.input[i-1] != ' ' {
+				i--
+			}
+			p.input = append(p.input[:i], p.input[p.pos:]...)
+			p.pos = i
+		case 'c':
+			m.prompt = nil
+			m.message = ""
+		}
+	}
+}
+
+// completeTag completes a tag before the cursor of the prompt.
+// it is completed up to the common prefix if there are candidates.
+func (m *model) completeTag() {
+	p := m.prompt
+	start := p.pos
+	for start > 0 && p.input[start-1] != ' ' {
+		start--
+	}
+	word := string(p.input[start:p.pos])
+	if !strings.HasPrefix(word, "#") {
+		return
+	}
+
+	var candidates []string
+	for _, v := range m.allTags {
+		if strings.HasPrefix(v.Label, word) {
+			candidates = append(candidates, v.Label)
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	completed := []rune(candidates[0])
+	for _, v := range candidates[1:] {
+		for !strings.HasPrefix(v, string(completed)) {
+			completed = completed[:len(completed)-1]
+		}
+	}
+	if len(candidates) == 1 {
+		completed = append(completed, ' ')
+		m.message = ""
+	} else {
+		m.setMessage("%s", strings.Join(candidates, " "))
+	}
+
+	r := completed
+	p.input = append(p.input[:start], append(r, p.input[p.pos:]...)...)
+	p.pos = start + len(r)
+}
@@ -0,0 +1,196 @@
+package tui
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/pankona/kokizami"
+	"github.com/pankona/kokizami/cmd/kkzm/repo/repotest"
+)
+
+// press handles keys of s as typed on the terminal
+func press(m *model, s string) {
+	ks, _ := parseKeys([]byte(s))
+	for _, v := range ks {
+		m.handle(v)
+	}
+}
+
+var sgr = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// screen renders m and returns the lines without styles
+func screen(t *testing.T, m *model, w, h int) []string {
+	f := m.view(w, h)
+	if len(f.lines) != h {
+		t.Fatalf("unexpected result: [got] %v [want] %v", len(f.lines), h)
+	}
+	ret := make([]string, len(f.lines))
+	for i, v := range f.lines {
+		ret[i] = sgr.ReplaceAllString(v, "")
+		if got := runewidth.StringWidth(ret[i]); got != w {
+			t.Errorf("[line %d] unexpected width: [got] %v [want] %v: %q", i, got, w, ret[i])
+		}
+	}
+	return ret
+}
+
+func contains(lines []string, s string) bool {
+	for _, v := range lines {
+		if strings.Contains(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestModel(t *testing.T) {
+	k := repotest.OpenTemp(t)
+	for _, v := range []string{"review #work", "lunch"} {
+		ki, err := k.Start(v)
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+		if err = k.TagByDesc(ki.ID, v); err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+	}
+
+	m := newModel(k, &Options{Location: time.UTC})
+	if err := m.load(); err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	lines := screen(t, m, 100, 20)
+	for _, v := range []string{"2 running", "review #work", "lunch", "Summary of", "#work"} {
+		if !contains(lines, v) {
+			t.Errorf("unexpected result: [got] %q [want] %q is shown", lines, v)
+		}
+	}
+
+	// start with tag completion
+	press(m, "swrite docs #wo\t")
+	if got, want := string(m.prompt.input), "write docs #work "; got != want {
+		t.Errorf("unexpected result: [got] %q [want] %q", got, want)
+	}
+	lines = screen(t, m, 100, 20)
+	if got, want := lines[19], "start: write docs #work "; !strings.HasPrefix(got, want) {
+		t.Errorf("unexpected result: [got] %q [want] %q", got, want)
+	}
+	press(m, "\r")
+	if m.failed || len(m.kizamis) != 3 || m.current().Desc != "write docs #work" {
+		t.Fatalf("unexpected result: [got] %v %v [want] started", m.message, m.kizamis)
+	}
+	started := m.current()
+	tags, err := k.TagsByKizamiID(started.ID)
+	if err != nil || len(tags) != 1 || tags[0].Label != "#work" {
+		t.Errorf("unexpected result: [got] %v %v [want] #work", tags, err)
+	}
+
+	// pause, resume and stop
+	press(m, "p")
+	if !m.current().IsPaused() {
+		t.Errorf("unexpected result: [got] %v [want] paused", m.message)
+	}
+	if lines = screen(t, m, 100, 20); !contains(lines, "paused") {
+		t.Errorf("unexpected result: [got] %q [want] paused is shown", lines)
+	}
+	press(m, "p")
+	if !m.current().IsRunning() {
+		t.Errorf("unexpected result: [got] %v [want] running", m.message)
+	}
+	press(m, "x")
+	if m.current().IsRunning() || m.current().IsPaused() {
+		t.Errorf("unexpected result: [got] %v [want] stopped", m.message)
+	}
+	press(m, "x")
+	if !m.failed {
+		t.Errorf("unexpected result: [got] %v [want] already stopped", m.message)
+	}
+
+	// filter by tag and desc
+	press(m, "/#work\r")
+	if got, want := len(m.kizamis), 2; got != want {
+		t.Errorf("unexpected result: [got] %v [want] %v", got, want)
+	}
+	press(m, "/\x15#work docs\r")
+	if got, want := len(m.kizamis), 1; got != want {
+		t.Errorf("unexpected result: [got] %v [want] %v", got, want)
+	}
+	press(m, "\x1b")
+	if got, want := len(m.kizamis), 3; got != want {
+		t.Errorf("unexpected result: [got] %v [want] %v", got, want)
+	}
+
+	// filter by the tag sidebar
+	screen(t, m, 100, 20)
+	press(m, "\tj\r")
+	if got, want := len(m.kizamis), 2; got != want {
+		t.Errorf("unexpected result: [got] %v [want] %v", got, want)
+	}
+	press(m, "g ")
+	if got, want := len(m.kizamis), 3; got != want {
+		t.Errorf("unexpected result: [got] %v [want] %v", got, want)
+	}
+	press(m, "\t")
+
+	// edit desc
+	press(m, "e\x15lunch #food\r")
+	if got, want := m.current().Desc, "lunch #food"; m.current().ID != started.ID || got != want {
+		t.Errorf("unexpected result: [got] %v [want] %v", got, want)
+	}
+
+	// delete is confirmed
+	press(m, "dn")
+	if got, want := len(m.kizamis), 3; got != want {
+		t.Errorf("unexpected result: [got] %v [want] %v", got, want)
+	}
+	press(m, "dy")
+	if got, want := len(m.kizamis), 2; got != want {
+		t.Errorf("unexpected result: [got] %v [want] %v", got, want)
+	}
+	if _, err := k.Get(started.ID); !errors.Is(err, kokizami.ErrNotFound) {
+		t.Errorf("unexpected result: [got] %v [want] not found", err)
+	}
+
+	// stop all
+	press(m, "X")
+	for _, v := range m.kizamis {
+		if v.IsRunning() {
+			t.Errorf("unexpected result: [got] %v [want] stopped", v)
+		}
+	}
+
+	press(m, "q")
+	if !m.quit {
+		t.Errorf("unexpected result: [got] %v [want] true", m.quit)
+	}
+}
+
+func TestView(t *testing.T) {
+	k := repotest.OpenTemp(t)
+	if _, err := k.Start("日本語のとても長い説明 #tag"); err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	m := newModel(k, &Options{Location: time.UTC})
+	if err := m.load(); err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	tcs := []struct {
+		w, h int
+	}{
+		{w: 120, h: 40},
+		{w: 80, h: 24},
+		{w: 40, h: 10},
+		{w: 20, h: 3},
+	}
+	for _, tc := range tcs {
+		screen(t, m, tc.w, tc.h)
+		m.help = true
+		screen(t, m, tc.w, tc.h)
+		m.help = false
+	}
+}
@@ -0,0 +1,32 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package tui
+
+import (
+	"errors"
+	"os"
+)
+
+var errUnsupported = errors.New("terminal UI is not supported on this platform")
+
+// terminal is a terminal switched into raw mode
+type terminal struct{}
+
+func openTerminal(fd int) (*terminal, error) {
+	return nil, errUnsupported
+}
+
+func (t *terminal) raw() error {
+	return errUnsupported
+}
+
+func (t *terminal) restore() error {
+	return errUnsupported
+}
+
+func (t *terminal) size() (int, int, error) {
+	return 0, 0, errUnsupported
+}
+
+func notifyResize(ch chan<- os.Signal) {}
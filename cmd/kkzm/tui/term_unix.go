@@ -0,0 +1,58 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package tui
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// terminal is a terminal switched into raw mode
+type terminal struct {
+	fd    int
+	saved *term.State
+}
+
+// openTerminal switches the terminal of fd into raw mode
+func openTerminal(fd int) (*terminal, error) {
+	if !term.IsTerminal(fd) {
+		return nil, errors.New("not a terminal")
+	}
+	t := &terminal{fd: fd}
+	return t, t.raw()
+}
+
+// raw switches the terminal into raw mode
+func (t *terminal) raw() error {
+	saved, err := term.MakeRaw(t.fd)
+	if err != nil {
+		return err
+	}
+	if t.saved == nil {
+		t.saved = saved
+	}
+	return nil
+}
+
+// restore restores the mode of the terminal before it was opened
+func (t *terminal) restore() error {
+	if t.saved == nil {
+		return nil
+	}
+	return term.Restore(t.fd, t.saved)
+}
+
+// size returns width and height of the terminal
+func (t *terminal) size() (int, int, error) {
+	return term.GetSize(t.fd)
+}
+
+// notifyResize relays signals of resizing the terminal to ch
+func notifyResize(ch chan<- os.Signal) {
+	signal.Notify(ch, syscall.SIGWINCH)
+}
@@ -0,0 +1,157 @@
+// Package tui provides full-screen terminal UI of kokizami.
+// it draws by ANSI escape sequences on a raw mode terminal,
+// and is available on Linux, macOS and BSDs.
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pankona/kokizami"
+)
+
+// Options configures the terminal UI
+type Options struct {
+	// Location is timezone to show times. time.Local is used if nil.
+	Location *time.Location
+	// Desc and Tags are the initial filter of the list
+	Desc string
+	Tags []string
+	// Edit edits the Kizami of id with the terminal restored, such as by $EDITOR.
+	// editing by "E" is disabled if nil.
+	Edit func(id int) error
+}
+
+// escape sequences to control the screen
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l"
+	leaveScreen = "\x1b[?25h\x1b[?1049l"
+	showCursor  = "\x1b[?25h"
+	hideCursor  = "\x1b[?25l"
+)
+
+// reloadTicks is interval of reloading in ticks, to reflect changes by others
+const reloadTicks = 10
+
+// Run runs the terminal UI on stdin and stdout until quit
+func Run(k *kokizami.Kokizami, opts *Options) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+	m := newModel(k, opts)
+	if err := m.load(); err != nil {
+		return err
+	}
+
+	t, err := openTerminal(int(os.Stdin.Fd()))
+	if err != nil {
+		return fmt.Errorf("failed to open terminal: %v", err)
+	}
+	defer func() { _ = t.restore() }()
+
+	out := bufio.NewWriter(os.Stdout)
+	fmt.Fprint(out, enterScreen)
+	defer func() {
+		fmt.Fprint(out, leaveScreen)
+		_ = out.Flush()
+	}()
+
+	// the reader waits for next before reading,
+	// so that it does not steal input from the external editor
+	keys := make(chan []key)
+	next := make(chan struct{}, 1)
+	go func() {
+		buf := make([]byte, 256)
+		var rest []byte
+		for range next {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			// a sequence may be split across reads
+			var ks []key
+			ks, rest = parseKeys(append(rest, buf[:n]...))
+			keys <- ks
+		}
+	}()
+	next <- struct{}{}
+
+	resize := make(chan os.Signal, 1)
+	notifyResize(resize)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	ticks := 0
+	for {
+		if err := draw(out, t, m); err != nil {
+			return err
+		}
+
+		select {
+		case ks, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			for _, v := range ks {
+				m.handle(v)
+			}
+			if m.quit {
+				return nil
+			}
+			if m.suspend != nil {
+				err := suspend(out, t, m.suspend)
+				m.suspend = nil
+				m.done(err)
+			}
+			next <- struct{}{}
+		case <-ticker.C:
+			ticks++
+			if ticks%reloadTicks == 0 && m.prompt == nil && m.confirm == nil {
+				if err := m.load(); err != nil {
+					m.done(err)
+				}
+			}
+		case <-resize:
+		}
+	}
+}
+
+// suspend runs f with the terminal restored to the normal screen
+func suspend(out *bufio.Writer, t *terminal, f func() error) error {
+	fmt.Fprint(out, leaveScreen)
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	if err := t.restore(); err != nil {
+		return err
+	}
+
+	ferr := f()
+
+	if err := t.raw(); err != nil {
+		return err
+	}
+	fmt.Fprint(out, enterScreen)
+	return ferr
+}
+
+// draw renders the model and writes it to the terminal
+func draw(out *bufio.Writer, t *terminal, m *model) error {
+	w, h, err := t.size()
+	if err != nil {
+		return fmt.Errorf("failed to get terminal size: %v", err)
+	}
+
+	f := m.view(w, h)
+	fmt.Fprint(out, hideCursor)
+	for i, v := range f.lines {
+		fmt.Fprintf(out, "\x1b[%d;1H%s", i+1, v)
+	}
+	if f.cursorY >= 0 {
+		fmt.Fprintf(out, "\x1b[%d;%dH%s", f.cursorY+1, f.cursorX+1, showCursor)
+	}
+	return out.Flush()
+}
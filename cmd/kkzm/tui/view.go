@@ -0,0 +1,356 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// styles of text by SGR escape sequences
+const (
+	styleReset   = "\x1b[0m"
+	styleBold    = "\x1b[1m"
+	styleDim     = "\x1b[2m"
+	styleReverse = "\x1b[7m"
+	styleRed     = "\x1b[31m"
+	styleGreen   = "\x1b[32m"
+	styleYellow  = "\x1b[33m"
+)
+
+// layout of the screen
+const (
+	sidebarWidth    = 20
+	minSidebarWidth = 60
+	minWidth        = 30
+	minHeight       = 6
+	timeLayout      = "2006-01-02 15:04"
+)
+
+// helpText is shown by "?"
+var helpText = []string{
+	"j/k, up/down    move",
+	"g/G, home/end   first/last",
+	"ctrl-d/ctrl-u   next/previous page",
+	"tab, h/l        switch list and tags",
+	"enter, space    toggle tag filter (tags)",
+	"s               start a new task",
+	"S               start a new task, stopping others",
+	"r / R           restart the task (R stops others)",
+	"x               stop the task",
+	"X               stop all tasks",
+	"p               pause/resume the task",
+	"e               edit desc of the task",
+	"E               edit the task with $EDITOR",
+	"d               delete the task",
+	"/               filter by #tags and desc",
+	"esc             clear filter",
+	"[ / ]           previous/next month of summary",
+	"b               summary by tag/desc",
+	"ctrl-l          reload",
+	"q, ctrl-c       quit",
+}
+
+// statusHelp is shown on the status line if there is nothing to show
+const statusHelp = "s:start x:stop r:restart p:pause e:edit d:delete /:filter tab:tags ?:help q:quit"
+
+// frame is a rendered screen
+type frame struct {
+	lines []string
+	// cursor is position of the cursor to show. it is hidden if cursorY is negative.
+	cursorX int
+	cursorY int
+}
+
+func styled(style, s string) string {
+	if style == "" {
+		return s
+	}
+	return style + s + styleReset
+}
+
+// fit truncates or pads s to fill w columns
+func fit(s string, w int) string {
+	if w <= 0 {
+		return ""
+	}
+	return runewidth.FillRight(runewidth.Truncate(s, w, "~"), w)
+}
+
+// fitLeft is fit that aligns s to the right
+func fitLeft(s string, w int) string {
+	if w <= 0 {
+		return ""
+	}
+	return runewidth.FillLeft(runewidth.Truncate(s, w, "~"), w)
+}
+
+func elapsedString(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
+
+// view renders the screen of w columns and h rows
+func (m *model) view(w, h int) *frame {
+	f := &frame{cursorY: -1}
+	if w < minWidth || h < minHeight {
+		f.lines = make([]string, h)
+		for i := range f.lines {
+			f.lines[i] = fit("", w)
+		}
+		if h > 0 {
+			f.lines[0] = fit("terminal is too small", w)
+		}
+		return f
+	}
+
+	f.lines = append(f.lines, m.title(w))
+
+	rows := h - 2
+	m.sidebar = w >= minSidebarWidth
+	mainW := w
+	var side []string
+	if m.sidebar {
+		mainW = w - sidebarWidth - 1
+		side = m.tagLines(rows)
+	} else {
+		m.focus = focusList
+	}
+
+	var main []string
+	if m.help {
+		main = m.helpLines(mainW, rows)
+	} else {
+		summary := m.summaryLines(mainW, rows/3)
+		main = append(m.listLines(mainW, rows-len(summary)), summary...)
+	}
+
+	for i := 0; i < rows; i++ {
+		if m.sidebar {
+			f.lines = append(f.lines, side[i]+styled(styleDim, "│")+main[i])
+		} else {
+			f.lines = append(f.lines, main[i])
+		}
+	}
+
+	// the last column is left blank, so that the screen does not scroll
+	status, x := m.status(w - 1)
+	f.lines = append(f.lines, status+" ")
+	if x >= 0 {
+		f.cursorX, f.cursorY = x, h-1
+	}
+	return f
+}
+
+func (m *model) title(w int) string {
+	left := " kokizami"
+	running := 0
+	for _, v := range m.kizamis {
+		if v.IsRunning() {
+			running++
+		}
+	}
+	left += fmt.Sprintf("  %d running", running)
+	if s := m.filterString(); s != "" {
+		left += "  filter: " + s
+	}
+	right := m.now().In(m.loc).Format("2006-01-02 15:04:05") + " "
+
+	lw := w - runewidth.StringWidth(right)
+	if lw < 0 {
+		return styled(styleReverse, fit(left, w))
+	}
+	return styled(styleReverse, fit(left, lw)+right)
+}
+
+// tagLines renders the tag sidebar
+func (m *model) tagLines(rows int) []string {
+	ret := []string{styled(styleBold, fit(" Tags", sidebarWidth))}
+
+	n := rows - 1
+	if m.tagCursor < m.tagOffset {
+		m.tagOffset = m.tagCursor
+	}
+	if m.tagCursor >= m.tagOffset+n {
+		m.tagOffset = m.tagCursor - n + 1
+	}
+
+	labels := []string{"(all)"}
+	for _, v := range m.allTags {
+		labels = append(labels, v.Label)
+	}
+	for i := m.tagOffset; i < len(labels) && len(ret) < rows; i++ {
+		mark := " "
+		if i == 0 && len(m.tags) == 0 {
+			mark = "*"
+		}
+		for _, v := range m.tags {
+			if i > 0 && v == labels[i] {
+				mark = "*"
+			}
+		}
+		s := fit(mark+labels[i], sidebarWidth)
+		if i == m.tagCursor && m.focus == focusTags {
+			s = styled(styleReverse, s)
+		}
+		ret = append(ret, s)
+	}
+	for len(ret) < rows {
+		ret = append(ret, fit("", sidebarWidth))
+	}
+	return ret
+}
+
+// listLines renders the list of Kizamis with its header
+func (m *model) listLines(w, rows int) []string {
+	idW := 2
+	for _, v := range m.kizamis {
+		if n := len(strconv.Itoa(v.ID)); n > idW {
+			idW = n
+		}
+	}
+	timeW := len(timeLayout)
+	elapsedW := 10
+	showStopped := w >= idW+2*timeW+elapsedW+30
+
+	descW := w - idW - timeW - elapsedW - 5
+	if showStopped {
+		descW -= timeW + 1
+	}
+
+	row := func(id, desc, started, stopped, elapsed string) string {
+		s := " " + fitLeft(id, idW) + " " + fit(desc, descW) + " " + fit(started, timeW)
+		if showStopped {
+			s += " " + fit(stopped, timeW)
+		}
+		return s + " " + fitLeft(elapsed, elapsedW) + " "
+	}
+
+	ret := []string{styled(styleBold, row("ID", "Desc", "Started", "Stopped", "Elapsed"))}
+
+	n := rows - 1
+	m.listRows = n
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+n {
+		m.offset = m.cursor - n + 1
+	}
+
+	for i := m.offset; i < len(m.kizamis) && len(ret) < rows; i++ {
+		k := m.kizamis[i]
+		var stopped, style string
+		switch {
+		case k.IsPaused():
+			stopped, style = "paused", styleYellow
+		case k.IsRunning():
+			stopped, style = "running", styleGreen
+		default:
+			stopped = k.StoppedAt.In(m.loc).Format(timeLayout)
+		}
+		if i == m.cursor {
+			if m.focus == focusList {
+				style += styleReverse
+			} else {
+				style += styleBold
+			}
+		}
+		ret = append(ret, styled(style, row(strconv.Itoa(k.ID), k.Desc, k.StartedAt.In(m.loc).Format(timeLayout), stopped, elapsedString(k.Elapsed()))))
+	}
+
+	if len(m.kizamis) == 0 && len(ret) < rows {
+		ret = append(ret, styled(styleDim, fit(" no tasks", w)))
+	}
+	for len(ret) < rows {
+		ret = append(ret, fit("", w))
+	}
+	return ret
+}
+
+// summaryLines renders the summary of the month in rows at most
+func (m *model) summaryLines(w, rows int) []string {
+	if rows < 2 {
+		return nil
+	}
+
+	by := "tag"
+	if m.byDesc {
+		by = "desc"
+	}
+	ret := []string{styled(styleReverse, fit(fmt.Sprintf(" Summary of %s by %s", m.month.Format("2006-01"), by), w))}
+
+	elapsedW := 12
+	for _, v := range m.summary {
+		if len(ret) == rows {
+			break
+		}
+		label := v.Tag
+		if label == "" {
+			label = "-- No tag --"
+		}
+		if m.byDesc {
+			label += "  " + v.Desc
+		}
+		elapsed := elapsedString(v.Elapsed)
+		if v.Running {
+			// same as "kkzm summary", elapsed time including on-going tasks
+			elapsed = "*" + elapsed
+		}
+		ret = append(ret, " "+fit(label, w-elapsedW-2)+fitLeft(elapsed, elapsedW)+" ")
+	}
+	if len(m.summary) == 0 {
+		ret = append(ret, styled(styleDim, fit(" no records", w)))
+	}
+	for len(ret) < rows {
+		ret = append(ret, fit("", w))
+	}
+	return ret
+}
+
+func (m *model) helpLines(w, rows int) []string {
+	ret := []string{styled(styleBold, fit(" Keys", w))}
+	for _, v := range helpText {
+		if len(ret) == rows {
+			break
+		}
+		ret = append(ret, fit(" "+v, w))
+	}
+	for len(ret) < rows {
+		ret = append(ret, fit("", w))
+	}
+	return ret
+}
+
+// status renders the status line, and returns column of the cursor if it is shown
+func (m *model) status(w int) (string, int) {
+	switch {
+	case m.prompt != nil:
+		p := m.prompt
+		before := p.label + string(p.input[:p.pos])
+		s := p.label + string(p.input)
+		x := runewidth.StringWidth(before)
+		if x >= w {
+			// scroll the input to show the cursor
+			cut := []rune(before)
+			for runewidth.StringWidth(string(cut)) >= w {
+				cut = cut[1:]
+			}
+			s = string(cut) + string(p.input[p.pos:])
+			x = runewidth.StringWidth(string(cut))
+		}
+		line := fit(s, w)
+		// candidates of completion follow the input
+		if m.message != "" && runewidth.StringWidth(s)+2 < w {
+			sw := runewidth.StringWidth(s)
+			line = s + "  " + styled(styleDim, fit(m.message, w-sw-2))
+		}
+		return line, x
+	case m.confirm != nil:
+		return styled(styleBold, fit(m.confirm.msg, w)), -1
+	case m.message != "" && m.failed:
+		return styled(styleRed, fit(m.message, w)), -1
+	case m.message != "":
+		return fit(m.message, w), -1
+	}
+	return styled(styleDim, fit(statusHelp, w)), -1
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/pankona/kokizami/web"
+	"github.com/urfave/cli"
+)
+
+func webCommand() cli.Command {
+	return cli.Command{
+		Name:   "web",
+		Usage:  "serve dashboard of tasks for browsers",
+		Action: CmdWeb,
+		Flags:  serveFlags(),
+	}
+}
+
+// CmdWeb serves the dashboard with the API until interrupted
+// kokizami web --listen 127.0.0.1:7777
+func CmdWeb(c *cli.Context) error {
+	return listenAndServe(c, web.Handler())
+}
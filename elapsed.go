@@ -10,10 +10,64 @@ type Elapsed struct {
 	Desc    string
 	Count   int
 	Elapsed time.Duration
+
+	// Running is true if on-going Kizamis are included in Elapsed
+	Running bool
+}
+
+// DailyElapsed represents total elapsed time of Kizamis in a day
+type DailyElapsed struct {
+	// Date is the beginning of the day in Location of Kokizami
+	Date    time.Time
+	Elapsed time.Duration
+
+	// Running is true if on-going Kizamis are included in Elapsed
+	Running bool
+}
+
+// SummaryQuery specifies how to summarize elapsed time of Kizamis
+type SummaryQuery struct {
+	// From and To specify the range [From, To) to summarize
+	From time.Time
+	To   time.Time
+
+	// Prorate clips elapsed time of each Kizami to the range,
+	// so that a Kizami across boundaries of the range is counted
+	// only for the time fell inside it.
+	// Otherwise Kizamis started in the range are counted entirely.
+	Prorate bool
+
+	// IncludeRunning includes on-going Kizamis in summaries.
+	// Their elapsed time is counted until Now.
+	IncludeRunning bool
+	Now            time.Time
+}
+
+// SummaryOption configures SummaryQuery
+type SummaryOption func(q *SummaryQuery)
+
+// WithProrate specifies whether elapsed time of each Kizami is clipped to the range
+func WithProrate(prorate bool) SummaryOption {
+	return func(q *SummaryQuery) {
+		q.Prorate = prorate
+	}
+}
+
+// WithRunning specifies whether on-going Kizamis are included in summaries
+func WithRunning(includeRunning bool) SummaryOption {
+	return func(q *SummaryQuery) {
+		q.IncludeRunning = includeRunning
+	}
 }
 
 // SummaryRepository is an interface to fetch summaries from repository
 type SummaryRepository interface {
-	ElapsedOfMonthByDesc(yyyymm string) ([]*Elapsed, error)
-	ElapsedOfMonthByTag(yyyymm string) ([]*Elapsed, error)
+	ElapsedByDesc(q *SummaryQuery) ([]*Elapsed, error)
+	ElapsedByTag(q *SummaryQuery) ([]*Elapsed, error)
+
+	// ElapsedOfDays returns total elapsed time of Kizamis in each of days.
+	// days are beginnings of the days in order, and each of them ends at
+	// the next one, or at To of the query for the last day.
+	// a Kizami with many tags is counted once.
+	ElapsedOfDays(q *SummaryQuery, days []time.Time) ([]*DailyElapsed, error)
 }
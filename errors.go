@@ -0,0 +1,47 @@
+package kokizami
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errors to classify failures of Kokizami APIs.
+// use errors.Is to test them, since they are wrapped with details.
+var (
+	// ErrNotFound is returned if specified Kizami or Tag does not exist
+	ErrNotFound = errors.New("not found")
+
+	// ErrInvalid is returned if arguments are invalid
+	ErrInvalid = errors.New("invalid argument")
+
+	// ErrConflict is returned if an operation conflicts with
+	// current state, such as pausing a stopped Kizami
+	ErrConflict = errors.New("conflict")
+)
+
+// kindError is an error of a kind, such as ErrInvalid.
+// its message is the detail only.
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() error {
+	return e.err
+}
+
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
+
+func invalidf(format string, a ...interface{}) error {
+	return &kindError{kind: ErrInvalid, err: fmt.Errorf(format, a...)}
+}
+
+func conflictf(format string, a ...interface{}) error {
+	return &kindError{kind: ErrConflict, err: fmt.Errorf(format, a...)}
+}
@@ -0,0 +1,134 @@
+package kokizami
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType is a type of changes of a Kizami
+type EventType string
+
+// types of Events
+const (
+	EventStarted EventType = "started"
+	// EventAdded is emitted when a finished Kizami is added
+	EventAdded   EventType = "added"
+	EventStopped EventType = "stopped"
+	EventPaused  EventType = "paused"
+	EventResumed EventType = "resumed"
+	EventEdited  EventType = "edited"
+	EventDeleted EventType = "deleted"
+	// EventTagged is emitted when tags of a Kizami are changed
+	EventTagged EventType = "tagged"
+)
+
+// Event notifies a change of a Kizami made by Kokizami
+type Event struct {
+	Type EventType
+	// Kizami is the changed Kizami. it is the one before deleted for EventDeleted.
+	Kizami *Kizami
+	Tags   []*Tag
+	At     time.Time
+}
+
+// eventBufferSize is capacity of channels of subscribers
+const eventBufferSize = 64
+
+// eventBus delivers Events to subscribers. zero value is ready to use.
+type eventBus struct {
+	mu   sync.Mutex
+	subs map[chan *Event]struct{}
+}
+
+func (b *eventBus) subscribe() (<-chan *Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subs == nil {
+		b.subs = map[chan *Event]struct{}{}
+	}
+	ch := make(chan *Event, eventBufferSize)
+	b.subs[ch] = struct{}{}
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subs, ch)
+			close(ch)
+		})
+	}
+}
+
+func (b *eventBus) subscribed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs) != 0
+}
+
+// publish sends e to subscribers. e is dropped for subscribers
+// whose channel is full, so that operations are not blocked by them.
+func (b *eventBus) publish(e *Event) {
+	if e == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel that receives Events of changes made by this Kokizami,
+// and a function to stop receiving them that closes the channel.
+// Events are dropped while the channel is full.
+// changes made by other processes on the same store are not notified.
+func (k *Kokizami) Subscribe() (<-chan *Event, func()) {
+	return k.events.subscribe()
+}
+
+// Publish notifies subscribers of a change of specified Kizami made
+// through the repositories of this Kokizami without its APIs, such as
+// by Handler of kokizami/client. the Kizami is fetched if ki is nil.
+func (k *Kokizami) Publish(t EventType, id int, ki *Kizami) {
+	k.publish(t, id, ki)
+}
+
+// event returns an Event of specified Kizami with its tags.
+// the Kizami is fetched if ki is nil. nil is returned if there are
+// no subscribers, or the Kizami can not be fetched.
+func (k *Kokizami) event(t EventType, id int, ki *Kizami) *Event {
+	if !k.events.subscribed() {
+		return nil
+	}
+
+	var err error
+	if ki == nil {
+		ki, err = k.KizamiRepo.FindByID(id)
+		if err != nil {
+			// the change has been made already. only its notification is lost.
+			return nil
+		}
+	}
+
+	tags, err := k.TagsByKizamiID(id)
+	if err != nil {
+		return nil
+	}
+
+	// subscribers receive a copy since callers may modify the Kizami
+	c := *ki
+	c.Segments = append([]Segment(nil), ki.Segments...)
+
+	return &Event{Type: t, Kizami: &c, Tags: tags, At: k.clock().UTC()}
+}
+
+// publish notifies subscribers of a change of specified Kizami
+func (k *Kokizami) publish(t EventType, id int, ki *Kizami) {
+	k.events.publish(k.event(t, id, ki))
+}
@@ -2,10 +2,13 @@ module github.com/pankona/kokizami
 
 require (
 	github.com/google/go-cmp v0.5.5
+	github.com/mattn/go-runewidth v0.0.9
 	github.com/mattn/go-sqlite3 v2.0.3+incompatible
 	github.com/olekukonko/tablewriter v0.0.5
 	github.com/urfave/cli v1.22.5
 	github.com/xo/xoutil v0.0.0-20171112033149-46189f4026a5
+	golang.org/x/sys v0.7.0 // indirect
+	golang.org/x/term v0.0.0-20210422114643-f5beecf764ed
 )
 
-go 1.15
+go 1.16
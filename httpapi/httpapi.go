@@ -0,0 +1,88 @@
+// Package httpapi provides conventions shared by HTTP APIs of kokizami,
+// such as checks of requests and statuses of errors
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pankona/kokizami"
+)
+
+// statuses maps errors of kokizami to HTTP statuses
+var statuses = []struct {
+	kind   error
+	status int
+}{
+	{kind: kokizami.ErrNotFound, status: http.StatusNotFound},
+	{kind: kokizami.ErrInvalid, status: http.StatusBadRequest},
+	{kind: kokizami.ErrConflict, status: http.StatusConflict},
+}
+
+// StatusOf returns HTTP status of specified error of kokizami
+func StatusOf(err error) int {
+	for _, v := range statuses {
+		if errors.Is(err, v.kind) {
+			return v.status
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// KindOf returns error of kokizami of specified HTTP status, such as
+// kokizami.ErrNotFound for 404. nil is returned for other statuses.
+func KindOf(status int) error {
+	for _, v := range statuses {
+		if v.status == status {
+			return v.kind
+		}
+	}
+	return nil
+}
+
+// CheckHost returns error if Host of request is neither a loopback name,
+// an IP address nor one of hosts. it blocks DNS rebinding attacks, that
+// reach servers on loopback by names of attackers.
+func CheckHost(r *http.Request, hosts ...string) error {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+
+	switch {
+	case host == "localhost" || strings.HasSuffix(host, ".localhost"):
+		return nil
+	case net.ParseIP(host) != nil:
+		return nil
+	}
+	for _, v := range hosts {
+		if strings.EqualFold(v, host) {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %s is not allowed", r.Host)
+}
+
+// CheckContentType returns error if request has a body that is not JSON.
+// forms of other sites can not send JSON without preflight requests of CORS.
+func CheckContentType(r *http.Request) error {
+	if r.Header.Get("Content-Type") == "" && r.ContentLength == 0 {
+		return nil
+	}
+	return RequireJSON(r)
+}
+
+// RequireJSON returns error if request is not JSON even if it has no body
+func RequireJSON(r *http.Request) error {
+	ct := r.Header.Get("Content-Type")
+	t, _, err := mime.ParseMediaType(ct)
+	if err != nil || t != "application/json" {
+		return fmt.Errorf("content type %q is not supported. should be application/json", ct)
+	}
+	return nil
+}
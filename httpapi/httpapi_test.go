@@ -0,0 +1,61 @@
+package httpapi
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckHost(t *testing.T) {
+	tcs := []struct {
+		in      string
+		inHosts []string
+		wantErr bool
+	}{
+		{in: "localhost:7777"},
+		{in: "LOCALHOST."},
+		{in: "app.localhost:7777"},
+		{in: "127.0.0.1:7777"},
+		{in: "[::1]:7777"},
+		{in: "192.168.0.10"},
+		{in: "myhost:7777", inHosts: []string{"myhost"}},
+		{in: "myhost:7777", wantErr: true},
+		{in: "evil.example.com:7777", wantErr: true},
+		{in: "localhost.evil.example.com", wantErr: true},
+	}
+
+	for i, tc := range tcs {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Host = tc.in
+		err := CheckHost(r, tc.inHosts...)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("[No.%d] unexpected result: [got] %v [want] error %v", i, err, tc.wantErr)
+		}
+	}
+}
+
+func TestCheckContentType(t *testing.T) {
+	tcs := []struct {
+		inType  string
+		inBody  string
+		wantErr bool
+	}{
+		{inType: "application/json", inBody: `{}`},
+		{inType: "application/json; charset=utf-8", inBody: `{}`},
+		{inType: "", inBody: ""},
+		{inType: "", inBody: `{}`, wantErr: true},
+		{inType: "text/plain", inBody: `{}`, wantErr: true},
+		{inType: "application/x-www-form-urlencoded", inBody: "", wantErr: true},
+	}
+
+	for i, tc := range tcs {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(tc.inBody))
+		if tc.inType != "" {
+			r.Header.Set("Content-Type", tc.inType)
+		}
+		err := CheckContentType(r)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("[No.%d] unexpected result: [got] %v [want] error %v", i, err, tc.wantErr)
+		}
+	}
+}
@@ -10,17 +10,41 @@ type Kizami struct {
 	Desc      string
 	StartedAt time.Time
 	StoppedAt time.Time
+
+	// Segments holds worked intervals of this Kizami.
+	// It is empty unless this Kizami has been paused at least once.
+	Segments []Segment
+}
+
+// Segment represents a worked interval of a Kizami.
+// StoppedAt of on-going segment holds initial value of time.
+type Segment struct {
+	ID        int
+	KizamiID  int
+	StartedAt time.Time
+	StoppedAt time.Time
 }
 
 // Elapsed returns kizami's elapsed time
 func (k *Kizami) Elapsed() time.Duration {
+	if len(k.Segments) != 0 {
+		var elapsed time.Duration
+		for i := range k.Segments {
+			elapsed += elapsedBetween(k.Segments[i].StartedAt, k.Segments[i].StoppedAt)
+		}
+		return elapsed
+	}
+	return elapsedBetween(k.StartedAt, k.StoppedAt)
+}
+
+func elapsedBetween(startedAt, stoppedAt time.Time) time.Duration {
 	var elapsed time.Duration
-	if k.StoppedAt.Unix() == 0 {
+	if stoppedAt.Unix() == 0 {
 		// this Kizami is on going. Show elapsed time until now.
 		now := time.Now().UTC()
-		elapsed = now.Sub(k.StartedAt)
+		elapsed = now.Sub(startedAt)
 	} else {
-		elapsed = k.StoppedAt.Sub(k.StartedAt)
+		elapsed = stoppedAt.Sub(startedAt)
 		if elapsed < 0 {
 			elapsed = 0
 		}
@@ -28,14 +52,60 @@ func (k *Kizami) Elapsed() time.Duration {
 	return elapsed
 }
 
+// IsRunning returns true if this Kizami is on going
+func (k *Kizami) IsRunning() bool {
+	if k.StoppedAt.Unix() != 0 {
+		return false
+	}
+	if len(k.Segments) == 0 {
+		return true
+	}
+	return k.Segments[len(k.Segments)-1].StoppedAt.Unix() == 0
+}
+
+// IsPaused returns true if this Kizami is paused
+func (k *Kizami) IsPaused() bool {
+	return k.StoppedAt.Unix() == 0 && !k.IsRunning()
+}
+
+// KizamiFilter specifies conditions to find Kizamis
+type KizamiFilter struct {
+	// Since and Until filter Kizamis by started_at in [Since, Until).
+	// Zero value means unbounded.
+	Since time.Time
+	Until time.Time
+
+	// Tags filters Kizamis that have all of specified tags
+	Tags []string
+
+	// Desc filters Kizamis whose desc contains specified string
+	Desc string
+
+	// Running filters Kizamis that are not stopped yet, including paused ones
+	Running bool
+
+	// Limit and Offset are applied to Kizamis ordered from the newest,
+	// so that Limit N returns the last N Kizamis. Limit 0 means no limit.
+	Limit  int
+	Offset int
+
+	// Reverse orders results from the newest.
+	// Otherwise results are ordered from the oldest.
+	Reverse bool
+}
+
 // KizamiRepository is an interface to fetch Kizami from repository
 type KizamiRepository interface {
 	FindAll() ([]*Kizami, error)
 	Insert(desc string) (*Kizami, error)
+	InsertWithTimes(desc string, startedAt, stoppedAt time.Time) (*Kizami, error)
 	Update(k *Kizami) error
 	Delete(k *Kizami) error
 	FindByID(id int) (*Kizami, error)
 	FindByStoppedAt(t time.Time) ([]*Kizami, error)
+	FindByFilter(f *KizamiFilter) ([]*Kizami, error)
 	Tagging(kizamiID int, tagIDs []int) error
 	Untagging(kizamiID int) error
+	InsertSegment(s *Segment) error
+	UpdateSegment(s *Segment) error
 }
@@ -13,9 +13,15 @@ import (
 type Kokizami struct {
 	now func() time.Time
 
+	// Location is used to compute boundaries of summaries,
+	// such as beginning of a month. time.Local is used if nil.
+	Location *time.Location
+
 	KizamiRepo  KizamiRepository
 	TagRepo     TagRepository
 	SummaryRepo SummaryRepository
+
+	events eventBus
 }
 
 // initialTime is used to insert a time value that indicates initial value of time.
@@ -27,13 +33,85 @@ func initialTime() time.Time {
 	return t.UTC()
 }
 
+// location returns location to compute boundaries of summaries
+func (k *Kokizami) location() *time.Location {
+	if k.Location == nil {
+		return time.Local
+	}
+	return k.Location
+}
+
+// clock returns current time using injected clock.
+// time.Now is used if no clock is injected.
+func (k *Kokizami) clock() time.Time {
+	if k.now == nil {
+		return time.Now()
+	}
+	return k.now()
+}
+
 // Start starts a new kizami with specified desc
 func (k *Kokizami) Start(desc string) (*Kizami, error) {
 	if len(desc) == 0 {
-		return nil, fmt.Errorf("desc must not be empty")
+		return nil, invalidf("desc must not be empty")
+	}
+
+	ki, err := k.KizamiRepo.Insert(desc)
+	if err != nil {
+		return nil, err
+	}
+	k.publish(EventStarted, ki.ID, ki)
+	return ki, nil
+}
+
+// StartAt starts a new kizami with specified desc at specified time.
+// The time must not be in the future.
+func (k *Kokizami) StartAt(desc string, t time.Time) (*Kizami, error) {
+	if len(desc) == 0 {
+		return nil, invalidf("desc must not be empty")
+	}
+
+	if err := k.validatePast(t); err != nil {
+		return nil, err
+	}
+
+	ki, err := k.KizamiRepo.InsertWithTimes(desc, t.UTC(), initialTime())
+	if err != nil {
+		return nil, err
+	}
+	k.publish(EventStarted, ki.ID, ki)
+	return ki, nil
+}
+
+// Add adds a finished kizami with specified desc, started_at and stopped_at.
+// It is used to record a work retroactively without starting a timer.
+func (k *Kokizami) Add(desc string, startedAt, stoppedAt time.Time) (*Kizami, error) {
+	if len(desc) == 0 {
+		return nil, invalidf("desc must not be empty")
+	}
+
+	if !startedAt.Before(stoppedAt) {
+		return nil, invalidf("started_at (%v) must be before stopped_at (%v)", startedAt.UTC(), stoppedAt.UTC())
+	}
+
+	if err := k.validatePast(stoppedAt); err != nil {
+		return nil, err
 	}
 
-	return k.KizamiRepo.Insert(desc)
+	ki, err := k.KizamiRepo.InsertWithTimes(desc, startedAt.UTC(), stoppedAt.UTC())
+	if err != nil {
+		return nil, err
+	}
+	k.publish(EventAdded, ki.ID, ki)
+	return ki, nil
+}
+
+// validatePast returns error if specified time is in the future
+func (k *Kokizami) validatePast(t time.Time) error {
+	if now := k.clock(); t.After(now) {
+		return invalidf("time (%v) must not be in the future", t.In(k.location()))
+	}
+	return nil
 }
 
 // Get returns a Kizami by specified ID
@@ -41,13 +119,23 @@ func (k *Kokizami) Get(id int) (*Kizami, error) {
 	return k.KizamiRepo.FindByID(id)
 }
 
-// Edit edits a specified kizami and update its model
+// Edit edits a specified kizami and update its model.
+// Tags are extracted again from desc if desc is changed.
 func (k *Kokizami) Edit(ki *Kizami) (*Kizami, error) {
+	if ki.StoppedAt.Unix() != 0 && ki.StoppedAt.Before(ki.StartedAt) {
+		return nil, invalidf("stopped_at (%v) must not be before started_at (%v)", ki.StoppedAt, ki.StartedAt)
+	}
+
 	m, err := k.KizamiRepo.FindByID(ki.ID)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := validateSegmentBounds(m, ki.StartedAt, ki.StoppedAt); err != nil {
+		return nil, err
+	}
+
+	descChanged := m.Desc != ki.Desc
 	m.Desc = ki.Desc
 	m.StartedAt = ki.StartedAt.UTC()
 	m.StoppedAt = ki.StoppedAt.UTC()
@@ -57,7 +145,57 @@ func (k *Kokizami) Edit(ki *Kizami) (*Kizami, error) {
 		return nil, err
 	}
 
-	return k.KizamiRepo.FindByID(ki.ID)
+	// keep the outer bounds of segments in sync with edited kizami.
+	// the last segment is reopened if the kizami is made on-going.
+	if n := len(m.Segments); n != 0 {
+		m.Segments[0].StartedAt = m.StartedAt
+		if m.StoppedAt.Unix() != 0 {
+			m.Segments[n-1].StoppedAt = m.StoppedAt
+		} else {
+			m.Segments[n-1].StoppedAt = initialTime()
+		}
+		for _, i := range []int{0, n - 1} {
+			if err := k.KizamiRepo.UpdateSegment(&m.Segments[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if descChanged {
+		if err := k.tagByDesc(m.ID, m.Desc); err != nil {
+			return nil, err
+		}
+	}
+
+	ret, err := k.KizamiRepo.FindByID(ki.ID)
+	if err != nil {
+		return nil, err
+	}
+	k.publish(EventEdited, ret.ID, ret)
+	return ret, nil
+}
+
+// validateSegmentBounds returns error if specified started_at and stopped_at
+// of a kizami make its first or last segment negative length.
+// Only the outer bounds of segments follow an edit, and the others are kept.
+func validateSegmentBounds(ki *Kizami, startedAt, stoppedAt time.Time) error {
+	n := len(ki.Segments)
+	if n == 0 {
+		return nil
+	}
+
+	first, last := ki.Segments[0], ki.Segments[n-1]
+	if n == 1 {
+		// both bounds of the only segment are edited
+		return nil
+	}
+	if first.StoppedAt.Unix() != 0 && startedAt.After(first.StoppedAt) {
+		return invalidf("started_at (%v) must not be after the first worked segment stopped at (%v)", startedAt.UTC(), first.StoppedAt.UTC())
+	}
+	if n > 1 && stoppedAt.Unix() != 0 && stoppedAt.Before(last.StartedAt) {
+		return invalidf("stopped_at (%v) must not be before the last worked segment started at (%v)", stoppedAt.UTC(), last.StartedAt.UTC())
+	}
+	return nil
 }
 
 // Stop stops a on-going kizami by specified ID
@@ -66,8 +204,74 @@ func (k *Kokizami) Stop(id int) error {
 	if err != nil {
 		return err
 	}
-	ki.StoppedAt = k.now().UTC()
-	return k.KizamiRepo.Update(ki)
+	return k.stop(ki, k.clock().UTC())
+}
+
+// StopAt stops a on-going kizami by specified ID at specified time.
+// The time must not be in the future nor before the kizami started.
+// A paused kizami is stopped at the time it was paused, and the time
+// must not be before that.
+func (k *Kokizami) StopAt(id int, t time.Time) error {
+	if err := k.validatePast(t); err != nil {
+		return err
+	}
+
+	ki, err := k.KizamiRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := validateStopAt(ki, t); err != nil {
+		return err
+	}
+
+	return k.stop(ki, t.UTC())
+}
+
+// StopAllAt stops all on-going kizamis at specified time.
+// The time must not be in the future nor before any of them started.
+// Paused kizamis are stopped at the time they were paused as StopAt.
+func (k *Kokizami) StopAllAt(t time.Time) error {
+	if err := k.validatePast(t); err != nil {
+		return err
+	}
+
+	ks, err := k.KizamiRepo.FindByStoppedAt(initialTime())
+	if err != nil {
+		return err
+	}
+
+	for i := range ks {
+		if err := validateStopAt(ks[i], t); err != nil {
+			return err
+		}
+	}
+
+	for i := range ks {
+		if err := k.stop(ks[i], t.UTC()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateStopAt returns error if specified kizami can not be stopped at specified time.
+// A paused kizami can not be stopped before it was paused.
+func validateStopAt(ki *Kizami, t time.Time) error {
+	startedAt := ki.StartedAt
+	if n := len(ki.Segments); n != 0 {
+		if ki.IsPaused() {
+			if pausedAt := ki.Segments[n-1].StoppedAt; t.Before(pausedAt) {
+				return invalidf("kizami [%d] can not be stopped at %v, before it was paused at %v", ki.ID, t.UTC(), pausedAt.UTC())
+			}
+			return nil
+		}
+		startedAt = ki.Segments[n-1].StartedAt
+	}
+	if t.Before(startedAt) {
+		return invalidf("kizami [%d] can not be stopped at %v, before it started at %v", ki.ID, t.UTC(), startedAt.UTC())
+	}
+	return nil
 }
 
 // StopAll stops all on-going kizamis
@@ -76,23 +280,135 @@ func (k *Kokizami) StopAll() error {
 	if err != nil {
 		return err
 	}
-	now := k.now().UTC()
+	now := k.clock().UTC()
+	for i := range ks {
+		if err := k.stop(ks[i], now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stop stops specified kizami at specified time.
+// A paused kizami is stopped at the time it was paused.
+func (k *Kokizami) stop(ki *Kizami, t time.Time) error {
+	ki.StoppedAt = t
+	var open *Segment
+	if n := len(ki.Segments); n != 0 {
+		last := &ki.Segments[n-1]
+		if last.StoppedAt.Unix() == 0 {
+			last.StoppedAt = t
+			open = last
+		}
+		ki.StoppedAt = last.StoppedAt
+	}
+
+	// the kizami is updated before its open segment, so that
+	// the store never has a paused kizami on the way of stopping
+	if err := k.KizamiRepo.Update(ki); err != nil {
+		return err
+	}
+	if open != nil {
+		if err := k.KizamiRepo.UpdateSegment(open); err != nil {
+			return err
+		}
+	}
+	k.publish(EventStopped, ki.ID, ki)
+	return nil
+}
+
+// Pause pauses a on-going kizami by specified ID.
+// Paused kizami can be resumed by Resume and keeps its ID.
+func (k *Kokizami) Pause(id int) error {
+	ki, err := k.KizamiRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	return k.pause(ki, k.clock().UTC())
+}
+
+// PauseAll pauses all on-going kizamis
+func (k *Kokizami) PauseAll() error {
+	ks, err := k.KizamiRepo.FindByStoppedAt(initialTime())
+	if err != nil {
+		return err
+	}
+	now := k.clock().UTC()
 	for i := range ks {
-		ks[i].StoppedAt = now
-		if err := k.KizamiRepo.Update(ks[i]); err != nil {
+		if !ks[i].IsRunning() {
+			continue
+		}
+		if err := k.pause(ks[i], now); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+func (k *Kokizami) pause(ki *Kizami, t time.Time) error {
+	if !ki.IsRunning() {
+		return conflictf("kizami [%d] is not running", ki.ID)
+	}
+
+	var err error
+	if n := len(ki.Segments); n == 0 {
+		// first pause. the worked interval so far becomes the first segment
+		err = k.KizamiRepo.InsertSegment(&Segment{
+			KizamiID:  ki.ID,
+			StartedAt: ki.StartedAt,
+			StoppedAt: t,
+		})
+	} else {
+		ki.Segments[n-1].StoppedAt = t
+		err = k.KizamiRepo.UpdateSegment(&ki.Segments[n-1])
+	}
+	if err != nil {
+		return err
+	}
+
+	k.publish(EventPaused, ki.ID, nil)
+	return nil
+}
+
+// Resume resumes a paused kizami by specified ID
+func (k *Kokizami) Resume(id int) error {
+	ki, err := k.KizamiRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+
+	if !ki.IsPaused() {
+		return conflictf("kizami [%d] is not paused", id)
+	}
+
+	err = k.KizamiRepo.InsertSegment(&Segment{
+		KizamiID:  ki.ID,
+		StartedAt: k.clock().UTC(),
+		StoppedAt: initialTime(),
+	})
+	if err != nil {
+		return err
+	}
+
+	k.publish(EventResumed, ki.ID, nil)
+	return nil
+}
+
 // Delete deletes a kizami by specified ID
 func (k *Kokizami) Delete(id int) error {
 	ki, err := k.KizamiRepo.FindByID(id)
 	if err != nil {
 		return err
 	}
-	return k.KizamiRepo.Delete(ki)
+
+	// tags are fetched before they are gone with the kizami
+	e := k.event(EventDeleted, ki.ID, ki)
+	err = k.KizamiRepo.Delete(ki)
+	if err != nil {
+		return err
+	}
+	k.events.publish(e)
+	return nil
 }
 
 // List returns all Kizamis
@@ -100,26 +416,118 @@ func (k *Kokizami) List() ([]*Kizami, error) {
 	return k.KizamiRepo.FindAll()
 }
 
+// ListByFilter returns Kizamis that match specified filter
+func (k *Kokizami) ListByFilter(f *KizamiFilter) ([]*Kizami, error) {
+	if !f.Since.IsZero() && !f.Until.IsZero() && !f.Since.Before(f.Until) {
+		return nil, invalidf("invalid range. since (%v) must be before until (%v)", f.Since.UTC(), f.Until.UTC())
+	}
+	if f.Limit < 0 || f.Offset < 0 {
+		return nil, invalidf("limit and offset must not be negative")
+	}
+
+	return k.KizamiRepo.FindByFilter(f)
+}
+
 // SummaryByTag returns total elapsed time of Kizamis in specified month grouped by tag
-func (k *Kokizami) SummaryByTag(yyyymm string) ([]*Elapsed, error) {
-	// validate input
-	_, err := time.Parse("2006-01", yyyymm)
+func (k *Kokizami) SummaryByTag(yyyymm string, opts ...SummaryOption) ([]*Elapsed, error) {
+	from, to, err := monthRange(yyyymm, k.location())
 	if err != nil {
-		return nil, fmt.Errorf("invalid argument format. should be yyyy-mm: %v", err)
+		return nil, err
 	}
 
-	return k.SummaryRepo.ElapsedOfMonthByTag(yyyymm)
+	return k.SummaryByTagBetween(from, to, opts...)
 }
 
 // SummaryByDesc returns total elapsed time of Kizamis in specified month grouped by desc
-func (k *Kokizami) SummaryByDesc(yyyymm string) ([]*Elapsed, error) {
+func (k *Kokizami) SummaryByDesc(yyyymm string, opts ...SummaryOption) ([]*Elapsed, error) {
+	from, to, err := monthRange(yyyymm, k.location())
+	if err != nil {
+		return nil, err
+	}
+
+	return k.SummaryByDescBetween(from, to, opts...)
+}
+
+// SummaryByTagBetween returns total elapsed time of Kizamis
+// in specified range [from, to) grouped by tag
+func (k *Kokizami) SummaryByTagBetween(from, to time.Time, opts ...SummaryOption) ([]*Elapsed, error) {
+	q, err := k.summaryQuery(from, to, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return k.SummaryRepo.ElapsedByTag(q)
+}
+
+// SummaryByDescBetween returns total elapsed time of Kizamis
+// in specified range [from, to) grouped by desc
+func (k *Kokizami) SummaryByDescBetween(from, to time.Time, opts ...SummaryOption) ([]*Elapsed, error) {
+	q, err := k.summaryQuery(from, to, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return k.SummaryRepo.ElapsedByDesc(q)
+}
+
+// DailySummaryBetween returns total elapsed time of Kizamis in each day
+// of specified range [from, to). days are split in Location, and
+// a Kizami that has multiple tags is counted once in a day.
+func (k *Kokizami) DailySummaryBetween(from, to time.Time, opts ...SummaryOption) ([]*DailyElapsed, error) {
+	q, err := k.summaryQuery(from, to, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := k.location()
+	var days []time.Time
+	for day := from.In(loc); day.Before(to); {
+		days = append(days, day)
+		y, m, d := day.Date()
+		day = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+	}
+
+	ret, err := k.SummaryRepo.ElapsedOfDays(q, days)
+	if err != nil {
+		return nil, err
+	}
+	if len(ret) != len(days) {
+		return nil, fmt.Errorf("unexpected number of days: [got] %d [want] %d", len(ret), len(days))
+	}
+
+	// dates are in location, not as returned from the repository
+	for i := range ret {
+		ret[i].Date = days[i]
+	}
+	return ret, nil
+}
+
+func (k *Kokizami) summaryQuery(from, to time.Time, opts []SummaryOption) (*SummaryQuery, error) {
+	if !from.Before(to) {
+		return nil, invalidf("invalid range. from (%v) must be before to (%v)", from, to)
+	}
+
+	q := &SummaryQuery{
+		From: from.UTC(),
+		To:   to.UTC(),
+		Now:  k.clock().UTC(),
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	return q, nil
+}
+
+// monthRange returns the range of specified month in specified location
+func monthRange(yyyymm string, loc *time.Location) (time.Time, time.Time, error) {
 	// validate input
-	_, err := time.Parse("2006-01", yyyymm)
+	from, err := time.ParseInLocation("2006-01", yyyymm, loc)
 	if err != nil {
-		return nil, fmt.Errorf("invalid argument format. should be yyyy-mm: %v", err)
+		return time.Time{}, time.Time{}, invalidf("invalid argument format. should be yyyy-mm: %v", err)
 	}
 
-	return k.SummaryRepo.ElapsedOfMonthByDesc(yyyymm)
+	return from, from.AddDate(0, 1, 0), nil
 }
 
 // AddTags adds a new tags
@@ -155,12 +563,64 @@ func (k *Kokizami) Tags() ([]*Tag, error) {
 
 // Tagging makes relation between specified kizami and tags
 func (k *Kokizami) Tagging(kizamiID int, tagIDs []int) error {
-	return k.KizamiRepo.Tagging(kizamiID, tagIDs)
+	err := k.KizamiRepo.Tagging(kizamiID, tagIDs)
+	if err != nil {
+		return err
+	}
+	k.publish(EventTagged, kizamiID, nil)
+	return nil
 }
 
 // Untagging removes all tags from specified kizami
 func (k *Kokizami) Untagging(kizamiID int) error {
-	return k.KizamiRepo.Untagging(kizamiID)
+	err := k.KizamiRepo.Untagging(kizamiID)
+	if err != nil {
+		return err
+	}
+	k.publish(EventTagged, kizamiID, nil)
+	return nil
+}
+
+// TagByDesc replaces tags of specified kizami with tags written in desc.
+// Missing tags are added.
+func (k *Kokizami) TagByDesc(kizamiID int, desc string) error {
+	err := k.tagByDesc(kizamiID, desc)
+	if err != nil {
+		return err
+	}
+	k.publish(EventTagged, kizamiID, nil)
+	return nil
+}
+
+// tagByDesc is TagByDesc without notifying the change
+func (k *Kokizami) tagByDesc(kizamiID int, desc string) error {
+	// remove all tags from specified kizami first.
+	// repositories are used directly to notify the change only once.
+	err := k.KizamiRepo.Untagging(kizamiID)
+	if err != nil {
+		return err
+	}
+
+	tags := ExtractTags(desc)
+	if len(tags) == 0 {
+		return nil
+	}
+
+	err = k.AddTags(tags)
+	if err != nil {
+		return err
+	}
+
+	ts, err := k.TagsByLabels(tags)
+	if err != nil {
+		return err
+	}
+	tagIDs := make([]int, len(ts))
+	for i, v := range ts {
+		tagIDs[i] = v.ID
+	}
+
+	return k.KizamiRepo.Tagging(kizamiID, tagIDs)
 }
 
 // TagsByKizamiID returns tags of specified kizami
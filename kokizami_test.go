@@ -1,8 +1,11 @@
 package kokizami
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -24,7 +27,9 @@ type mockTagRepo struct {
 	repo *mockRepo
 }
 
-type mockSummaryRepo struct{}
+type mockSummaryRepo struct {
+	query *SummaryQuery
+}
 
 func (m *mockKizamiRepo) FindAll() ([]*Kizami, error) {
 	ks := make([]Kizami, len(m.repo.kizamis))
@@ -54,6 +59,18 @@ func (m *mockKizamiRepo) Insert(desc string) (*Kizami, error) {
 	return k, nil
 }
 
+func (m *mockKizamiRepo) InsertWithTimes(desc string, startedAt, stoppedAt time.Time) (*Kizami, error) {
+	id := len(m.repo.kizamis) + 1
+	k := &Kizami{
+		ID:        id,
+		Desc:      desc,
+		StartedAt: startedAt,
+		StoppedAt: stoppedAt,
+	}
+	m.repo.kizamis[strconv.Itoa(id)] = k
+	return k, nil
+}
+
 func (m *mockKizamiRepo) Update(k *Kizami) error {
 	m.repo.kizamis[strconv.Itoa(k.ID)] = k
 	return nil
@@ -81,6 +98,49 @@ func (m *mockKizamiRepo) FindByStoppedAt(t time.Time) ([]*Kizami, error) {
 	return ret, nil
 }
 
+func (m *mockKizamiRepo) FindByFilter(f *KizamiFilter) ([]*Kizami, error) {
+	ret := []*Kizami{}
+	for _, v := range m.repo.kizamis {
+		if !f.Since.IsZero() && v.StartedAt.Before(f.Since) {
+			continue
+		}
+		if !f.Until.IsZero() && !v.StartedAt.Before(f.Until) {
+			continue
+		}
+		if !strings.Contains(v.Desc, f.Desc) {
+			continue
+		}
+		if f.Running && v.StoppedAt != initialTime() {
+			continue
+		}
+		ret = append(ret, v)
+	}
+
+	// newest first
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].StartedAt.Equal(ret[j].StartedAt) {
+			return ret[i].ID > ret[j].ID
+		}
+		return ret[i].StartedAt.After(ret[j].StartedAt)
+	})
+
+	if f.Offset > len(ret) {
+		f.Offset = len(ret)
+	}
+	ret = ret[f.Offset:]
+	if f.Limit > 0 && f.Limit < len(ret) {
+		ret = ret[:f.Limit]
+	}
+
+	if !f.Reverse {
+		for i, j := 0, len(ret)-1; i < j; i, j = i+1, j-1 {
+			ret[i], ret[j] = ret[j], ret[i]
+		}
+	}
+
+	return ret, nil
+}
+
 func (m *mockKizamiRepo) Tagging(kizamiID int, tagIDs []int) error {
 	m.repo.relation[kizamiID] = tagIDs
 	return nil
@@ -91,6 +151,30 @@ func (m *mockKizamiRepo) Untagging(kizamiID int) error {
 	return nil
 }
 
+func (m *mockKizamiRepo) InsertSegment(s *Segment) error {
+	k, ok := m.repo.kizamis[strconv.Itoa(s.KizamiID)]
+	if !ok {
+		return fmt.Errorf("Kizami that has id [%d] is not found", s.KizamiID)
+	}
+	s.ID = len(k.Segments) + 1
+	k.Segments = append(k.Segments, *s)
+	return nil
+}
+
+func (m *mockKizamiRepo) UpdateSegment(s *Segment) error {
+	k, ok := m.repo.kizamis[strconv.Itoa(s.KizamiID)]
+	if !ok {
+		return fmt.Errorf("Kizami that has id [%d] is not found", s.KizamiID)
+	}
+	for i := range k.Segments {
+		if k.Segments[i].ID == s.ID {
+			k.Segments[i] = *s
+			return nil
+		}
+	}
+	return fmt.Errorf("Segment that has id [%d] is not found", s.ID)
+}
+
 func (m *mockTagRepo) FindByID(id int) (*Tag, error) {
 	panic("not implemented")
 }
@@ -154,14 +238,25 @@ func (m *mockTagRepo) Delete(id int) error {
 	return nil
 }
 
-func (m *mockSummaryRepo) ElapsedOfMonthByDesc(yyyymm string) ([]*Elapsed, error) {
+func (m *mockSummaryRepo) ElapsedByDesc(q *SummaryQuery) ([]*Elapsed, error) {
+	m.query = q
 	return nil, nil
 }
 
-func (m *mockSummaryRepo) ElapsedOfMonthByTag(yyyymm string) ([]*Elapsed, error) {
+func (m *mockSummaryRepo) ElapsedByTag(q *SummaryQuery) ([]*Elapsed, error) {
+	m.query = q
 	return nil, nil
 }
 
+func (m *mockSummaryRepo) ElapsedOfDays(q *SummaryQuery, days []time.Time) ([]*DailyElapsed, error) {
+	m.query = q
+	ret := make([]*DailyElapsed, len(days))
+	for i := range days {
+		ret[i] = &DailyElapsed{Date: days[i]}
+	}
+	return ret, nil
+}
+
 func setup() *Kokizami {
 	mockNow := time.Now()
 	repo := &mockRepo{
@@ -340,6 +435,66 @@ func TestEdit(t *testing.T) {
 	}
 }
 
+func TestEditRetag(t *testing.T) {
+	k := setup()
+
+	ki, err := k.Start("hoge #a")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if err = k.TagByDesc(ki.ID, ki.Desc); err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	tcs := []struct {
+		inDesc   string
+		wantTags []string
+	}{
+		{inDesc: "hoge #b #c", wantTags: []string{"#b", "#c"}},
+		{inDesc: "hoge", wantTags: []string{}},
+	}
+
+	for i, tc := range tcs {
+		edited := *ki
+		edited.Desc = tc.inDesc
+		if _, err := k.Edit(&edited); err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+
+		ts, err := k.TagsByKizamiID(ki.ID)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		labels := []string{}
+		for _, v := range ts {
+			labels = append(labels, v.Label)
+		}
+		sort.Strings(labels)
+		if diff := cmp.Diff(labels, tc.wantTags); diff != "" {
+			t.Errorf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+}
+
+func TestEditInvalidTime(t *testing.T) {
+	k := setup()
+
+	ki, err := k.Start("hoge")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	_, err = k.Edit(&Kizami{
+		ID:        ki.ID,
+		Desc:      "hoge",
+		StartedAt: k.now(),
+		StoppedAt: k.now().Add(-1 * time.Minute),
+	})
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+}
+
 func TestStop(t *testing.T) {
 	k := setup()
 
@@ -453,6 +608,233 @@ func TestStopAll(t *testing.T) {
 	}
 }
 
+func TestPauseResume(t *testing.T) {
+	k := setup()
+	now := k.now()
+	k.now = func() time.Time { return now }
+
+	ki, err := k.Start("hoge")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	err = k.Resume(ki.ID)
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+
+	// work 1h, pause 30m, work 2h and then stop
+	now = now.Add(1 * time.Hour)
+	err = k.Pause(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	err = k.Pause(ki.ID)
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+
+	ret, err := k.Get(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if !ret.IsPaused() || ret.IsRunning() {
+		t.Fatalf("unexpected result: kizami should be paused")
+	}
+	if ret.Elapsed() != 1*time.Hour {
+		t.Fatalf("unexpected result: [got] %v [want] %v", ret.Elapsed(), 1*time.Hour)
+	}
+
+	now = now.Add(30 * time.Minute)
+	err = k.Resume(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	err = k.Stop(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	ret, err = k.Get(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if ret.ID != ki.ID || len(ret.Segments) != 2 {
+		t.Fatalf("unexpected result: [got] %v [want] 2 segments of kizami %d", ret, ki.ID)
+	}
+	if !ret.StoppedAt.Equal(now.UTC()) {
+		t.Fatalf("unexpected result: [got] %v [want] %v", ret.StoppedAt, now.UTC())
+	}
+	if ret.Elapsed() != 3*time.Hour {
+		t.Fatalf("unexpected result: [got] %v [want] %v", ret.Elapsed(), 3*time.Hour)
+	}
+}
+
+func TestStopPaused(t *testing.T) {
+	k := setup()
+	now := k.now()
+	k.now = func() time.Time { return now }
+
+	ki, err := k.Start("hoge")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	now = now.Add(1 * time.Hour)
+	err = k.PauseAll()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	pausedAt := now.UTC()
+
+	// stopping paused kizami stops it at the time it was paused
+	now = now.Add(1 * time.Hour)
+	err = k.StopAll()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	ret, err := k.Get(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if !ret.StoppedAt.Equal(pausedAt) {
+		t.Fatalf("unexpected result: [got] %v [want] %v", ret.StoppedAt, pausedAt)
+	}
+	if ret.Elapsed() != 1*time.Hour {
+		t.Fatalf("unexpected result: [got] %v [want] %v", ret.Elapsed(), 1*time.Hour)
+	}
+}
+
+func TestStopAtPaused(t *testing.T) {
+	k := setup()
+	now := k.now()
+	k.now = func() time.Time { return now }
+
+	ki, err := k.Start("hoge")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	now = now.Add(1 * time.Hour)
+	err = k.Pause(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	pausedAt := now.UTC()
+	now = now.Add(1 * time.Hour)
+
+	// a paused kizami can not be stopped before it was paused
+	for i, f := range []func() error{
+		func() error { return k.StopAt(ki.ID, pausedAt.Add(-1*time.Minute)) },
+		func() error { return k.StopAllAt(pausedAt.Add(-1 * time.Minute)) },
+	} {
+		if err := f(); !errors.Is(err, ErrInvalid) {
+			t.Errorf("[No.%d] unexpected result: [got] %v [want] %v", i, err, ErrInvalid)
+		}
+	}
+
+	// and it is stopped at the time it was paused
+	err = k.StopAt(ki.ID, pausedAt.Add(30*time.Minute))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	ret, err := k.Get(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if !ret.StoppedAt.Equal(pausedAt) {
+		t.Fatalf("unexpected result: [got] %v [want] %v", ret.StoppedAt, pausedAt)
+	}
+}
+
+func TestEditSegments(t *testing.T) {
+	k := setup()
+	now := k.now()
+	k.now = func() time.Time { return now }
+	startedAt := now.UTC()
+
+	// work 1h, pause 1h, work 1h and then stop
+	ki, err := k.Start("hoge")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	now = now.Add(1 * time.Hour)
+	if err = k.Pause(ki.ID); err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	now = now.Add(1 * time.Hour)
+	if err = k.Resume(ki.ID); err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	now = now.Add(1 * time.Hour)
+	if err = k.Stop(ki.ID); err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	stoppedAt := now.UTC()
+
+	tcs := []struct {
+		startedAt time.Time
+		stoppedAt time.Time
+		want      error
+		elapsed   time.Duration
+	}{
+		{
+			// the first segment gets shorter
+			startedAt: startedAt.Add(30 * time.Minute),
+			stoppedAt: stoppedAt,
+			elapsed:   90 * time.Minute,
+		},
+		{
+			// the last segment gets shorter
+			startedAt: startedAt,
+			stoppedAt: stoppedAt.Add(-30 * time.Minute),
+			elapsed:   90 * time.Minute,
+		},
+		{
+			startedAt: startedAt.Add(90 * time.Minute),
+			stoppedAt: stoppedAt,
+			want:      ErrInvalid,
+		},
+		{
+			startedAt: startedAt,
+			stoppedAt: stoppedAt.Add(-90 * time.Minute),
+			want:      ErrInvalid,
+		},
+	}
+
+	for i, tc := range tcs {
+		ret, err := k.Edit(&Kizami{ID: ki.ID, Desc: "hoge", StartedAt: tc.startedAt, StoppedAt: tc.stoppedAt})
+		if !errors.Is(err, tc.want) {
+			t.Errorf("[No.%d] unexpected result: [got] %v [want] %v", i, err, tc.want)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if ret.Elapsed() != tc.elapsed {
+			t.Errorf("[No.%d] unexpected result: [got] %v [want] %v", i, ret.Elapsed(), tc.elapsed)
+		}
+		// restore for the next case
+		_, err = k.Edit(&Kizami{ID: ki.ID, Desc: "hoge", StartedAt: startedAt, StoppedAt: stoppedAt})
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+	}
+
+	// the last segment is reopened if the kizami is made on-going
+	ret, err := k.Edit(&Kizami{ID: ki.ID, Desc: "hoge", StartedAt: startedAt, StoppedAt: initialTime()})
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if !ret.IsRunning() || len(ret.Segments) != 2 {
+		t.Errorf("unexpected result: [got] %+v [want] running kizami of 2 segments", ret)
+	}
+}
+
 func TestDelete(t *testing.T) {
 	k := setup()
 
@@ -646,3 +1028,425 @@ func TestSummaryByDesc(t *testing.T) {
 		t.Fatalf("unexpected result: [got] %v [want] nil", err)
 	}
 }
+
+func TestSummaryByTagBetween(t *testing.T) {
+	k := setup()
+
+	from := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+
+	_, err := k.SummaryByTagBetween(from, to)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	m := k.SummaryRepo.(*mockSummaryRepo)
+	if !m.query.From.Equal(from) || !m.query.To.Equal(to) || m.query.Prorate {
+		t.Fatalf("unexpected result: [got] %+v [want] %v - %v", m.query, from, to)
+	}
+
+	_, err = k.SummaryByTagBetween(from, to, WithProrate(true))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	if !m.query.Prorate {
+		t.Fatalf("unexpected result: [got] %+v [want] prorated query", m.query)
+	}
+
+	_, err = k.SummaryByTagBetween(from, to, WithRunning(true))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	// elapsed time of on-going kizamis is counted until now of injected clock
+	if !m.query.IncludeRunning || !m.query.Now.Equal(k.now()) {
+		t.Fatalf("unexpected result: [got] %+v [want] query including running until %v", m.query, k.now())
+	}
+
+	_, err = k.SummaryByTagBetween(to, from)
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+}
+
+// dailySummaryRepo records days and returns an hour of each of them in UTC
+type dailySummaryRepo struct {
+	mockSummaryRepo
+	days []time.Time
+}
+
+func (m *dailySummaryRepo) ElapsedOfDays(q *SummaryQuery, days []time.Time) ([]*DailyElapsed, error) {
+	m.query = q
+	m.days = days
+	ret := make([]*DailyElapsed, len(days))
+	for i := range days {
+		ret[i] = &DailyElapsed{Date: days[i].UTC(), Elapsed: time.Hour, Running: i == 0}
+	}
+	return ret, nil
+}
+
+func TestDailySummaryBetween(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	k := setup()
+	k.Location = jst
+	m := &dailySummaryRepo{}
+	k.SummaryRepo = m
+
+	from := time.Date(2024, 3, 1, 12, 0, 0, 0, jst)
+	to := time.Date(2024, 3, 3, 0, 0, 0, 0, jst)
+	ret, err := k.DailySummaryBetween(from.UTC(), to.UTC(), WithProrate(true))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	// days are split in location, and dates are in location
+	want := []*DailyElapsed{
+		{Date: from, Elapsed: time.Hour, Running: true},
+		{Date: time.Date(2024, 3, 2, 0, 0, 0, 0, jst), Elapsed: time.Hour},
+	}
+	if diff := cmp.Diff(ret, want); diff != "" {
+		t.Fatalf("unexpected result (-got +want):\n%s", diff)
+	}
+	if len(m.days) != 2 || !m.query.From.Equal(from) || !m.query.To.Equal(to) || !m.query.Prorate {
+		t.Fatalf("unexpected result: [got] %+v %v [want] query of the range and each day", m.query, m.days)
+	}
+
+	_, err = k.DailySummaryBetween(to, from)
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+}
+
+func TestSummaryByDescMonthRange(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+
+	tcs := []struct {
+		inLocation *time.Location
+		wantFrom   time.Time
+		wantTo     time.Time
+	}{
+		{
+			inLocation: time.UTC,
+			wantFrom:   time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC),
+			wantTo:     time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// beginning of the month in JST is 15:00 of the previous day in UTC
+			inLocation: jst,
+			wantFrom:   time.Date(2019, 11, 30, 15, 0, 0, 0, time.UTC),
+			wantTo:     time.Date(2019, 12, 31, 15, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for i, tc := range tcs {
+		k := setup()
+		k.Location = tc.inLocation
+
+		_, err := k.SummaryByDesc("2019-12")
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+
+		m := k.SummaryRepo.(*mockSummaryRepo)
+		if !m.query.From.Equal(tc.wantFrom) || !m.query.To.Equal(tc.wantTo) {
+			t.Fatalf("[No.%d] unexpected result: [got] %v - %v [want] %v - %v", i, m.query.From, m.query.To, tc.wantFrom, tc.wantTo)
+		}
+	}
+
+	k := setup()
+	_, err := k.SummaryByDesc("2019/12")
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+}
+
+func TestStartAtStopAt(t *testing.T) {
+	k := setup()
+	now := k.now()
+
+	_, err := k.StartAt("hoge", now.Add(1*time.Minute))
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+
+	ki, err := k.StartAt("hoge", now.Add(-1*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if !ki.StartedAt.Equal(now.Add(-1 * time.Hour)) {
+		t.Fatalf("unexpected result: [got] %v [want] %v", ki.StartedAt, now.Add(-1*time.Hour))
+	}
+
+	tcs := []struct {
+		inTime  time.Time
+		wantErr bool
+	}{
+		{
+			// future
+			inTime:  now.Add(1 * time.Minute),
+			wantErr: true,
+		},
+		{
+			// before started
+			inTime:  now.Add(-2 * time.Hour),
+			wantErr: true,
+		},
+		{
+			inTime:  now.Add(-30 * time.Minute),
+			wantErr: false,
+		},
+	}
+
+	for i, tc := range tcs {
+		err = k.StopAt(ki.ID, tc.inTime)
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("[No.%d] unexpected result: [got] nil [want] some error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+
+		ret, err := k.Get(ki.ID)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if ret.Elapsed() != 30*time.Minute {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] %v", i, ret.Elapsed(), 30*time.Minute)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	k := setup()
+	now := k.now()
+
+	tcs := []struct {
+		inDesc      string
+		inStartedAt time.Time
+		inStoppedAt time.Time
+		wantErr     bool
+	}{
+		{
+			inDesc:      "",
+			inStartedAt: now.Add(-1 * time.Hour),
+			inStoppedAt: now,
+			wantErr:     true,
+		},
+		{
+			// stopped before started
+			inDesc:      "hoge",
+			inStartedAt: now,
+			inStoppedAt: now.Add(-1 * time.Hour),
+			wantErr:     true,
+		},
+		{
+			// stopped in the future
+			inDesc:      "hoge",
+			inStartedAt: now,
+			inStoppedAt: now.Add(1 * time.Hour),
+			wantErr:     true,
+		},
+		{
+			inDesc:      "hoge",
+			inStartedAt: now.Add(-90 * time.Minute),
+			inStoppedAt: now,
+			wantErr:     false,
+		},
+	}
+
+	for i, tc := range tcs {
+		ret, err := k.Add(tc.inDesc, tc.inStartedAt, tc.inStoppedAt)
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("[No.%d] unexpected result: [got] nil [want] some error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+
+		if ret.IsRunning() || ret.Elapsed() != 90*time.Minute {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] finished kizami of %v", i, ret, 90*time.Minute)
+		}
+	}
+}
+
+func TestListByFilter(t *testing.T) {
+	k := setup()
+	now := k.now()
+
+	for i := 0; i < 5; i++ {
+		_, err := k.Add(fmt.Sprintf("task %d", i), now.Add(time.Duration(i-10)*time.Hour), now.Add(time.Duration(i-10)*time.Hour+time.Minute))
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+	}
+
+	tcs := []struct {
+		inFilter *KizamiFilter
+		wantIDs  []int
+		wantErr  bool
+	}{
+		{
+			inFilter: &KizamiFilter{},
+			wantIDs:  []int{1, 2, 3, 4, 5},
+		},
+		{
+			// last 2 kizamis
+			inFilter: &KizamiFilter{Limit: 2},
+			wantIDs:  []int{4, 5},
+		},
+		{
+			inFilter: &KizamiFilter{Limit: 2, Offset: 1, Reverse: true},
+			wantIDs:  []int{4, 3},
+		},
+		{
+			inFilter: &KizamiFilter{Since: now.Add(-9 * time.Hour), Until: now.Add(-7 * time.Hour)},
+			wantIDs:  []int{2, 3},
+		},
+		{
+			inFilter: &KizamiFilter{Since: now, Until: now.Add(-1 * time.Hour)},
+			wantErr:  true,
+		},
+		{
+			inFilter: &KizamiFilter{Limit: -1},
+			wantErr:  true,
+		},
+	}
+
+	for i, tc := range tcs {
+		ret, err := k.ListByFilter(tc.inFilter)
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("[No.%d] unexpected result: [got] nil [want] some error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+
+		ids := make([]int, len(ret))
+		for j := range ret {
+			ids[j] = ret[j].ID
+		}
+		if diff := cmp.Diff(ids, tc.wantIDs); diff != "" {
+			t.Fatalf("[No.%d] unexpected result: (-got +want) %s", i, diff)
+		}
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	k := setup()
+
+	ki, err := k.Start("foo")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = k.Stop(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	now := k.clock()
+	_, errStart := k.Start("")
+	_, errAdd := k.Add("foo", now, now.Add(-time.Hour))
+	_, errList := k.ListByFilter(&KizamiFilter{Limit: -1})
+	_, errMonth := k.SummaryByTag("2019/05")
+
+	tcs := []struct {
+		in   error
+		want error
+	}{
+		{in: errStart, want: ErrInvalid},
+		{in: errAdd, want: ErrInvalid},
+		{in: errList, want: ErrInvalid},
+		{in: errMonth, want: ErrInvalid},
+		{in: k.StopAt(ki.ID, now.Add(time.Hour)), want: ErrInvalid},
+		{in: k.Pause(ki.ID), want: ErrConflict},
+		{in: k.Resume(ki.ID), want: ErrConflict},
+	}
+
+	for i, tc := range tcs {
+		if !errors.Is(tc.in, tc.want) {
+			t.Errorf("[No.%d] unexpected result: [got] %v [want] %v", i, tc.in, tc.want)
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	k := setup()
+
+	// no events are kept before subscribing
+	_, err := k.Start("before")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	events, cancel := k.Subscribe()
+
+	ki, err := k.Start("hoge #foo")
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = k.TagByDesc(ki.ID, ki.Desc)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = k.Pause(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = k.Resume(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	// failed operations are not notified
+	err = k.Resume(ki.ID)
+	if err == nil {
+		t.Fatalf("unexpected result: [got] nil [want] some error")
+	}
+	err = k.StopAll()
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	ki.Desc = "fuga"
+	_, err = k.Edit(ki)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	err = k.Delete(ki.ID)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	cancel()
+	// canceling twice is safe
+	cancel()
+
+	var got []string
+	for e := range events {
+		got = append(got, fmt.Sprintf("%s %d %s %d", e.Type, e.Kizami.ID, e.Kizami.Desc, len(e.Tags)))
+	}
+	want := []string{
+		"started 2 hoge #foo 0",
+		"tagged 2 hoge #foo 1",
+		"paused 2 hoge #foo 1",
+		"resumed 2 hoge #foo 1",
+		// "before" is stopped too
+		"stopped 1 before 0",
+		"stopped 2 hoge #foo 1",
+		"edited 2 fuga 1",
+		"deleted 2 fuga 1",
+	}
+	// order of stopping on-going kizamis is undefined
+	sort.Strings(got[4:6])
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("unexpected result: (-got +want) %s", diff)
+	}
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// AllRelations returns all relations ordered by id
+func AllRelations(db XODB) ([]*Relation, error) {
+	const sqlstr = `SELECT ` +
+		`id, kizami_id, tag_id ` +
+		`FROM relation ` +
+		`ORDER BY id`
+
+	XOLog(sqlstr)
+	q, err := db.Query(sqlstr)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		e := q.Close()
+		if e != nil {
+			XOLog(fmt.Sprintf("failed close query: %v", e))
+		}
+	}()
+
+	res := []*Relation{}
+	for q.Next() {
+		r := Relation{
+			_exists: true,
+		}
+
+		err = q.Scan(&r.ID, &r.KizamiID, &r.TagID)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, &r)
+	}
+
+	return res, q.Err()
+}
+
+// IsEmpty returns true if there is no kizami, tag, relation and segment
+func IsEmpty(db XODB) (bool, error) {
+	const sqlstr = `SELECT ` +
+		`NOT EXISTS (SELECT 1 FROM kizami) ` +
+		`AND NOT EXISTS (SELECT 1 FROM tag) ` +
+		`AND NOT EXISTS (SELECT 1 FROM relation) ` +
+		`AND NOT EXISTS (SELECT 1 FROM segment)`
+
+	XOLog(sqlstr)
+	var empty bool
+	err := db.QueryRow(sqlstr).Scan(&empty)
+	return empty, err
+}
+
+// InsertWithID inserts the Kizami to the database with its ID as is.
+func (k *Kizami) InsertWithID(db XODB) error {
+	if k._exists {
+		return errors.New("insert failed: already exists")
+	}
+
+	const sqlstr = `INSERT INTO kizami (` +
+		`id, desc, started_at, stopped_at` +
+		`) VALUES (` +
+		`?, ?, ?, ?` +
+		`)`
+
+	XOLog(sqlstr, k.ID, k.Desc, k.StartedAt, k.StoppedAt)
+	_, err := db.Exec(sqlstr, k.ID, k.Desc, k.StartedAt, k.StoppedAt)
+	if err != nil {
+		return err
+	}
+
+	k._exists = true
+	return nil
+}
+
+// InsertWithID inserts the Tag to the database with its ID as is.
+func (t *Tag) InsertWithID(db XODB) error {
+	if t._exists {
+		return errors.New("insert failed: already exists")
+	}
+
+	const sqlstr = `INSERT INTO tag (` +
+		`id, label` +
+		`) VALUES (` +
+		`?, ?` +
+		`)`
+
+	XOLog(sqlstr, t.ID, t.Label)
+	_, err := db.Exec(sqlstr, t.ID, t.Label)
+	if err != nil {
+		return err
+	}
+
+	t._exists = true
+	return nil
+}
+
+// InsertWithID inserts the Relation to the database with its ID as is.
+func (r *Relation) InsertWithID(db XODB) error {
+	if r._exists {
+		return errors.New("insert failed: already exists")
+	}
+
+	const sqlstr = `INSERT INTO relation (` +
+		`id, kizami_id, tag_id` +
+		`) VALUES (` +
+		`?, ?, ?` +
+		`)`
+
+	XOLog(sqlstr, r.ID, r.KizamiID, r.TagID)
+	_, err := db.Exec(sqlstr, r.ID, r.KizamiID, r.TagID)
+	if err != nil {
+		return err
+	}
+
+	r._exists = true
+	return nil
+}
+
+// InsertWithID inserts the Segment to the database with its ID as is.
+func (s *Segment) InsertWithID(db XODB) error {
+	if s._exists {
+		return errors.New("insert failed: already exists")
+	}
+
+	const sqlstr = `INSERT INTO segment (` +
+		`id, kizami_id, started_at, stopped_at` +
+		`) VALUES (` +
+		`?, ?, ?, ?` +
+		`)`
+
+	XOLog(sqlstr, s.ID, s.KizamiID, s.StartedAt, s.StoppedAt)
+	_, err := db.Exec(sqlstr, s.ID, s.KizamiID, s.StartedAt, s.StoppedAt)
+	if err != nil {
+		return err
+	}
+
+	s._exists = true
+	return nil
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -72,18 +73,86 @@ type Elapsed struct {
 	Desc    string
 	Count   int
 	Elapsed time.Duration
+	Running bool
 }
 
-func elapsedOfMonthBy(db XODB, yyyymm string, groupBy string) ([]*Elapsed, error) {
-	sqlstr := fmt.Sprintf(`SELECT `+
-		`tag, desc, count(desc), SUM(strftime('%%s', kizami.stopped_at) - strftime('%%s', kizami.started_at)) AS elapsed `+
-		`FROM kizami `+
-		`LEFT JOIN relation ON kizami.id = relation.kizami_id `+
-		`LEFT JOIN tag      ON tag.id    = relation.tag_id `+
-		`WHERE started_at LIKE '%s-%%' AND stopped_at NOT LIKE '1970-%%' `+
-		`GROUP BY %s`, yyyymm, groupBy) // #nosec
-	XOLog(sqlstr)
-	q, err := db.Query(sqlstr)
+// ElapsedQuery specifies conditions to calculate elapsed time
+type ElapsedQuery struct {
+	// From and To specify the range [From, To) to calculate
+	From time.Time
+	To   time.Time
+
+	// Prorate clips elapsed time of each kizami to the range.
+	// Otherwise kizamis started in the range are calculated entirely.
+	Prorate bool
+
+	// IncludeRunning includes on-going kizamis and calculates
+	// their elapsed time until Now.
+	IncludeRunning bool
+	Now            time.Time
+}
+
+// workIntervals is a query of worked intervals of all kizamis.
+// kizamis that have been paused are represented by their segments.
+const workIntervals = `SELECT id AS kizami_id, started_at, stopped_at FROM kizami ` +
+	`WHERE id NOT IN (SELECT kizami_id FROM segment) ` +
+	`UNION ALL ` +
+	`SELECT kizami_id, started_at, stopped_at FROM segment`
+
+// expressions of start and whether on-going of worked intervals
+const (
+	workStart   = `CAST(strftime('%s', started_at) AS INTEGER)`
+	workRunning = `stopped_at LIKE '1970-%'`
+)
+
+// workStop returns an expression of stop of worked intervals and its arguments.
+// on-going intervals stop at Now if they are included.
+func workStop(eq *ElapsedQuery) (string, []interface{}) {
+	stop := `CAST(strftime('%s', stopped_at) AS INTEGER)`
+	if !eq.IncludeRunning {
+		return stop, []interface{}{}
+	}
+	return `(CASE WHEN ` + workRunning + ` THEN ? ELSE ` + stop + ` END)`, []interface{}{eq.Now.Unix()}
+}
+
+func elapsedBy(db XODB, eq *ElapsedQuery, groupBy string) ([]*Elapsed, error) {
+	const (
+		start   = workStart
+		running = workRunning
+	)
+	stop, stopArgs := workStop(eq)
+
+	elapsed := stop + ` - ` + start
+	elapsedArgs := stopArgs
+	cond := `CAST(strftime('%s', kizami.started_at) AS INTEGER) >= ? ` +
+		`AND CAST(strftime('%s', kizami.started_at) AS INTEGER) < ?`
+	condArgs := []interface{}{eq.From.Unix(), eq.To.Unix()}
+	if eq.Prorate {
+		// clip each worked interval to the range
+		elapsed = `MAX(0, MIN(` + stop + `, ?) - MAX(` + start + `, ?))`
+		elapsedArgs = append(stopArgs, eq.To.Unix(), eq.From.Unix())
+		cond = `work.elapsed > 0`
+		condArgs = []interface{}{}
+	}
+	if !eq.IncludeRunning {
+		cond += ` AND kizami.stopped_at NOT LIKE '1970-%'`
+	}
+
+	sqlstr := `SELECT ` +
+		`tag.label, desc, count(desc), SUM(work.elapsed) AS elapsed, MAX(work.running) AS running ` +
+		`FROM kizami ` +
+		`INNER JOIN (` +
+		`SELECT kizami_id, SUM(` + elapsed + `) AS elapsed, MAX(` + running + `) AS running ` +
+		`FROM (` + workIntervals + `) GROUP BY kizami_id` +
+		`) AS work ON kizami.id = work.kizami_id ` +
+		`LEFT JOIN relation ON kizami.id = relation.kizami_id ` +
+		`LEFT JOIN tag      ON tag.id    = relation.tag_id ` +
+		`WHERE ` + cond + ` ` +
+		`GROUP BY ` + groupBy // #nosec
+	args := append(elapsedArgs, condArgs...)
+
+	XOLog(sqlstr, args...)
+	q, err := db.Query(sqlstr, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -102,7 +171,7 @@ func elapsedOfMonthBy(db XODB, yyyymm string, groupBy string) ([]*Elapsed, error
 	for q.Next() {
 		e := Elapsed{}
 
-		err = q.Scan(&tag, &e.Desc, &e.Count, &sec)
+		err = q.Scan(&tag, &e.Desc, &e.Count, &sec, &e.Running)
 		if err != nil {
 			return nil, err
 		}
@@ -120,14 +189,222 @@ func elapsedOfMonthBy(db XODB, yyyymm string, groupBy string) ([]*Elapsed, error
 	return res, nil
 }
 
-// ElapsedOfMonthByDesc returns each all kizami's total elapsed time
-// elapsed in specified month group by desc and tag
-func ElapsedOfMonthByDesc(db XODB, yyyymm string) ([]*Elapsed, error) {
-	return elapsedOfMonthBy(db, yyyymm, "desc, tag")
+// ElapsedByDesc returns each all kizami's total elapsed time
+// in specified range group by desc and tag
+func ElapsedByDesc(db XODB, eq *ElapsedQuery) ([]*Elapsed, error) {
+	return elapsedBy(db, eq, "desc, tag.label")
 }
 
-// ElapsedOfMonthByTag returns each all kizami's total
-// elapsed time elapsed in specified month group by tag
-func ElapsedOfMonthByTag(db XODB, yyyymm string) ([]*Elapsed, error) {
-	return elapsedOfMonthBy(db, yyyymm, "tag")
+// ElapsedByTag returns each all kizami's total elapsed time
+// in specified range group by tag
+func ElapsedByTag(db XODB, eq *ElapsedQuery) ([]*Elapsed, error) {
+	return elapsedBy(db, eq, "tag.label")
+}
+
+// DailyElapsed represents total elapsed time of kizamis in a day
+type DailyElapsed struct {
+	Elapsed time.Duration
+	Running bool
 }
+
+// ElapsedOfDays returns total elapsed time of kizamis in each of days in the range.
+// days are beginnings of the days in order, and each of them ends at the next one,
+// or at To of the query for the last day. tags are not joined, so that a kizami
+// with many tags is counted once.
+func ElapsedOfDays(db XODB, eq *ElapsedQuery, days []time.Time) ([]*DailyElapsed, error) {
+	if len(days) == 0 {
+		return []*DailyElapsed{}, nil
+	}
+
+	// bounds of days are integers formatted by the query itself
+	bounds := make([]string, len(days))
+	for i := range days {
+		to := eq.To
+		if i+1 < len(days) {
+			to = days[i+1]
+		}
+		bounds[i] = fmt.Sprintf("(%d, %d, %d)", i, days[i].Unix(), to.Unix())
+	}
+
+	stop, stopArgs := workStop(eq)
+	elapsed := `work.stop - work.start`
+	cond := `kizami_start >= day.start_at AND kizami_start < day.stop_at`
+	if eq.Prorate {
+		// clip each worked interval to the day
+		elapsed = `MAX(0, MIN(work.stop, day.stop_at) - MAX(work.start, day.start_at))`
+		cond = elapsed + ` > 0`
+	}
+	if !eq.IncludeRunning {
+		cond += ` AND kizami_running = 0`
+	}
+
+	sqlstr := `WITH day(i, start_at, stop_at) AS (VALUES ` + strings.Join(bounds, `, `) + `) ` +
+		`SELECT day.i, SUM(` + elapsed + `), MAX(work.running) ` +
+		`FROM day INNER JOIN (` +
+		`SELECT ` + workStart + ` AS start, ` + stop + ` AS stop, ` + workRunning + ` AS running, kizami_start, kizami_running ` +
+		`FROM (` +
+		`SELECT w.started_at, w.stopped_at, ` +
+		`CAST(strftime('%s', kizami.started_at) AS INTEGER) AS kizami_start, ` +
+		`kizami.stopped_at LIKE '1970-%' AS kizami_running ` +
+		`FROM (` + workIntervals + `) AS w INNER JOIN kizami ON kizami.id = w.kizami_id` +
+		`)) AS work ` +
+		`WHERE ` + cond + ` ` +
+		`GROUP BY day.i` // #nosec
+
+	XOLog(sqlstr, stopArgs...)
+	q, err := db.Query(sqlstr, stopArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		e := q.Close()
+		if e != nil {
+			XOLog(fmt.Sprintf("failed close query: %v", e))
+		}
+	}()
+
+	res := make([]*DailyElapsed, len(days))
+	for i := range res {
+		res[i] = &DailyElapsed{}
+	}
+	for q.Next() {
+		var (
+			i   int
+			sec int64
+			e   DailyElapsed
+		)
+		err = q.Scan(&i, &sec, &e.Running)
+		if err != nil {
+			return nil, err
+		}
+		e.Elapsed = time.Duration(sec) * time.Second
+		res[i] = &e
+	}
+
+	return res, q.Err()
+}
+
+// KizamiFilter specifies conditions to find kizamis
+type KizamiFilter struct {
+	// Since and Until filter kizamis by started_at in [Since, Until).
+	// Zero value means unbounded.
+	Since time.Time
+	Until time.Time
+
+	// Tags filters kizamis that have all of specified tag labels
+	Tags []string
+
+	// Desc filters kizamis whose desc contains specified string
+	Desc string
+
+	// Running filters kizamis that are not stopped yet
+	Running bool
+
+	// Limit and Offset are applied to kizamis ordered from the newest.
+	// Limit 0 means no limit.
+	Limit  int
+	Offset int
+
+	// Reverse orders results from the newest.
+	// Otherwise results are ordered from the oldest.
+	Reverse bool
+}
+
+// KizamisByFilter returns kizamis that match specified filter
+func KizamisByFilter(db XODB, f *KizamiFilter) ([]*Kizami, error) {
+	var (
+		conds []string
+		args  []interface{}
+	)
+
+	if !f.Since.IsZero() {
+		conds = append(conds, `CAST(strftime('%s', started_at) AS INTEGER) >= ?`)
+		args = append(args, f.Since.Unix())
+	}
+	if !f.Until.IsZero() {
+		conds = append(conds, `CAST(strftime('%s', started_at) AS INTEGER) < ?`)
+		args = append(args, f.Until.Unix())
+	}
+	if f.Desc != "" {
+		conds = append(conds, `desc LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+likeEscaper.Replace(f.Desc)+"%")
+	}
+	if f.Running {
+		conds = append(conds, `stopped_at LIKE '1970-%'`)
+	}
+	if len(f.Tags) != 0 {
+		conds = append(conds, `id IN (`+
+			`SELECT relation.kizami_id FROM relation `+
+			`INNER JOIN tag ON tag.id = relation.tag_id `+
+			`WHERE tag.label IN (?`+strings.Repeat(`, ?`, len(f.Tags)-1)+`) `+
+			`GROUP BY relation.kizami_id `+
+			`HAVING COUNT(DISTINCT tag.label) = ?`+
+			`)`)
+		for _, v := range f.Tags {
+			args = append(args, v)
+		}
+		args = append(args, len(f.Tags))
+	}
+
+	where := ""
+	if len(conds) != 0 {
+		where = `WHERE ` + strings.Join(conds, ` AND `) + ` `
+	}
+
+	limit := f.Limit
+	if limit <= 0 {
+		// no limit
+		limit = -1
+	}
+	args = append(args, limit, f.Offset)
+
+	order := `ASC`
+	if f.Reverse {
+		order = `DESC`
+	}
+
+	sqlstr := `SELECT ` +
+		`id, desc, started_at, stopped_at ` +
+		`FROM (` +
+		`SELECT id, desc, started_at, stopped_at ` +
+		`FROM kizami ` +
+		where +
+		`ORDER BY started_at DESC, id DESC ` +
+		`LIMIT ? OFFSET ?` +
+		`) ` +
+		`ORDER BY started_at ` + order + `, id ` + order // #nosec
+
+	// run query
+	XOLog(sqlstr, args...)
+	q, err := db.Query(sqlstr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		e := q.Close()
+		if e != nil {
+			XOLog(fmt.Sprintf("failed close query: %v", e))
+		}
+	}()
+
+	// load results
+	res := []*Kizami{}
+	for q.Next() {
+		k := Kizami{
+			_exists: true,
+		}
+
+		// scan
+		err = q.Scan(&k.ID, &k.Desc, &k.StartedAt, &k.StoppedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, &k)
+	}
+
+	return res, q.Err()
+}
+
+// likeEscaper escapes wildcards of LIKE operator
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
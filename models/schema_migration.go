@@ -0,0 +1,90 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// SchemaMigration represents a row from 'schema_migration'.
+// Each row records a migration that has been applied to the database.
+type SchemaMigration struct {
+	Version   int
+	AppliedAt time.Time
+}
+
+// CreateSchemaMigrationTable creates table to record applied migrations
+func CreateSchemaMigrationTable(db XODB) error {
+	// sql query
+	const sqlstr = "CREATE TABLE IF NOT EXISTS schema_migration (" +
+		" version INTEGER PRIMARY KEY NOT NULL" +
+		", applied_at TIMESTAMP DEFAULT (DATETIME('now'))" +
+		")"
+	XOLog(sqlstr)
+	_, err := db.Exec(sqlstr)
+	return err
+}
+
+// SchemaMigrationTableExists returns true if schema_migration table exists
+func SchemaMigrationTableExists(db XODB) (bool, error) {
+	const sqlstr = `SELECT COUNT(*) FROM sqlite_master ` +
+		`WHERE type = 'table' AND name = 'schema_migration'`
+	XOLog(sqlstr)
+	var n int
+	err := db.QueryRow(sqlstr).Scan(&n)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// AllSchemaMigrations returns all applied migrations ordered by version
+func AllSchemaMigrations(db XODB) ([]*SchemaMigration, error) {
+	// sql query
+	const sqlstr = `SELECT ` +
+		`version, applied_at ` +
+		`FROM schema_migration ` +
+		`ORDER BY version`
+
+	// run query
+	XOLog(sqlstr)
+	q, err := db.Query(sqlstr)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		e := q.Close()
+		if e != nil {
+			XOLog(fmt.Sprintf("failed close query: %v", e))
+		}
+	}()
+
+	// load results
+	res := []*SchemaMigration{}
+	for q.Next() {
+		m := SchemaMigration{}
+		t := SqTime(time.Time{})
+
+		// scan
+		err = q.Scan(&m.Version, &t)
+		if err != nil {
+			return nil, err
+		}
+		m.AppliedAt = t.Time
+
+		res = append(res, &m)
+	}
+
+	return res, q.Err()
+}
+
+// InsertSchemaMigration records that the migration of specified version is applied
+func InsertSchemaMigration(db XODB, version int, appliedAt time.Time) error {
+	const sqlstr = `INSERT INTO schema_migration (` +
+		`version, applied_at` +
+		`) VALUES (` +
+		`?, ?` +
+		`)`
+	XOLog(sqlstr, version, appliedAt)
+	_, err := db.Exec(sqlstr, version, SqTime(appliedAt))
+	return err
+}
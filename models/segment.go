@@ -0,0 +1,211 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xo/xoutil"
+)
+
+// Segment represents a row from 'segment'.
+// A segment is a worked interval of a kizami that has been paused at least once.
+type Segment struct {
+	ID        int           `json:"id"`         // id
+	KizamiID  int           `json:"kizami_id"`  // kizami_id
+	StartedAt xoutil.SqTime `json:"started_at"` // started_at
+	StoppedAt xoutil.SqTime `json:"stopped_at"` // stopped_at
+
+	// xo fields
+	_exists, _deleted bool
+}
+
+// CreateSegmentTable creates table and index for Segment model
+func CreateSegmentTable(db XODB) error {
+	// sql query
+	sqlstr := "CREATE TABLE IF NOT EXISTS segment (" +
+		" id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL" +
+		", kizami_id INTEGER NOT NULL" +
+		", started_at TIMESTAMP DEFAULT (DATETIME('now'))" +
+		", stopped_at TIMESTAMP DEFAULT (DATETIME('1970-01-01'))" +
+		")"
+	XOLog(sqlstr)
+	_, err := db.Exec(sqlstr)
+	if err != nil {
+		return err
+	}
+
+	sqlstr = "CREATE INDEX IF NOT EXISTS index_segment_kizami_id ON segment(kizami_id)"
+	XOLog(sqlstr)
+	_, err = db.Exec(sqlstr)
+	return err
+}
+
+// Insert inserts the Segment to the database.
+func (s *Segment) Insert(db XODB) error {
+	// if already exist, bail
+	if s._exists {
+		return errors.New("insert failed: already exists")
+	}
+
+	const sqlstr = `INSERT INTO segment (` +
+		`kizami_id, started_at, stopped_at` +
+		`) VALUES (` +
+		`?, ?, ?` +
+		`)`
+
+	XOLog(sqlstr, s.KizamiID, s.StartedAt, s.StoppedAt)
+	res, err := db.Exec(sqlstr, s.KizamiID, s.StartedAt, s.StoppedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	s.ID = int(id)
+	s._exists = true
+
+	return nil
+}
+
+// Update updates the Segment in the database.
+func (s *Segment) Update(db XODB) error {
+	// if doesn't exist, bail
+	if !s._exists {
+		return errors.New("update failed: does not exist")
+	}
+
+	// if deleted, bail
+	if s._deleted {
+		return errors.New("update failed: marked for deletion")
+	}
+
+	const sqlstr = `UPDATE segment SET ` +
+		`kizami_id = ?, started_at = ?, stopped_at = ?` +
+		` WHERE id = ?`
+
+	XOLog(sqlstr, s.KizamiID, s.StartedAt, s.StoppedAt, s.ID)
+	_, err := db.Exec(sqlstr, s.KizamiID, s.StartedAt, s.StoppedAt, s.ID)
+	return err
+}
+
+// SegmentByID retrieves a row from 'segment' as a Segment.
+func SegmentByID(db XODB, id int) (*Segment, error) {
+	const sqlstr = `SELECT ` +
+		`id, kizami_id, started_at, stopped_at ` +
+		`FROM segment ` +
+		`WHERE id = ?`
+
+	XOLog(sqlstr, id)
+	s := Segment{
+		_exists: true,
+	}
+
+	err := db.QueryRow(sqlstr, id).Scan(&s.ID, &s.KizamiID, &s.StartedAt, &s.StoppedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// SegmentsByKizamiID returns segments of specified kizami ordered by started_at
+func SegmentsByKizamiID(db XODB, kizamiID int) ([]*Segment, error) {
+	const sqlstr = `SELECT ` +
+		`id, kizami_id, started_at, stopped_at ` +
+		`FROM segment ` +
+		`WHERE kizami_id = ? ` +
+		`ORDER BY started_at, id`
+
+	XOLog(sqlstr, kizamiID)
+	return querySegments(db, sqlstr, kizamiID)
+}
+
+// maxSegmentQueryIDs is the number of kizami IDs queried at once,
+// to keep the number of variables within the limit of sqlite
+const maxSegmentQueryIDs = 500
+
+// SegmentsByKizamiIDs returns segments of specified kizamis ordered by kizami_id and started_at
+func SegmentsByKizamiIDs(db XODB, kizamiIDs []int) ([]*Segment, error) {
+	res := []*Segment{}
+	for len(kizamiIDs) > 0 {
+		ids := kizamiIDs
+		if len(ids) > maxSegmentQueryIDs {
+			ids = ids[:maxSegmentQueryIDs]
+		}
+		kizamiIDs = kizamiIDs[len(ids):]
+
+		sqlstr := `SELECT ` +
+			`id, kizami_id, started_at, stopped_at ` +
+			`FROM segment ` +
+			`WHERE kizami_id IN (?` + strings.Repeat(`, ?`, len(ids)-1) + `) ` +
+			`ORDER BY kizami_id, started_at, id`
+
+		args := make([]interface{}, len(ids))
+		for i, v := range ids {
+			args[i] = v
+		}
+
+		XOLog(sqlstr, args...)
+		ss, err := querySegments(db, sqlstr, args...)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, ss...)
+	}
+
+	return res, nil
+}
+
+// AllSegments returns all segments ordered by kizami_id and started_at
+func AllSegments(db XODB) ([]*Segment, error) {
+	const sqlstr = `SELECT ` +
+		`id, kizami_id, started_at, stopped_at ` +
+		`FROM segment ` +
+		`ORDER BY kizami_id, started_at, id`
+
+	XOLog(sqlstr)
+	return querySegments(db, sqlstr)
+}
+
+func querySegments(db XODB, sqlstr string, args ...interface{}) ([]*Segment, error) {
+	q, err := db.Query(sqlstr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		e := q.Close()
+		if e != nil {
+			XOLog(fmt.Sprintf("failed close query: %v", e))
+		}
+	}()
+
+	res := []*Segment{}
+	for q.Next() {
+		s := Segment{
+			_exists: true,
+		}
+
+		err = q.Scan(&s.ID, &s.KizamiID, &s.StartedAt, &s.StoppedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, &s)
+	}
+
+	return res, q.Err()
+}
+
+// DeleteSegmentsByKizamiID removes all segments of specified kizami
+func DeleteSegmentsByKizamiID(db XODB, kizamiID int) error {
+	const sqlstr = `DELETE FROM` +
+		` segment` +
+		` WHERE kizami_id = ?`
+	XOLog(sqlstr, kizamiID)
+	_, err := db.Exec(sqlstr, kizamiID)
+	return err
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// keepAliveInterval is the interval of comments sent to idle event streams,
+// so that proxies and clients do not close them
+const keepAliveInterval = 30 * time.Second
+
+// events streams changes of Kizamis as Server-Sent Events until the client disconnects.
+// each event is named by its type and carries Event as JSON data.
+func (s *Server) events(w http.ResponseWriter, r *http.Request, _ int) error {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		return fmt.Errorf("streaming is not supported")
+	}
+
+	events, cancel := s.Kokizami.Subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	// errors of writing mean that the client has gone.
+	// they are not reported since the response has been started.
+	write := func(format string, a ...interface{}) bool {
+		_, err := fmt.Fprintf(w, format, a...)
+		if err != nil {
+			return false
+		}
+		f.Flush()
+		return true
+	}
+
+	// a comment lets the client know that the stream is established
+	if !write(": connected\n\n") {
+		return nil
+	}
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-ticker.C:
+			if !write(": keep-alive\n\n") {
+				return nil
+			}
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			b, err := json.Marshal(&Event{
+				Type:   string(e.Type),
+				Kizami: NewKizami(e.Kizami, e.Tags, s.location()),
+				Time:   e.At.In(s.location()),
+			})
+			if err != nil {
+				return nil
+			}
+			if !write("event: %s\ndata: %s\n\n", e.Type, b) {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pankona/kokizami"
+)
+
+// location returns timezone of times in responses
+func (s *Server) location() *time.Location {
+	if s.Kokizami.Location == nil {
+		return time.Local
+	}
+	return s.Kokizami.Location
+}
+
+// kizami returns a Kizami of specified ID with its tags
+func (s *Server) kizami(id int) (*Kizami, error) {
+	k, err := s.Kokizami.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	ts, err := s.Kokizami.TagsByKizamiID(k.ID)
+	if err != nil {
+		return nil, err
+	}
+	return NewKizami(k, ts, s.location()), nil
+}
+
+// parseTime parses RFC 3339, or "yyyy-mm-dd [hh:mm[:ss]]" in specified location
+func parseTime(s string, loc *time.Location) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		t, err = time.ParseInLocation(layout, s, loc)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, badRequestf("invalid time. should be RFC 3339 or yyyy-mm-dd [hh:mm[:ss]]: %s", s)
+}
+
+// queryBool returns boolean query parameter. false if not specified.
+func queryBool(q url.Values, name string) (bool, error) {
+	v := q.Get(name)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, badRequestf("invalid %s: %s", name, v)
+	}
+	return b, nil
+}
+
+// queryInt returns integer query parameter. 0 if not specified.
+func queryInt(q url.Values, name string) (int, error) {
+	v := q.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, badRequestf("invalid %s: %s", name, v)
+	}
+	return i, nil
+}
+
+// filterFromQuery returns filter of Kizamis specified by query parameters
+func filterFromQuery(q url.Values, loc *time.Location) (*kokizami.KizamiFilter, error) {
+	f := &kokizami.KizamiFilter{
+		Desc: q.Get("desc"),
+	}
+
+	var err error
+	if v := q.Get("since"); v != "" {
+		f.Since, err = parseTime(v, loc)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if v := q.Get("until"); v != "" {
+		f.Until, err = parseTime(v, loc)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, v := range q["tag"] {
+		if v == "" {
+			continue
+		}
+		if v[0] != '#' {
+			v = "#" + v
+		}
+		f.Tags = append(f.Tags, v)
+	}
+
+	f.Running, err = queryBool(q, "running")
+	if err != nil {
+		return nil, err
+	}
+	f.Reverse, err = queryBool(q, "reverse")
+	if err != nil {
+		return nil, err
+	}
+	f.Limit, err = queryInt(q, "limit")
+	if err != nil {
+		return nil, err
+	}
+	f.Offset, err = queryInt(q, "offset")
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// listKizamis lists Kizamis that match the filter of query parameters
+func (s *Server) listKizamis(w http.ResponseWriter, r *http.Request, _ int) error {
+	f, err := filterFromQuery(r.URL.Query(), s.location())
+	if err != nil {
+		return err
+	}
+
+	l, err := s.Kokizami.ListByFilter(f)
+	if err != nil {
+		return err
+	}
+
+	ret := make([]*Kizami, len(l))
+	for i, v := range l {
+		ts, err := s.Kokizami.TagsByKizamiID(v.ID)
+		if err != nil {
+			return err
+		}
+		ret[i] = NewKizami(v, ts, s.location())
+	}
+	return writeJSON(w, http.StatusOK, ret)
+}
+
+// start starts a Kizami, or adds a finished Kizami if stoppedAt is not nil.
+// tags written in desc are tagged.
+func (s *Server) start(w http.ResponseWriter, desc string, startedAt, stoppedAt *time.Time, stopOthers bool) error {
+	if stoppedAt != nil && startedAt == nil {
+		return badRequestf("started_at must be specified with stopped_at")
+	}
+
+	var err error
+	if stopOthers {
+		if startedAt != nil {
+			err = s.Kokizami.StopAllAt(*startedAt)
+		} else {
+			err = s.Kokizami.StopAll()
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	var k *kokizami.Kizami
+	switch {
+	case stoppedAt != nil:
+		k, err = s.Kokizami.Add(desc, *startedAt, *stoppedAt)
+	case startedAt != nil:
+		k, err = s.Kokizami.StartAt(desc, *startedAt)
+	default:
+		k, err = s.Kokizami.Start(desc)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = s.Kokizami.TagByDesc(k.ID, k.Desc)
+	if err != nil {
+		return err
+	}
+
+	ret, err := s.kizami(k.ID)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Location", Prefix+"/kizamis/"+strconv.Itoa(k.ID))
+	return writeJSON(w, http.StatusCreated, ret)
+}
+
+func (s *Server) startKizami(w http.ResponseWriter, r *http.Request, _ int) error {
+	req := &StartRequest{}
+	err := readJSON(r, req)
+	if err != nil {
+		return err
+	}
+	return s.start(w, req.Desc, req.StartedAt, req.StoppedAt, req.StopOthers)
+}
+
+func (s *Server) restartKizami(w http.ResponseWriter, r *http.Request, id int) error {
+	req := &RestartRequest{}
+	err := readJSON(r, req)
+	if err != nil {
+		return err
+	}
+
+	k, err := s.Kokizami.Get(id)
+	if err != nil {
+		return err
+	}
+	return s.start(w, k.Desc, req.StartedAt, nil, req.StopOthers)
+}
+
+func (s *Server) getKizami(w http.ResponseWriter, _ *http.Request, id int) error {
+	ret, err := s.kizami(id)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, ret)
+}
+
+// editKizami edits specified fields. tags are extracted again if desc is edited.
+func (s *Server) editKizami(w http.ResponseWriter, r *http.Request, id int) error {
+	req := &EditRequest{}
+	err := readJSON(r, req)
+	if err != nil {
+		return err
+	}
+
+	k, err := s.Kokizami.Get(id)
+	if err != nil {
+		return err
+	}
+	if req.Desc != nil {
+		if *req.Desc == "" {
+			return badRequestf("desc must not be empty")
+		}
+		k.Desc = *req.Desc
+	}
+	if req.StartedAt != nil {
+		k.StartedAt = *req.StartedAt
+	}
+	if req.StoppedAt != nil {
+		k.StoppedAt = *req.StoppedAt
+	}
+
+	_, err = s.Kokizami.Edit(k)
+	if err != nil {
+		return err
+	}
+
+	ret, err := s.kizami(id)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, ret)
+}
+
+func (s *Server) deleteKizami(w http.ResponseWriter, _ *http.Request, id int) error {
+	err := s.Kokizami.Delete(id)
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *Server) stopKizami(w http.ResponseWriter, r *http.Request, id int) error {
+	req := &StopRequest{}
+	err := readJSON(r, req)
+	if err != nil {
+		return err
+	}
+
+	if req.StoppedAt != nil {
+		err = s.Kokizami.StopAt(id, *req.StoppedAt)
+	} else {
+		err = s.Kokizami.Stop(id)
+	}
+	if err != nil {
+		return err
+	}
+
+	ret, err := s.kizami(id)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, ret)
+}
+
+// stopAll stops all on-going Kizamis
+func (s *Server) stopAll(w http.ResponseWriter, r *http.Request, _ int) error {
+	req := &StopRequest{}
+	err := readJSON(r, req)
+	if err != nil {
+		return err
+	}
+
+	if req.StoppedAt != nil {
+		err = s.Kokizami.StopAllAt(*req.StoppedAt)
+	} else {
+		err = s.Kokizami.StopAll()
+	}
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *Server) pauseKizami(w http.ResponseWriter, _ *http.Request, id int) error {
+	err := s.Kokizami.Pause(id)
+	if err != nil {
+		return err
+	}
+
+	ret, err := s.kizami(id)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, ret)
+}
+
+func (s *Server) resumeKizami(w http.ResponseWriter, _ *http.Request, id int) error {
+	err := s.Kokizami.Resume(id)
+	if err != nil {
+		return err
+	}
+
+	ret, err := s.kizami(id)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, ret)
+}
+
+func (s *Server) kizamiTags(w http.ResponseWriter, _ *http.Request, id int) error {
+	// fails if the Kizami does not exist
+	_, err := s.Kokizami.Get(id)
+	if err != nil {
+		return err
+	}
+
+	ts, err := s.Kokizami.TagsByKizamiID(id)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, newTags(ts))
+}
@@ -0,0 +1,337 @@
+package server
+
+// openAPI is the OpenAPI description of the API
+const openAPI = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "kokizami",
+    "description": "JSON REST API of kokizami, a task timer and tracker. Times are RFC 3339. Query parameters of times also accept yyyy-mm-dd [hh:mm[:ss]] in the timezone of the server.",
+    "version": "1"
+  },
+  "servers": [
+    {"url": "/api/v1"}
+  ],
+  "paths": {
+    "/health": {
+      "get": {
+        "summary": "check the server is alive",
+        "operationId": "health",
+        "responses": {
+          "200": {"description": "the server is alive", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Health"}}}}
+        }
+      }
+    },
+    "/openapi.json": {
+      "get": {
+        "summary": "get this description",
+        "operationId": "openapi",
+        "responses": {
+          "200": {"description": "OpenAPI description", "content": {"application/json": {}}}
+        }
+      }
+    },
+    "/events": {
+      "get": {
+        "summary": "stream changes of kizamis",
+        "description": "Server-Sent Events of changes made through this server. Each event is named by its type and its data is an Event.",
+        "operationId": "events",
+        "responses": {
+          "200": {"description": "stream of events", "content": {"text/event-stream": {"schema": {"$ref": "#/components/schemas/Event"}}}}
+        }
+      }
+    },
+    "/kizamis": {
+      "get": {
+        "summary": "list kizamis from the oldest",
+        "operationId": "listKizamis",
+        "parameters": [
+          {"name": "since", "in": "query", "description": "kizamis started at or after the time", "schema": {"type": "string"}},
+          {"name": "until", "in": "query", "description": "kizamis started before the time", "schema": {"type": "string"}},
+          {"name": "tag", "in": "query", "description": "kizamis that have all of the tags", "schema": {"type": "array", "items": {"type": "string"}}, "explode": true},
+          {"name": "desc", "in": "query", "description": "kizamis whose desc contains the string", "schema": {"type": "string"}},
+          {"name": "running", "in": "query", "description": "on-going kizamis only, including paused ones", "schema": {"type": "boolean"}},
+          {"name": "limit", "in": "query", "description": "max number of kizamis counted from the newest. 0 means no limit", "schema": {"type": "integer", "minimum": 0}},
+          {"name": "offset", "in": "query", "description": "number of newest kizamis to skip", "schema": {"type": "integer", "minimum": 0}},
+          {"name": "reverse", "in": "query", "description": "order from the newest", "schema": {"type": "boolean"}}
+        ],
+        "responses": {
+          "200": {"description": "kizamis", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Kizami"}}}}},
+          "400": {"$ref": "#/components/responses/BadRequest"}
+        }
+      },
+      "post": {
+        "summary": "start a kizami, or add a finished kizami if stopped_at is specified",
+        "description": "tags written in desc like #tag are tagged",
+        "operationId": "startKizami",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/StartRequest"}}}},
+        "responses": {
+          "201": {"description": "started kizami", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Kizami"}}}},
+          "400": {"$ref": "#/components/responses/BadRequest"}
+        }
+      }
+    },
+    "/kizamis/stop": {
+      "post": {
+        "summary": "stop all on-going kizamis",
+        "operationId": "stopAll",
+        "requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/StopRequest"}}}},
+        "responses": {
+          "204": {"description": "stopped"},
+          "400": {"$ref": "#/components/responses/BadRequest"}
+        }
+      }
+    },
+    "/kizamis/{id}": {
+      "parameters": [{"$ref": "#/components/parameters/ID"}],
+      "get": {
+        "summary": "get a kizami",
+        "operationId": "getKizami",
+        "responses": {
+          "200": {"description": "kizami", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Kizami"}}}},
+          "404": {"$ref": "#/components/responses/NotFound"}
+        }
+      },
+      "patch": {
+        "summary": "edit a kizami",
+        "description": "tags are extracted again if desc is edited",
+        "operationId": "editKizami",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/EditRequest"}}}},
+        "responses": {
+          "200": {"description": "edited kizami", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Kizami"}}}},
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "404": {"$ref": "#/components/responses/NotFound"}
+        }
+      },
+      "delete": {
+        "summary": "delete a kizami",
+        "operationId": "deleteKizami",
+        "responses": {
+          "204": {"description": "deleted"},
+          "404": {"$ref": "#/components/responses/NotFound"}
+        }
+      }
+    },
+    "/kizamis/{id}/stop": {
+      "parameters": [{"$ref": "#/components/parameters/ID"}],
+      "post": {
+        "summary": "stop a kizami",
+        "operationId": "stopKizami",
+        "requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/StopRequest"}}}},
+        "responses": {
+          "200": {"description": "stopped kizami", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Kizami"}}}},
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "404": {"$ref": "#/components/responses/NotFound"}
+        }
+      }
+    },
+    "/kizamis/{id}/restart": {
+      "parameters": [{"$ref": "#/components/parameters/ID"}],
+      "post": {
+        "summary": "start a new kizami with desc of the kizami",
+        "operationId": "restartKizami",
+        "requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/RestartRequest"}}}},
+        "responses": {
+          "201": {"description": "started kizami", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Kizami"}}}},
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "404": {"$ref": "#/components/responses/NotFound"}
+        }
+      }
+    },
+    "/kizamis/{id}/pause": {
+      "parameters": [{"$ref": "#/components/parameters/ID"}],
+      "post": {
+        "summary": "pause a running kizami",
+        "operationId": "pauseKizami",
+        "responses": {
+          "200": {"description": "paused kizami", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Kizami"}}}},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "409": {"$ref": "#/components/responses/Conflict"}
+        }
+      }
+    },
+    "/kizamis/{id}/resume": {
+      "parameters": [{"$ref": "#/components/parameters/ID"}],
+      "post": {
+        "summary": "resume a paused kizami",
+        "operationId": "resumeKizami",
+        "responses": {
+          "200": {"description": "resumed kizami", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Kizami"}}}},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "409": {"$ref": "#/components/responses/Conflict"}
+        }
+      }
+    },
+    "/kizamis/{id}/tags": {
+      "parameters": [{"$ref": "#/components/parameters/ID"}],
+      "get": {
+        "summary": "list tags of a kizami",
+        "operationId": "kizamiTags",
+        "responses": {
+          "200": {"description": "tags", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Tag"}}}}},
+          "404": {"$ref": "#/components/responses/NotFound"}
+        }
+      }
+    },
+    "/summary": {
+      "get": {
+        "summary": "total elapsed time by tag or desc",
+        "operationId": "summary",
+        "parameters": [
+          {"name": "by", "in": "query", "schema": {"type": "string", "enum": ["tag", "desc"], "default": "tag"}},
+          {"name": "month", "in": "query", "description": "month to summarize (yyyy-mm). this month by default", "schema": {"type": "string"}},
+          {"name": "from", "in": "query", "description": "beginning of the range instead of month", "schema": {"type": "string"}},
+          {"name": "to", "in": "query", "description": "end of the range instead of month", "schema": {"type": "string"}},
+          {"name": "prorate", "in": "query", "description": "clip kizamis to the range", "schema": {"type": "boolean"}},
+          {"name": "running", "in": "query", "description": "include on-going kizamis until now", "schema": {"type": "boolean"}},
+          {"name": "daily", "in": "query", "description": "include totals of each day of the range, up to 366 days", "schema": {"type": "boolean"}}
+        ],
+        "responses": {
+          "200": {"description": "summary", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Summary"}}}},
+          "400": {"$ref": "#/components/responses/BadRequest"}
+        }
+      }
+    },
+    "/tags": {
+      "get": {
+        "summary": "list tags",
+        "operationId": "tags",
+        "responses": {
+          "200": {"description": "tags", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Tag"}}}}}
+        }
+      }
+    },
+    "/tags/{id}": {
+      "parameters": [{"$ref": "#/components/parameters/ID"}],
+      "delete": {
+        "summary": "delete a tag",
+        "operationId": "deleteTag",
+        "responses": {
+          "204": {"description": "deleted"},
+          "404": {"$ref": "#/components/responses/NotFound"}
+        }
+      }
+    }
+  },
+  "components": {
+    "parameters": {
+      "ID": {"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}}
+    },
+    "responses": {
+      "BadRequest": {"description": "invalid request", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}},
+      "NotFound": {"description": "not found", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}},
+      "Conflict": {"description": "conflicts with current state", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}}
+    },
+    "schemas": {
+      "Health": {
+        "type": "object",
+        "properties": {
+          "status": {"type": "string", "example": "ok"},
+          "version": {"type": "string"},
+          "time": {"type": "string", "format": "date-time"}
+        }
+      },
+      "Error": {
+        "type": "object",
+        "properties": {
+          "error": {"type": "string"}
+        }
+      },
+      "Kizami": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "integer"},
+          "desc": {"type": "string"},
+          "tags": {"type": "array", "items": {"type": "string"}},
+          "started_at": {"type": "string", "format": "date-time"},
+          "stopped_at": {"type": "string", "format": "date-time", "nullable": true, "description": "null while running or paused"},
+          "elapsed": {"type": "integer", "description": "elapsed time in seconds"},
+          "running": {"type": "boolean"},
+          "paused": {"type": "boolean"},
+          "segments": {"type": "array", "items": {"$ref": "#/components/schemas/Segment"}, "description": "worked intervals if the kizami has been paused"}
+        }
+      },
+      "Segment": {
+        "type": "object",
+        "properties": {
+          "started_at": {"type": "string", "format": "date-time"},
+          "stopped_at": {"type": "string", "format": "date-time", "nullable": true}
+        }
+      },
+      "Tag": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "integer"},
+          "label": {"type": "string"}
+        }
+      },
+      "Elapsed": {
+        "type": "object",
+        "properties": {
+          "tag": {"type": "string"},
+          "desc": {"type": "string"},
+          "count": {"type": "integer"},
+          "elapsed": {"type": "integer", "description": "elapsed time in seconds"},
+          "running": {"type": "boolean", "description": "on-going kizamis are included"}
+        }
+      },
+      "Summary": {
+        "type": "object",
+        "properties": {
+          "by": {"type": "string", "enum": ["tag", "desc"]},
+          "from": {"type": "string", "format": "date-time"},
+          "to": {"type": "string", "format": "date-time"},
+          "items": {"type": "array", "items": {"$ref": "#/components/schemas/Elapsed"}},
+          "daily": {"type": "array", "items": {"$ref": "#/components/schemas/DailyElapsed"}}
+        }
+      },
+      "DailyElapsed": {
+        "type": "object",
+        "properties": {
+          "date": {"type": "string", "format": "date-time", "description": "beginning of the day"},
+          "elapsed": {"type": "integer", "description": "seconds"},
+          "running": {"type": "boolean"}
+        }
+      },
+      "Event": {
+        "type": "object",
+        "properties": {
+          "type": {"type": "string", "enum": ["started", "added", "stopped", "paused", "resumed", "edited", "deleted", "tagged"]},
+          "kizami": {"$ref": "#/components/schemas/Kizami"},
+          "time": {"type": "string", "format": "date-time"}
+        }
+      },
+      "StartRequest": {
+        "type": "object",
+        "required": ["desc"],
+        "properties": {
+          "desc": {"type": "string"},
+          "started_at": {"type": "string", "format": "date-time", "description": "now if omitted"},
+          "stopped_at": {"type": "string", "format": "date-time", "description": "adds a finished kizami. started_at is required"},
+          "stop_others": {"type": "boolean", "description": "stop on-going kizamis before starting"}
+        }
+      },
+      "RestartRequest": {
+        "type": "object",
+        "properties": {
+          "started_at": {"type": "string", "format": "date-time", "description": "now if omitted"},
+          "stop_others": {"type": "boolean"}
+        }
+      },
+      "EditRequest": {
+        "type": "object",
+        "properties": {
+          "desc": {"type": "string"},
+          "started_at": {"type": "string", "format": "date-time"},
+          "stopped_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "StopRequest": {
+        "type": "object",
+        "properties": {
+          "stopped_at": {"type": "string", "format": "date-time", "description": "now if omitted"}
+        }
+      }
+    }
+  }
+}
+`
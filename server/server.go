@@ -0,0 +1,247 @@
+// Package server provides a JSON REST API of kokizami over HTTP
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pankona/kokizami"
+	"github.com/pankona/kokizami/httpapi"
+)
+
+// Prefix is the path prefix of the current version of the API
+const Prefix = "/api/v1"
+
+// Server serves the API of a Kokizami
+type Server struct {
+	Kokizami *kokizami.Kokizami
+
+	// Version is version of the application reported by the health endpoint
+	Version string
+
+	// AllowOrigins are origins allowed to access the API from browsers.
+	// No origin other than the API itself is allowed if empty.
+	AllowOrigins []string
+
+	// AllowHosts are names of the API allowed in Host header besides
+	// loopback names and IP addresses, to block DNS rebinding.
+	AllowHosts []string
+
+	now func() time.Time
+}
+
+// New returns a Server of specified Kokizami
+func New(k *kokizami.Kokizami, version string) *Server {
+	return &Server{
+		Kokizami: k,
+		Version:  version,
+		now:      time.Now,
+	}
+}
+
+// route is a handler of a path with its parameter
+type route struct {
+	method  string
+	handler func(w http.ResponseWriter, r *http.Request, id int) error
+}
+
+// routes returns handlers of specified path segments and ID in the path.
+// nil is returned if the path does not exist.
+func (s *Server) routes(segments []string) ([]route, int) {
+	var id int
+	if len(segments) >= 2 {
+		if v, err := strconv.Atoi(segments[1]); err == nil {
+			id = v
+			segments[1] = "{id}"
+		}
+	}
+
+	switch strings.Join(segments, "/") {
+	case "health":
+		return []route{{http.MethodGet, s.health}}, id
+	case "openapi.json":
+		return []route{{http.MethodGet, s.openAPI}}, id
+	case "events":
+		return []route{{http.MethodGet, s.events}}, id
+	case "kizamis":
+		return []route{{http.MethodGet, s.listKizamis}, {http.MethodPost, s.startKizami}}, id
+	case "kizamis/stop":
+		return []route{{http.MethodPost, s.stopAll}}, id
+	case "kizamis/{id}":
+		return []route{{http.MethodGet, s.getKizami}, {http.MethodPatch, s.editKizami}, {http.MethodDelete, s.deleteKizami}}, id
+	case "kizamis/{id}/stop":
+		return []route{{http.MethodPost, s.stopKizami}}, id
+	case "kizamis/{id}/restart":
+		return []route{{http.MethodPost, s.restartKizami}}, id
+	case "kizamis/{id}/pause":
+		return []route{{http.MethodPost, s.pauseKizami}}, id
+	case "kizamis/{id}/resume":
+		return []route{{http.MethodPost, s.resumeKizami}}, id
+	case "kizamis/{id}/tags":
+		return []route{{http.MethodGet, s.kizamiTags}}, id
+	case "summary":
+		return []route{{http.MethodGet, s.summary}}, id
+	case "tags":
+		return []route{{http.MethodGet, s.tags}}, id
+	case "tags/{id}":
+		return []route{{http.MethodDelete, s.deleteTag}}, id
+	}
+	return nil, 0
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	err := s.serve(w, r)
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) error {
+	err := httpapi.CheckHost(r, s.AllowHosts...)
+	if err != nil {
+		return &httpError{status: http.StatusForbidden, err: err}
+	}
+
+	path := r.URL.Path
+	if path != Prefix && !strings.HasPrefix(path, Prefix+"/") {
+		return notFoundf("%s is not found", path)
+	}
+
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(path, Prefix), "/"), "/")
+	routes, id := s.routes(segments)
+	if routes == nil {
+		return notFoundf("%s is not found", path)
+	}
+
+	methods := make([]string, len(routes))
+	for i, v := range routes {
+		methods[i] = v.method
+	}
+	allow := strings.Join(append(methods, http.MethodOptions), ", ")
+
+	err = s.allowOrigin(w, r, allow)
+	if err != nil {
+		return err
+	}
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Allow", allow)
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	for _, v := range routes {
+		if v.method != r.Method {
+			continue
+		}
+		if r.Method != http.MethodGet {
+			err = httpapi.CheckContentType(r)
+			if err != nil {
+				return &httpError{status: http.StatusUnsupportedMediaType, err: err}
+			}
+		}
+		return v.handler(w, r, id)
+	}
+
+	w.Header().Set("Allow", allow)
+	return &httpError{status: http.StatusMethodNotAllowed, err: fmt.Errorf("method %s is not allowed", r.Method)}
+}
+
+// allowOrigin sets headers of CORS if the origin of request is allowed.
+// error is returned if the request comes from other origins than allowed ones
+// and the API itself, since browsers send simple requests without preflight.
+func (s *Server) allowOrigin(w http.ResponseWriter, r *http.Request, allow string) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	for _, v := range s.AllowOrigins {
+		if v == origin || v == "*" {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", allow)
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.Header().Add("Vary", "Origin")
+			return nil
+		}
+	}
+
+	// the dashboard served with the API is same origin
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return nil
+	}
+	return &httpError{status: http.StatusForbidden, err: fmt.Errorf("origin %s is not allowed", origin)}
+}
+
+func (s *Server) health(w http.ResponseWriter, _ *http.Request, _ int) error {
+	return writeJSON(w, http.StatusOK, &Health{
+		Status:  "ok",
+		Version: s.Version,
+		Time:    s.now().UTC(),
+	})
+}
+
+func (s *Server) openAPI(w http.ResponseWriter, _ *http.Request, _ int) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err := io.WriteString(w, openAPI)
+	return err
+}
+
+// httpError is an error with HTTP status
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+func badRequestf(format string, a ...interface{}) error {
+	return &httpError{status: http.StatusBadRequest, err: fmt.Errorf(format, a...)}
+}
+
+func notFoundf(format string, a ...interface{}) error {
+	return &httpError{status: http.StatusNotFound, err: fmt.Errorf(format, a...)}
+}
+
+// statusOf returns HTTP status of specified error
+func statusOf(err error) int {
+	var e *httpError
+	if errors.As(err, &e) {
+		return e.status
+	}
+	return httpapi.StatusOf(err)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	// error of writing the error can not be reported anymore
+	_ = writeJSON(w, statusOf(err), &Error{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// readJSON decodes body of request into v. empty body is allowed.
+func readJSON(r *http.Request, v interface{}) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil && err != io.EOF {
+		return badRequestf("invalid request body: %v", err)
+	}
+	return nil
+}
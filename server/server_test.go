@@ -0,0 +1,435 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pankona/kokizami/cmd/kkzm/repo/repotest"
+)
+
+// setup returns a test server of a Kokizami on a temporary sqlite database
+func setup(t *testing.T) *httptest.Server {
+	s := New(repotest.OpenTemp(t), "test")
+	s.AllowOrigins = []string{"http://localhost:3000"}
+
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// do sends a request and decodes its JSON response into v if v is not nil
+func do(t *testing.T, ts *httptest.Server, method, path, body string, v interface{}) *http.Response {
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	defer res.Body.Close()
+
+	if v != nil {
+		err = json.NewDecoder(res.Body).Decode(v)
+		if err != nil {
+			t.Fatalf("%s %s: failed to decode response: %v", method, path, err)
+		}
+	}
+	return res
+}
+
+func TestKizamis(t *testing.T) {
+	ts := setup(t)
+
+	started := &Kizami{}
+	res := do(t, ts, http.MethodPost, "/api/v1/kizamis", `{"desc": "write docs #doc"}`, started)
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("unexpected result: [got] %v [want] %v", res.StatusCode, http.StatusCreated)
+	}
+	if got := res.Header.Get("Location"); got != "/api/v1/kizamis/1" {
+		t.Errorf("unexpected result: [got] %v [want] %v", got, "/api/v1/kizamis/1")
+	}
+	if !started.Running || started.StoppedAt != nil || !cmp.Equal(started.Tags, []string{"#doc"}) {
+		t.Errorf("unexpected result: [got] %+v [want] running kizami tagged #doc", started)
+	}
+
+	added := &Kizami{}
+	res = do(t, ts, http.MethodPost, "/api/v1/kizamis",
+		`{"desc": "review #review", "started_at": "2024-03-01T09:00:00Z", "stopped_at": "2024-03-01T10:30:00Z"}`, added)
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("unexpected result: [got] %v [want] %v", res.StatusCode, http.StatusCreated)
+	}
+	if added.Running || added.StoppedAt == nil || added.Elapsed != 90*60 {
+		t.Errorf("unexpected result: [got] %+v [want] finished kizami of 90 minutes", added)
+	}
+
+	edited := &Kizami{}
+	res = do(t, ts, http.MethodPatch, "/api/v1/kizamis/2", `{"desc": "review again #review #again"}`, edited)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected result: [got] %v [want] %v", res.StatusCode, http.StatusOK)
+	}
+	if edited.Desc != "review again #review #again" || !cmp.Equal(edited.Tags, []string{"#again", "#review"}, sortStrings) {
+		t.Errorf("unexpected result: [got] %+v [want] edited desc and tags", edited)
+	}
+
+	var l []*Kizami
+	res = do(t, ts, http.MethodGet, "/api/v1/kizamis?tag=review&reverse=true", "", &l)
+	if res.StatusCode != http.StatusOK || len(l) != 1 || l[0].ID != 2 {
+		t.Errorf("unexpected result: [got] %v %+v [want] kizami 2", res.StatusCode, l)
+	}
+
+	paused := &Kizami{}
+	res = do(t, ts, http.MethodPost, "/api/v1/kizamis/1/pause", "", paused)
+	if res.StatusCode != http.StatusOK || !paused.Paused || len(paused.Segments) != 1 {
+		t.Errorf("unexpected result: [got] %v %+v [want] paused kizami", res.StatusCode, paused)
+	}
+
+	// paused kizami can not be paused again
+	res = do(t, ts, http.MethodPost, "/api/v1/kizamis/1/pause", "", nil)
+	if res.StatusCode != http.StatusConflict {
+		t.Errorf("unexpected result: [got] %v [want] %v", res.StatusCode, http.StatusConflict)
+	}
+
+	stopped := &Kizami{}
+	res = do(t, ts, http.MethodPost, "/api/v1/kizamis/1/stop", "", stopped)
+	if res.StatusCode != http.StatusOK || stopped.Running || stopped.Paused || stopped.StoppedAt == nil {
+		t.Errorf("unexpected result: [got] %v %+v [want] stopped kizami", res.StatusCode, stopped)
+	}
+
+	restarted := &Kizami{}
+	res = do(t, ts, http.MethodPost, "/api/v1/kizamis/1/restart", "", restarted)
+	if res.StatusCode != http.StatusCreated || restarted.ID != 3 || restarted.Desc != "write docs #doc" || !restarted.Running {
+		t.Errorf("unexpected result: [got] %v %+v [want] restarted kizami", res.StatusCode, restarted)
+	}
+
+	res = do(t, ts, http.MethodPost, "/api/v1/kizamis/stop", "", nil)
+	if res.StatusCode != http.StatusNoContent {
+		t.Errorf("unexpected result: [got] %v [want] %v", res.StatusCode, http.StatusNoContent)
+	}
+	l = nil
+	do(t, ts, http.MethodGet, "/api/v1/kizamis?running=true", "", &l)
+	if len(l) != 0 {
+		t.Errorf("unexpected result: [got] %+v [want] no running kizamis", l)
+	}
+
+	res = do(t, ts, http.MethodDelete, "/api/v1/kizamis/3", "", nil)
+	if res.StatusCode != http.StatusNoContent {
+		t.Errorf("unexpected result: [got] %v [want] %v", res.StatusCode, http.StatusNoContent)
+	}
+	res = do(t, ts, http.MethodGet, "/api/v1/kizamis/3", "", nil)
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("unexpected result: [got] %v [want] %v", res.StatusCode, http.StatusNotFound)
+	}
+}
+
+var sortStrings = cmp.Transformer("sort", func(in []string) []string {
+	out := append([]string(nil), in...)
+	sort.Strings(out)
+	return out
+})
+
+func TestSummaryAndTags(t *testing.T) {
+	ts := setup(t)
+
+	for _, v := range []string{
+		`{"desc": "write docs #doc", "started_at": "2024-03-01T09:00:00Z", "stopped_at": "2024-03-01T10:00:00Z"}`,
+		`{"desc": "write docs #doc", "started_at": "2024-03-02T09:00:00Z", "stopped_at": "2024-03-02T09:30:00Z"}`,
+		`{"desc": "review #review", "started_at": "2024-04-01T09:00:00Z", "stopped_at": "2024-04-01T10:00:00Z"}`,
+	} {
+		res := do(t, ts, http.MethodPost, "/api/v1/kizamis", v, nil)
+		if res.StatusCode != http.StatusCreated {
+			t.Fatalf("unexpected result: [got] %v [want] %v", res.StatusCode, http.StatusCreated)
+		}
+	}
+
+	summary := &Summary{}
+	res := do(t, ts, http.MethodGet, "/api/v1/summary?month=2024-03", "", summary)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected result: [got] %v [want] %v", res.StatusCode, http.StatusOK)
+	}
+	want := &Summary{
+		By:    "tag",
+		From:  time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:    time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+		Items: []*Elapsed{{Tag: "#doc", Count: 2, Elapsed: 90 * 60}},
+	}
+	if diff := cmp.Diff(summary, want); diff != "" {
+		t.Errorf("unexpected result: (-got +want) %s", diff)
+	}
+
+	// daily totals are in the same response
+	summary = &Summary{}
+	res = do(t, ts, http.MethodGet, "/api/v1/summary?from=2024-03-01T00:00:00Z&to=2024-03-04T00:00:00Z&daily=true", "", summary)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected result: [got] %v [want] %v", res.StatusCode, http.StatusOK)
+	}
+	wantDaily := []*DailyElapsed{
+		{Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Elapsed: 60 * 60},
+		{Date: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), Elapsed: 30 * 60},
+		{Date: time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC), Elapsed: 0},
+	}
+	if diff := cmp.Diff(summary.Daily, wantDaily); diff != "" {
+		t.Errorf("unexpected result: (-got +want) %s", diff)
+	}
+
+	var tags []*Tag
+	res = do(t, ts, http.MethodGet, "/api/v1/tags", "", &tags)
+	if res.StatusCode != http.StatusOK || len(tags) != 2 {
+		t.Fatalf("unexpected result: [got] %v %+v [want] 2 tags", res.StatusCode, tags)
+	}
+
+	res = do(t, ts, http.MethodDelete, "/api/v1/tags/999", "", nil)
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("unexpected result: [got] %v [want] %v", res.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ts := setup(t)
+
+	tcs := []struct {
+		inMethod   string
+		inPath     string
+		inBody     string
+		wantStatus int
+	}{
+		{inMethod: http.MethodPost, inPath: "/api/v1/kizamis", inBody: `{"desc": ""}`, wantStatus: http.StatusBadRequest},
+		{inMethod: http.MethodPost, inPath: "/api/v1/kizamis", inBody: `{"desc": `, wantStatus: http.StatusBadRequest},
+		{inMethod: http.MethodPost, inPath: "/api/v1/kizamis", inBody: `{"desc": "future", "started_at": "2999-01-01T00:00:00Z"}`, wantStatus: http.StatusBadRequest},
+		{inMethod: http.MethodPost, inPath: "/api/v1/kizamis", inBody: `{"desc": "no start", "stopped_at": "2024-01-01T00:00:00Z"}`, wantStatus: http.StatusBadRequest},
+		{inMethod: http.MethodGet, inPath: "/api/v1/kizamis?limit=-1", wantStatus: http.StatusBadRequest},
+		{inMethod: http.MethodGet, inPath: "/api/v1/kizamis?running=maybe", wantStatus: http.StatusBadRequest},
+		{inMethod: http.MethodGet, inPath: "/api/v1/summary?by=week", wantStatus: http.StatusBadRequest},
+		{inMethod: http.MethodGet, inPath: "/api/v1/summary?from=2024-03-01", wantStatus: http.StatusBadRequest},
+		{inMethod: http.MethodGet, inPath: "/api/v1/summary?from=2024-01-01T00:00:00Z&to=2025-03-01T00:00:00Z&daily=true", wantStatus: http.StatusBadRequest},
+		{inMethod: http.MethodGet, inPath: "/api/v1/kizamis/1", wantStatus: http.StatusNotFound},
+		{inMethod: http.MethodPost, inPath: "/api/v1/kizamis/1/resume", wantStatus: http.StatusNotFound},
+		{inMethod: http.MethodGet, inPath: "/api/v1/unknown", wantStatus: http.StatusNotFound},
+		{inMethod: http.MethodGet, inPath: "/unknown", wantStatus: http.StatusNotFound},
+		{inMethod: http.MethodPut, inPath: "/api/v1/kizamis", wantStatus: http.StatusMethodNotAllowed},
+	}
+
+	for i, tc := range tcs {
+		e := &Error{}
+		res := do(t, ts, tc.inMethod, tc.inPath, tc.inBody, e)
+		if res.StatusCode != tc.wantStatus {
+			t.Errorf("[No.%d] unexpected result: [got] %v (%s) [want] %v", i, res.StatusCode, e.Error, tc.wantStatus)
+		}
+		if e.Error == "" {
+			t.Errorf("[No.%d] unexpected result: [got] empty error [want] message", i)
+		}
+	}
+}
+
+func TestHealthAndCORS(t *testing.T) {
+	ts := setup(t)
+
+	h := &Health{}
+	res := do(t, ts, http.MethodGet, "/api/v1/health", "", h)
+	if res.StatusCode != http.StatusOK || h.Status != "ok" || h.Version != "test" {
+		t.Errorf("unexpected result: [got] %v %+v [want] ok", res.StatusCode, h)
+	}
+
+	tcs := []struct {
+		inOrigin string
+		want     string
+	}{
+		{inOrigin: "http://localhost:3000", want: "http://localhost:3000"},
+		{inOrigin: "http://evil.example.com", want: ""},
+	}
+	for i, tc := range tcs {
+		req, err := http.NewRequest(http.MethodOptions, ts.URL+"/api/v1/kizamis", nil)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		req.Header.Set("Origin", tc.inOrigin)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		res.Body.Close()
+
+		if got := res.Header.Get("Access-Control-Allow-Origin"); got != tc.want {
+			t.Errorf("[No.%d] unexpected result: [got] %v [want] %v", i, got, tc.want)
+		}
+	}
+}
+
+func TestCSRF(t *testing.T) {
+	ts := setup(t)
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+
+	tcs := []struct {
+		inMethod   string
+		inPath     string
+		inHost     string
+		inOrigin   string
+		inType     string
+		inBody     string
+		wantStatus int
+	}{
+		// forms of other sites can send text/plain without preflight
+		{inMethod: http.MethodPost, inType: "text/plain", inBody: `{"desc": "csrf"}`, wantStatus: http.StatusUnsupportedMediaType},
+		{inMethod: http.MethodPost, inBody: `{"desc": "csrf"}`, wantStatus: http.StatusUnsupportedMediaType},
+		{inMethod: http.MethodPost, inOrigin: "http://evil.example.com", inType: "application/json", inBody: `{"desc": "csrf"}`, wantStatus: http.StatusForbidden},
+		{inMethod: http.MethodGet, inOrigin: "http://evil.example.com", wantStatus: http.StatusForbidden},
+		{inMethod: http.MethodOptions, inOrigin: "http://evil.example.com", wantStatus: http.StatusForbidden},
+		// DNS rebinding reaches the API by name of the attacker
+		{inMethod: http.MethodGet, inHost: "evil.example.com:" + u.Port(), wantStatus: http.StatusForbidden},
+		{inMethod: http.MethodPost, inHost: "evil.example.com", inOrigin: "http://evil.example.com", inType: "application/json", inBody: `{"desc": "csrf"}`, wantStatus: http.StatusForbidden},
+
+		{inMethod: http.MethodPost, inOrigin: "http://localhost:3000", inType: "application/json", inBody: `{"desc": "allowed"}`, wantStatus: http.StatusCreated},
+		{inMethod: http.MethodPost, inOrigin: ts.URL, inType: "application/json; charset=utf-8", inBody: `{"desc": "same origin"}`, wantStatus: http.StatusCreated},
+		{inMethod: http.MethodPost, inHost: "localhost:" + u.Port(), inType: "application/json", inBody: `{"desc": "localhost"}`, wantStatus: http.StatusCreated},
+		// requests without body are allowed for clients such as curl
+		{inMethod: http.MethodPost, inPath: "/api/v1/kizamis/stop", wantStatus: http.StatusNoContent},
+	}
+
+	for i, tc := range tcs {
+		path := "/api/v1/kizamis"
+		if tc.inPath != "" {
+			path = tc.inPath
+		}
+		req, err := http.NewRequest(tc.inMethod, ts.URL+path, strings.NewReader(tc.inBody))
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		if tc.inHost != "" {
+			req.Host = tc.inHost
+		}
+		if tc.inOrigin != "" {
+			req.Header.Set("Origin", tc.inOrigin)
+		}
+		if tc.inType != "" {
+			req.Header.Set("Content-Type", tc.inType)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode != tc.wantStatus {
+			t.Errorf("[No.%d] unexpected result: [got] %v [want] %v", i, res.StatusCode, tc.wantStatus)
+		}
+	}
+
+	// nothing is started by forbidden requests
+	var ks []*Kizami
+	do(t, ts, http.MethodGet, "/api/v1/kizamis", "", &ks)
+	if len(ks) != 3 {
+		t.Errorf("unexpected result: [got] %v [want] 3 kizamis", len(ks))
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	ts := setup(t)
+
+	doc := &struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}{}
+	res := do(t, ts, http.MethodGet, "/api/v1/openapi.json", "", doc)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected result: [got] %v [want] %v", res.StatusCode, http.StatusOK)
+	}
+
+	// every operation in the description is routed
+	s := &Server{}
+	for path, ops := range doc.Paths {
+		routes, _ := s.routes(strings.Split(strings.Trim(strings.Replace(path, "{id}", "1", 1), "/"), "/"))
+		if routes == nil {
+			t.Errorf("unexpected result: %s is not routed", path)
+			continue
+		}
+		for method := range ops {
+			if method == "parameters" {
+				continue
+			}
+			found := false
+			for _, v := range routes {
+				found = found || v.method == strings.ToUpper(method)
+			}
+			if !found {
+				t.Errorf("unexpected result: %s %s is not routed", method, path)
+			}
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	ts := setup(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/v1/events", nil)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected result: [got] %v [want] nil", err)
+	}
+	defer res.Body.Close()
+	if got := res.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("unexpected result: [got] %v [want] %v", got, "text/event-stream")
+	}
+
+	r := bufio.NewReader(res.Body)
+	// wait until subscribed
+	line, err := r.ReadString('\n')
+	if err != nil || line != ": connected\n" {
+		t.Fatalf("unexpected result: [got] %q %v [want] connected", line, err)
+	}
+
+	do(t, ts, http.MethodPost, "/api/v1/kizamis", `{"desc": "write docs #doc"}`, nil)
+	do(t, ts, http.MethodPost, "/api/v1/kizamis/1/stop", "", nil)
+
+	// skip blank lines and then read "event:" and "data:" lines of each event
+	var got []string
+	for len(got) < 3 {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+		if !strings.HasPrefix(line, "event: ") {
+			continue
+		}
+		name := strings.TrimSpace(strings.TrimPrefix(line, "event: "))
+
+		line, err = r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+		e := &Event{}
+		err = json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), e)
+		if err != nil {
+			t.Fatalf("unexpected result: [got] %v [want] nil", err)
+		}
+		if e.Type != name {
+			t.Errorf("unexpected result: [got] %v [want] %v", e.Type, name)
+		}
+		got = append(got, fmt.Sprintf("%s %d %v %v", e.Type, e.Kizami.ID, e.Kizami.Tags, e.Kizami.Running))
+	}
+
+	// tags are set after the kizami started
+	want := []string{"started 1 [] true", "tagged 1 [#doc] true", "stopped 1 [#doc] false"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("unexpected result: (-got +want) %s", diff)
+	}
+}
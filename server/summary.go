@@ -0,0 +1,153 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/pankona/kokizami"
+)
+
+// groups of summaries
+const (
+	byTag  = "tag"
+	byDesc = "desc"
+)
+
+// maxDailyDays is the longest range of summaries with daily totals
+const maxDailyDays = 366
+
+// summaryRange returns the range of summary specified by query parameters.
+// "month" (yyyy-mm), or "from" and "to" are accepted. this month by default.
+func (s *Server) summaryRange(r *http.Request) (time.Time, time.Time, error) {
+	q := r.URL.Query()
+	loc := s.location()
+
+	month := q.Get("month")
+	from, to := q.Get("from"), q.Get("to")
+	switch {
+	case month != "" && (from != "" || to != ""):
+		return time.Time{}, time.Time{}, badRequestf("month and from/to can not be specified at once")
+	case from != "" || to != "":
+		if from == "" || to == "" {
+			return time.Time{}, time.Time{}, badRequestf("both from and to must be specified")
+		}
+		f, err := parseTime(from, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		t, err := parseTime(to, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		return f, t, nil
+	}
+
+	if month == "" {
+		month = s.now().In(loc).Format("2006-01")
+	}
+	f, err := time.ParseInLocation("2006-01", month, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, badRequestf("invalid month. should be yyyy-mm: %s", month)
+	}
+	return f, f.AddDate(0, 1, 0), nil
+}
+
+// summary returns total elapsed time by tag or desc, and
+// totals of each day in the location if daily is true
+func (s *Server) summary(w http.ResponseWriter, r *http.Request, _ int) error {
+	q := r.URL.Query()
+
+	by := q.Get("by")
+	if by == "" {
+		by = byTag
+	}
+	if by != byTag && by != byDesc {
+		return badRequestf("invalid by %s. should be %s or %s", by, byTag, byDesc)
+	}
+
+	from, to, err := s.summaryRange(r)
+	if err != nil {
+		return err
+	}
+
+	prorate, err := queryBool(q, "prorate")
+	if err != nil {
+		return err
+	}
+	running, err := queryBool(q, "running")
+	if err != nil {
+		return err
+	}
+	daily, err := queryBool(q, "daily")
+	if err != nil {
+		return err
+	}
+	if daily && to.Sub(from) > maxDailyDays*24*time.Hour {
+		return badRequestf("range of daily summary must not be longer than %d days", maxDailyDays)
+	}
+	opts := []kokizami.SummaryOption{
+		kokizami.WithProrate(prorate),
+		kokizami.WithRunning(running),
+	}
+
+	var es []*kokizami.Elapsed
+	if by == byTag {
+		es, err = s.Kokizami.SummaryByTagBetween(from, to, opts...)
+	} else {
+		es, err = s.Kokizami.SummaryByDescBetween(from, to, opts...)
+	}
+	if err != nil {
+		return err
+	}
+
+	ret := &Summary{
+		By:    by,
+		From:  from.In(s.location()),
+		To:    to.In(s.location()),
+		Items: make([]*Elapsed, len(es)),
+	}
+	for i, v := range es {
+		ret.Items[i] = NewElapsed(v)
+		if by == byTag {
+			// desc of a total by tag is one of descs in the tag
+			ret.Items[i].Desc = ""
+		}
+	}
+
+	if daily {
+		ds, err := s.Kokizami.DailySummaryBetween(from, to, opts...)
+		if err != nil {
+			return err
+		}
+		ret.Daily = make([]*DailyElapsed, len(ds))
+		for i, v := range ds {
+			ret.Daily[i] = NewDailyElapsed(v)
+		}
+	}
+	return writeJSON(w, http.StatusOK, ret)
+}
+
+func newTags(ts []*kokizami.Tag) []*Tag {
+	ret := make([]*Tag, len(ts))
+	for i, v := range ts {
+		ret[i] = &Tag{ID: v.ID, Label: v.Label}
+	}
+	return ret
+}
+
+func (s *Server) tags(w http.ResponseWriter, _ *http.Request, _ int) error {
+	ts, err := s.Kokizami.Tags()
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, newTags(ts))
+}
+
+func (s *Server) deleteTag(w http.ResponseWriter, _ *http.Request, id int) error {
+	err := s.Kokizami.DeleteTag(id)
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
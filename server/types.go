@@ -0,0 +1,172 @@
+package server
+
+import (
+	"time"
+
+	"github.com/pankona/kokizami"
+)
+
+// Health is the response of the health endpoint
+type Health struct {
+	Status  string    `json:"status"`
+	Version string    `json:"version"`
+	Time    time.Time `json:"time"`
+}
+
+// Error is the response of failed requests
+type Error struct {
+	Error string `json:"error"`
+}
+
+// Kizami represents a Kizami with its tags
+type Kizami struct {
+	ID        int       `json:"id"`
+	Desc      string    `json:"desc"`
+	Tags      []string  `json:"tags"`
+	StartedAt time.Time `json:"started_at"`
+	// StoppedAt is null while the Kizami is running or paused
+	StoppedAt *time.Time `json:"stopped_at"`
+	// Elapsed is elapsed time in seconds
+	Elapsed int64 `json:"elapsed"`
+	Running bool  `json:"running"`
+	Paused  bool  `json:"paused"`
+	// Segments are worked intervals if the Kizami has been paused
+	Segments []Segment `json:"segments"`
+}
+
+// Segment represents a worked interval of a Kizami
+type Segment struct {
+	StartedAt time.Time `json:"started_at"`
+	// StoppedAt is null while the segment is on-going
+	StoppedAt *time.Time `json:"stopped_at"`
+}
+
+// Tag represents a tag
+type Tag struct {
+	ID    int    `json:"id"`
+	Label string `json:"label"`
+}
+
+// Elapsed represents total elapsed time of a tag, or a desc in a tag
+type Elapsed struct {
+	Tag   string `json:"tag"`
+	Desc  string `json:"desc,omitempty"`
+	Count int    `json:"count"`
+	// Elapsed is elapsed time in seconds
+	Elapsed int64 `json:"elapsed"`
+	Running bool  `json:"running"`
+}
+
+// DailyElapsed represents total elapsed time of a day
+type DailyElapsed struct {
+	// Date is the beginning of the day
+	Date time.Time `json:"date"`
+	// Elapsed is elapsed time in seconds
+	Elapsed int64 `json:"elapsed"`
+	Running bool  `json:"running"`
+}
+
+// Summary is the response of the summary endpoint
+type Summary struct {
+	// By is "tag" or "desc"
+	By    string     `json:"by"`
+	From  time.Time  `json:"from"`
+	To    time.Time  `json:"to"`
+	Items []*Elapsed `json:"items"`
+	// Daily is totals of each day if requested
+	Daily []*DailyElapsed `json:"daily,omitempty"`
+}
+
+// Event is a change of a Kizami sent by the events endpoint
+type Event struct {
+	// Type is one of started, added, stopped, paused, resumed, edited, deleted and tagged
+	Type   string    `json:"type"`
+	Kizami *Kizami   `json:"kizami"`
+	Time   time.Time `json:"time"`
+}
+
+// StartRequest is the request to start a Kizami.
+// a finished Kizami is added if StoppedAt is specified.
+type StartRequest struct {
+	Desc string `json:"desc"`
+	// StartedAt is now if null
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	StoppedAt *time.Time `json:"stopped_at,omitempty"`
+	// StopOthers stops on-going Kizamis before starting
+	StopOthers bool `json:"stop_others,omitempty"`
+}
+
+// RestartRequest is the request to start a new Kizami with desc of another one
+type RestartRequest struct {
+	// StartedAt is now if null
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	StopOthers bool       `json:"stop_others,omitempty"`
+}
+
+// EditRequest is the request to edit a Kizami. null fields are not changed.
+type EditRequest struct {
+	Desc      *string    `json:"desc,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	StoppedAt *time.Time `json:"stopped_at,omitempty"`
+}
+
+// StopRequest is the request to stop Kizamis
+type StopRequest struct {
+	// StoppedAt is now if null
+	StoppedAt *time.Time `json:"stopped_at,omitempty"`
+}
+
+func stoppedAt(t time.Time, loc *time.Location) *time.Time {
+	if t.Unix() == 0 {
+		return nil
+	}
+	t = t.In(loc)
+	return &t
+}
+
+// NewKizami returns Kizami of the API
+func NewKizami(k *kokizami.Kizami, tags []*kokizami.Tag, loc *time.Location) *Kizami {
+	ret := &Kizami{
+		ID:        k.ID,
+		Desc:      k.Desc,
+		Tags:      []string{},
+		StartedAt: k.StartedAt.In(loc),
+		Elapsed:   int64(k.Elapsed().Round(time.Second) / time.Second),
+		Running:   k.IsRunning(),
+		Paused:    k.IsPaused(),
+		Segments:  []Segment{},
+	}
+	for _, v := range tags {
+		ret.Tags = append(ret.Tags, v.Label)
+	}
+	if !ret.Running && !ret.Paused {
+		ret.StoppedAt = stoppedAt(k.StoppedAt, loc)
+	}
+	for _, v := range k.Segments {
+		ret.Segments = append(ret.Segments, Segment{
+			StartedAt: v.StartedAt.In(loc),
+			StoppedAt: stoppedAt(v.StoppedAt, loc),
+		})
+	}
+	return ret
+}
+
+// NewDailyElapsed returns DailyElapsed of the API
+func NewDailyElapsed(e *kokizami.DailyElapsed) *DailyElapsed {
+	return &DailyElapsed{
+		Date:    e.Date,
+		Elapsed: int64(e.Elapsed.Round(time.Second) / time.Second),
+		Running: e.Running,
+	}
+}
+
+// NewElapsed returns Elapsed of the API
+func NewElapsed(e *kokizami.Elapsed) *Elapsed {
+	return &Elapsed{
+		Tag:     e.Tag,
+		Desc:    e.Desc,
+		Count:   e.Count,
+		Elapsed: int64(e.Elapsed.Round(time.Second) / time.Second),
+		Running: e.Running,
+	}
+}
@@ -1,5 +1,7 @@
 package kokizami
 
+import "strings"
+
 // Tag represents a tag
 type Tag struct {
 	ID    int
@@ -18,3 +20,15 @@ type TagRepository interface {
 	Insert(labels []string) error
 	Delete(id int) error
 }
+
+// ExtractTags returns words that start with "#" in specified string as tags
+func ExtractTags(s string) []string {
+	ss := strings.Split(s, " ")
+	var tags []string
+	for _, v := range ss {
+		if strings.HasPrefix(v, "#") && len(v) >= 2 {
+			tags = append(tags, v)
+		}
+	}
+	return tags
+}
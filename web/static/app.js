@@ -0,0 +1,422 @@
+// dashboard of kokizami. it uses the JSON API served on the same origin.
+'use strict';
+
+const API = 'api/v1';
+const PAGE = 20;
+
+const state = {
+  tags: [],
+  limit: PAGE,
+  period: 'week',
+  by: 'tag',
+  // offset of the charted period from the current one
+  offset: 0,
+};
+
+const $ = (id) => document.getElementById(id);
+
+// api sends a request and returns its JSON response. errors are thrown with their messages.
+async function api(method, path, body) {
+  const opts = { method, headers: {} };
+  if (body !== undefined) {
+    opts.headers['Content-Type'] = 'application/json';
+    opts.body = JSON.stringify(body);
+  }
+  const res = await fetch(`${API}/${path}`, opts);
+  if (res.status === 204) {
+    return null;
+  }
+  const v = await res.json();
+  if (!res.ok) {
+    throw new Error(v.error || res.statusText);
+  }
+  return v;
+}
+
+function showError(err) {
+  const p = $('error');
+  p.textContent = err ? err.message : '';
+  p.hidden = !err;
+}
+
+// run runs an action and shows its error if any
+async function run(action) {
+  try {
+    await action();
+    showError(null);
+  } catch (err) {
+    showError(err);
+  }
+}
+
+function el(tag, attrs, ...children) {
+  const e = document.createElement(tag);
+  Object.entries(attrs || {}).forEach(([k, v]) => {
+    if (k.startsWith('on')) {
+      e.addEventListener(k.slice(2), v);
+    } else if (v !== undefined && v !== null && v !== false) {
+      e.setAttribute(k, v === true ? '' : v);
+    }
+  });
+  e.append(...children.filter((c) => c !== null && c !== undefined));
+  return e;
+}
+
+function pad(n) {
+  return String(n).padStart(2, '0');
+}
+
+// duration formats seconds as h:mm:ss
+function duration(sec) {
+  sec = Math.max(0, Math.floor(sec));
+  return `${Math.floor(sec / 3600)}:${pad(Math.floor(sec / 60) % 60)}:${pad(sec % 60)}`;
+}
+
+// hours formats seconds as hours for charts
+function hours(sec) {
+  return `${(sec / 3600).toFixed(1)}h`;
+}
+
+// localInput formats a time for datetime-local inputs
+function localInput(t) {
+  if (!t) {
+    return '';
+  }
+  const d = new Date(t);
+  return `${d.getFullYear()}-${pad(d.getMonth() + 1)}-${pad(d.getDate())}T${pad(d.getHours())}:${pad(d.getMinutes())}:${pad(d.getSeconds())}`;
+}
+
+function localDate(d) {
+  return `${d.getFullYear()}-${pad(d.getMonth() + 1)}-${pad(d.getDate())}`;
+}
+
+// elapsed returns elapsed seconds of a kizami, counting on-going time until now
+function elapsed(k) {
+  if (!k.running) {
+    return k.elapsed;
+  }
+  return k.elapsed + (Date.now() - k.fetchedAt) / 1000;
+}
+
+function fetched(ks) {
+  const now = Date.now();
+  ks.forEach((k) => { k.fetchedAt = now; });
+  return ks;
+}
+
+// --- running timers ---
+
+async function loadRunning() {
+  const ks = fetched(await api('GET', 'kizamis?running=true'));
+  const ul = $('running');
+  ul.replaceChildren(...ks.map((k) => el('li', { class: k.paused ? 'paused' : 'running' },
+    el('span', { class: 'elapsed', 'data-id': k.id }, duration(elapsed(k))),
+    el('span', { class: 'desc' }, k.desc),
+    k.paused
+      ? el('button', { type: 'button', onclick: () => run(() => api('POST', `kizamis/${k.id}/resume`)) }, 'Resume')
+      : el('button', { type: 'button', onclick: () => run(() => api('POST', `kizamis/${k.id}/pause`)) }, 'Pause'),
+    el('button', { type: 'button', onclick: () => run(() => api('POST', `kizamis/${k.id}/stop`)) }, 'Stop'))));
+  ul.kizamis = ks;
+  if (ks.length === 0) {
+    ul.append(el('li', { class: 'empty' }, 'no running timers'));
+  }
+}
+
+// tick updates elapsed time of running timers every second
+function tick() {
+  const ks = $('running').kizamis || [];
+  ks.forEach((k) => {
+    const span = document.querySelector(`#running .elapsed[data-id="${k.id}"]`);
+    if (span) {
+      span.textContent = duration(elapsed(k));
+    }
+  });
+  const running = ks.find((k) => k.running);
+  document.title = running ? `${duration(elapsed(running))} ${running.desc} - kokizami` : 'kokizami';
+}
+
+// --- start box with tag completion ---
+
+async function loadTags() {
+  state.tags = (await api('GET', 'tags')).map((t) => t.label).sort();
+}
+
+// currentWord returns the word at the caret and its start position
+function currentWord(input) {
+  const before = input.value.slice(0, input.selectionStart);
+  const start = before.lastIndexOf(' ') + 1;
+  return { word: before.slice(start), start };
+}
+
+function suggest() {
+  const input = $('desc');
+  const ul = $('suggestions');
+  const { word } = currentWord(input);
+  const prefix = word.toLowerCase();
+  const tags = word.startsWith('#')
+    ? state.tags.filter((t) => t.toLowerCase().startsWith(prefix) && t.toLowerCase() !== prefix).slice(0, 8)
+    : [];
+
+  ul.replaceChildren(...tags.map((t, i) => el('li', {
+    role: 'option',
+    'aria-selected': i === 0 ? 'true' : 'false',
+    // mousedown keeps the focus on the input
+    onmousedown: (e) => { e.preventDefault(); complete(t); },
+  }, t)));
+  ul.hidden = tags.length === 0;
+}
+
+function complete(tag) {
+  const input = $('desc');
+  const { start } = currentWord(input);
+  const after = input.value.slice(input.selectionStart).replace(/^\S*/, '');
+  input.value = `${input.value.slice(0, start)}${tag} ${after.trimStart()}`;
+  const caret = start + tag.length + 1;
+  input.setSelectionRange(caret, caret);
+  $('suggestions').hidden = true;
+}
+
+function onSuggestionKey(e) {
+  const ul = $('suggestions');
+  if (ul.hidden) {
+    return;
+  }
+  const items = [...ul.children];
+  const i = items.findIndex((li) => li.getAttribute('aria-selected') === 'true');
+  const select = (j) => items.forEach((li, k) => li.setAttribute('aria-selected', k === j ? 'true' : 'false'));
+
+  switch (e.key) {
+    case 'ArrowDown':
+      select((i + 1) % items.length);
+      break;
+    case 'ArrowUp':
+      select((i - 1 + items.length) % items.length);
+      break;
+    case 'Tab':
+    case 'Enter':
+      complete(items[Math.max(i, 0)].textContent);
+      break;
+    case 'Escape':
+      ul.hidden = true;
+      break;
+    default:
+      return;
+  }
+  e.preventDefault();
+}
+
+async function start(e) {
+  e.preventDefault();
+  const input = $('desc');
+  await run(async () => {
+    await api('POST', 'kizamis', { desc: input.value.trim(), stop_others: $('stop-others').checked });
+    input.value = '';
+  });
+}
+
+// --- recent kizamis ---
+
+function row(k) {
+  const desc = el('input', { value: k.desc, 'aria-label': 'desc' });
+  const startedAt = el('input', { type: 'datetime-local', step: 1, value: localInput(k.started_at), 'aria-label': 'started at' });
+  const stoppedAt = el('input', { type: 'datetime-local', step: 1, value: localInput(k.stopped_at), 'aria-label': 'stopped at', disabled: k.stopped_at === null });
+
+  // only changed fields are sent, since inputs drop fractions of seconds
+  const save = () => run(async () => {
+    const req = {};
+    if (desc.value !== k.desc) {
+      req.desc = desc.value;
+    }
+    if (startedAt.value !== localInput(k.started_at)) {
+      req.started_at = new Date(startedAt.value).toISOString();
+    }
+    if (k.stopped_at !== null && stoppedAt.value !== localInput(k.stopped_at)) {
+      req.stopped_at = new Date(stoppedAt.value).toISOString();
+    }
+    document.activeElement.blur();
+    if (Object.keys(req).length !== 0) {
+      await api('PATCH', `kizamis/${k.id}`, req);
+    }
+  });
+  const remove = () => {
+    if (window.confirm(`delete kizami ${k.id} "${k.desc}"?`)) {
+      run(() => api('DELETE', `kizamis/${k.id}`));
+    }
+  };
+  [desc, startedAt, stoppedAt].forEach((input) => input.addEventListener('keydown', (e) => {
+    if (e.key === 'Enter') {
+      save();
+    }
+  }));
+
+  return el('tr', { class: k.running ? 'running' : (k.paused ? 'paused' : null) },
+    el('td', {}, String(k.id)),
+    el('td', {}, desc),
+    el('td', {}, startedAt),
+    el('td', {}, stoppedAt),
+    el('td', { class: 'number' }, duration(elapsed(k))),
+    el('td', { class: 'actions' },
+      el('button', { type: 'button', onclick: save }, 'Save'),
+      el('button', { type: 'button', onclick: () => run(() => api('POST', `kizamis/${k.id}/restart`, { stop_others: $('stop-others').checked })) }, 'Restart'),
+      el('button', { type: 'button', class: 'danger', onclick: remove }, 'Delete')));
+}
+
+async function loadRecent() {
+  const ks = fetched(await api('GET', `kizamis?reverse=true&limit=${state.limit}`));
+  const tbody = $('kizamis');
+  // rows being edited are kept as they are
+  if (tbody.contains(document.activeElement) && document.activeElement.tagName === 'INPUT') {
+    return;
+  }
+  tbody.replaceChildren(...ks.map(row));
+  $('more').hidden = ks.length < state.limit;
+}
+
+// --- charts ---
+
+// periodRange returns the beginning and the end of charted period
+function periodRange() {
+  const now = new Date();
+  if (state.period === 'month') {
+    const from = new Date(now.getFullYear(), now.getMonth() + state.offset, 1);
+    return [from, new Date(from.getFullYear(), from.getMonth() + 1, 1)];
+  }
+  // weeks start on Monday
+  const monday = now.getDate() - ((now.getDay() + 6) % 7);
+  const from = new Date(now.getFullYear(), now.getMonth(), monday + 7 * state.offset);
+  return [from, new Date(from.getFullYear(), from.getMonth(), from.getDate() + 7)];
+}
+
+// summary returns totals of the range with totals of each day
+function summary(from, to, by) {
+  const q = new URLSearchParams({
+    by, from: from.toISOString(), to: to.toISOString(), prorate: 'true', running: 'true', daily: 'true',
+  });
+  return api('GET', `summary?${q}`);
+}
+
+// calendarDate returns a local Date of the date part of RFC 3339 time,
+// so that days are labeled as the server splits them
+function calendarDate(s) {
+  const [y, m, d] = s.slice(0, 10).split('-').map(Number);
+  return new Date(y, m - 1, d);
+}
+
+// uniqueDescs returns one item per desc. summaries by desc are grouped by tag too,
+// so a kizami with many tags appears in each of them.
+function uniqueDescs(items) {
+  const m = new Map();
+  items.forEach((v) => {
+    if (!m.has(v.desc)) {
+      m.set(v.desc, v);
+    }
+  });
+  return [...m.values()];
+}
+
+async function loadCharts() {
+  const [from, to] = periodRange();
+  const last = new Date(to.getFullYear(), to.getMonth(), to.getDate() - 1);
+  $('range').textContent = `${localDate(from)} - ${localDate(last)}`;
+  $('next').disabled = state.offset >= 0;
+
+  // daily totals count a kizami with many tags once
+  const totals = await summary(from, to, state.by);
+  const days = (totals.daily || []).map((v) => calendarDate(v.date));
+  const sums = (totals.daily || []).map((v) => v.elapsed);
+
+  const items = (state.by === 'tag' ? totals.items : uniqueDescs(totals.items)).sort((a, b) => b.elapsed - a.elapsed);
+  const max = Math.max(1, ...items.map((v) => v.elapsed));
+  $('totals').replaceChildren(...items.map((v) => {
+    const label = (state.by === 'tag' ? v.tag : v.desc) || '(untagged)';
+    return el('div', { class: 'bar', title: `${label} ${duration(v.elapsed)} (${v.count})` },
+      el('span', { class: 'label' }, label),
+      el('span', { class: 'fill', style: `width: ${(100 * v.elapsed) / max}%` }),
+      el('span', { class: 'value' }, hours(v.elapsed)));
+  }));
+  if (items.length === 0) {
+    $('totals').append(el('p', { class: 'empty' }, 'no records'));
+  }
+
+  const maxDay = Math.max(1, ...sums);
+  $('daily').replaceChildren(...days.map((d, i) => el('div', { class: 'column', title: `${localDate(d)} ${duration(sums[i])}` },
+    el('span', { class: 'value' }, sums[i] ? hours(sums[i]) : ''),
+    el('span', { class: 'fill', style: `height: ${(100 * sums[i]) / maxDay}%` }),
+    el('span', { class: 'label' }, state.period === 'week' ? d.toLocaleDateString(undefined, { weekday: 'short' }) : String(d.getDate())))));
+  $('total').textContent = `total ${hours(sums.reduce((a, b) => a + b, 0))}`;
+}
+
+// --- updates ---
+
+function refresh() {
+  return run(() => Promise.all([loadRunning(), loadRecent(), loadCharts()]));
+}
+
+let pending = null;
+
+// refreshSoon coalesces refreshes requested by bursts of events
+function refreshSoon() {
+  clearTimeout(pending);
+  pending = setTimeout(refresh, 200);
+}
+
+function listen() {
+  const conn = $('connection');
+  const events = new EventSource(`${API}/events`);
+  events.onopen = () => {
+    conn.textContent = 'live';
+    conn.className = 'online';
+    // changes while disconnected are not notified
+    refreshSoon();
+  };
+  events.onerror = () => {
+    conn.textContent = 'offline';
+    conn.className = 'offline';
+  };
+  ['started', 'added', 'stopped', 'paused', 'resumed', 'edited', 'deleted'].forEach((t) => events.addEventListener(t, refreshSoon));
+  events.addEventListener('tagged', () => {
+    run(loadTags);
+    refreshSoon();
+  });
+}
+
+function init() {
+  $('start').addEventListener('submit', start);
+  $('desc').addEventListener('input', suggest);
+  $('desc').addEventListener('keydown', onSuggestionKey);
+  $('desc').addEventListener('blur', () => { $('suggestions').hidden = true; });
+  $('stop-all').addEventListener('click', () => run(() => api('POST', 'kizamis/stop')));
+  $('more').addEventListener('click', () => {
+    state.limit += PAGE;
+    run(loadRecent);
+  });
+
+  $('period').value = state.period;
+  $('period').addEventListener('change', (e) => {
+    state.period = e.target.value;
+    state.offset = 0;
+    run(loadCharts);
+  });
+  $('by').addEventListener('change', (e) => {
+    state.by = e.target.value;
+    run(loadCharts);
+  });
+  $('prev').addEventListener('click', () => {
+    state.offset -= 1;
+    run(loadCharts);
+  });
+  $('next').addEventListener('click', () => {
+    state.offset += 1;
+    run(loadCharts);
+  });
+
+  setInterval(tick, 1000);
+  // running timers are counted in charts. redraw them now and then.
+  setInterval(() => run(loadCharts), 60 * 1000);
+
+  run(loadTags);
+  refresh();
+  listen();
+}
+
+init();
@@ -0,0 +1,22 @@
+// Package web provides a dashboard of kokizami in browsers.
+// the dashboard is a single page that uses the API of package server on the same origin.
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed static
+var static embed.FS
+
+// Handler returns a handler that serves static assets of the dashboard
+func Handler() http.Handler {
+	assets, err := fs.Sub(static, "static")
+	if err != nil {
+		// the directory is embedded at build time
+		panic(err)
+	}
+	return http.FileServer(http.FS(assets))
+}
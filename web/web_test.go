@@ -0,0 +1,49 @@
+package web
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	ts := httptest.NewServer(Handler())
+	defer ts.Close()
+
+	tcs := []struct {
+		inPath          string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{inPath: "/", wantStatus: http.StatusOK, wantContentType: "text/html", wantBody: `<script src="app.js">`},
+		{inPath: "/app.js", wantStatus: http.StatusOK, wantContentType: "javascript", wantBody: "const API = 'api/v1';"},
+		{inPath: "/style.css", wantStatus: http.StatusOK, wantContentType: "text/css"},
+		{inPath: "/web.go", wantStatus: http.StatusNotFound},
+	}
+
+	for i, tc := range tcs {
+		res, err := http.Get(ts.URL + tc.inPath)
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+		b, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatalf("[No.%d] unexpected result: [got] %v [want] nil", i, err)
+		}
+
+		if res.StatusCode != tc.wantStatus {
+			t.Errorf("[No.%d] unexpected result: [got] %v [want] %v", i, res.StatusCode, tc.wantStatus)
+			continue
+		}
+		if got := res.Header.Get("Content-Type"); !strings.Contains(got, tc.wantContentType) {
+			t.Errorf("[No.%d] unexpected result: [got] %v [want] %v", i, got, tc.wantContentType)
+		}
+		if !strings.Contains(string(b), tc.wantBody) {
+			t.Errorf("[No.%d] unexpected result: [got] %v [want] %v", i, string(b), tc.wantBody)
+		}
+	}
+}